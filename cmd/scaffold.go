@@ -16,10 +16,6 @@ var (
 )
 
 func main() {
-	if ver.PrintVersionOnly(version, commit, date, out) {
-		exitFunc(0)
-	} else {
-		dir, _ := os.Getwd()
-		exitFunc(service.Setup(dir, os.Stdout, os.Args[1:]...))
-	}
+	dir, _ := os.Getwd()
+	exitFunc(service.Run(dir, out, ver.Info{Version: version, Commit: commit, Date: date}, os.Args[1:]...))
 }
@@ -20,7 +20,7 @@ func Test(t *testing.T) {
 	defer pkg.SetEnv("REGISTRY", "dockerhub")()
 
 	exitFunc = func(code int) {
-		assert.Equal(t, -1, code)
+		assert.Equal(t, 2, code)
 	}
 	os.Args = []string{"service-setup"}
 	main()
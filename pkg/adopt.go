@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"github.com/buildtool/scaffold/pkg/config"
+	"github.com/buildtool/scaffold/pkg/config/vcs"
+	"github.com/buildtool/scaffold/pkg/events"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/buildtool/scaffold/pkg/stack"
+	"io"
+	"strings"
+	"time"
+)
+
+func Adopt(dir string, out io.Writer, args ...string) int {
+	var stackName string
+	var keepOnFailure bool
+	var progress string
+	var selection config.Selection
+	var timeout time.Duration
+	const (
+		stackUsage         = "stack to scaffold"
+		keepOnFailureUsage = "keep the created build pipeline and webhook if scaffolding fails instead of removing them"
+	)
+	set := newFlagSet("scaffold adopt", "[options]", "Run inside an existing clone, for example <blue>`scaffold adopt --stack go`</blue> would create a build pipeline and webhook for the repository its origin points to and add the files of a Go-project, keeping files that already exist", out)
+	set.StringVar(&stackName, "stack", "none", stackUsage)
+	set.StringVar(&stackName, "s", "none", stackUsage+" (shorthand)")
+	set.BoolVar(&keepOnFailure, "keep-on-failure", false, keepOnFailureUsage)
+	set.DurationVar(&timeout, "timeout", defaultTimeout, timeoutUsage)
+	set.StringVar(&progress, "progress", report.Auto, report.Usage)
+	selectionFlags(set, &selection)
+
+	if exitCode, ok := parseFlags(set, args); !ok {
+		return exitCode
+	}
+	r, err := report.New(out, progress)
+	if err != nil {
+		set.Usage()
+		return failure.Usage.ExitCode()
+	}
+	followColors(set, out, r)
+	if set.NArg() > 0 {
+		set.Usage()
+		return failure.Usage.ExitCode()
+	}
+	defer r.Done()
+
+	currentStack, exists := stack.Stacks[stackName]
+	if !exists {
+		r.Failed("Provided stack does not exist yet. Available stacks are: %s", "("+strings.Join(stackNames(), ", ")+")")
+		return failure.Usage.ExitCode()
+	}
+	root, repository, err := vcs.Origin(dir)
+	if err != nil {
+		return fail(events.Nop, r, "origin", failure.Config, err)
+	}
+	name, err := repository.Name()
+	if err != nil {
+		return fail(events.Nop, r, "origin", failure.Config, err)
+	}
+	cfg, err := config.Load(root, selection, r)
+	if err != nil {
+		return fail(events.Nop, r, "load-config", failure.Config, err)
+	}
+	if err := cfg.ValidateConfig(); err != nil {
+		return fail(events.Nop, r, "validate-config", failure.Config, err)
+	}
+	cfg.KeepOnFailure = keepOnFailure
+	if err := cfg.Configure(r); err != nil {
+		return fail(events.Nop, r, "configure", failure.Config, err)
+	}
+	if err := cfg.CurrentVCS.Adopt(repository); err != nil {
+		return fail(events.Nop, r, "origin", failure.Config, err)
+	}
+	ctx, cancel := interruptible(timeout)
+	defer cancel()
+	repoPath, err := repository.Path()
+	if err != nil {
+		return fail(events.Nop, r, "origin", failure.Config, err)
+	}
+	// The repository already exists, only the build pipeline must not
+	if err := cfg.CurrentCI.Adopt(ctx, repoPath); err != nil {
+		return fail(events.Nop, r, "validate", failure.Remote, err)
+	}
+	r.Started("Adopting '%s' in '%s'", repository.SSHURL, root)
+	return cfg.Adopt(ctx, root, name, repository, currentStack, r)
+}
@@ -0,0 +1,53 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAdopt_Arguments(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Adopt(name, &out, "project")
+
+	assert.Equal(t, 2, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "\x1b[0mUsage: scaffold adopt [options]\n"))
+}
+
+func TestAdopt_NonExistingStack(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Adopt(name, &out, "--stack", "unknown")
+
+	assert.Equal(t, 2, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[31mProvided stack does not exist yet. Available stacks are: \x1b[39m\x1b[97m\x1b[1m(go, none, scala)\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", out.String())
+}
+
+func TestAdopt_Not_A_Repository(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Adopt(name, &out)
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[31m'%s' is not inside a git repository\x1b[39m\x1b[0m\n", name), out.String())
+}
+
+func TestAdopt_NoVCS(t *testing.T) {
+	dir := filepath.Join(name, "existing")
+	defer func() { _ = os.RemoveAll(dir) }()
+	repo, _ := git.PlainInit(dir, false)
+	_, _ = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"git@github.com:org/existing.git"}})
+	out := bytes.Buffer{}
+
+	exitCode := Adopt(dir, &out)
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[31mno VCS configured\x1b[39m\x1b[0m\n", out.String())
+}
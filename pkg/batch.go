@@ -0,0 +1,216 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/color"
+	"github.com/buildtool/scaffold/pkg/config"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/buildtool/scaffold/pkg/stack"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type manifest struct {
+	Services []manifestEntry `yaml:"services"`
+}
+
+type manifestEntry struct {
+	Name      string        `yaml:"name"`
+	Stack     string        `yaml:"stack"`
+	Overrides yaml.MapSlice `yaml:"overrides"`
+}
+
+type batchJob struct {
+	name     string
+	stack    stack.Stack
+	cfg      *config.Config
+	out      *bytes.Buffer
+	reporter report.Reporter
+}
+
+func Batch(dir string, out io.Writer, args ...string) int {
+	var concurrency int
+	var dryRun, keepOnFailure bool
+	var progress string
+	var selection config.Selection
+	var timeout time.Duration
+	const (
+		concurrencyUsage   = "number of services to scaffold at the same time"
+		dryRunUsage        = "print the actions that would be taken without calling any API or writing any files"
+		keepOnFailureUsage = "keep already created resources if scaffolding fails instead of removing them"
+	)
+	set := newFlagSet("scaffold batch", "[options] <manifest>", "For example <blue>`scaffold batch services.yaml`</blue> would create every service listed in services.yaml", out)
+	set.IntVar(&concurrency, "concurrency", 4, concurrencyUsage)
+	set.BoolVar(&dryRun, "dry-run", false, dryRunUsage)
+	set.BoolVar(&keepOnFailure, "keep-on-failure", false, keepOnFailureUsage)
+	set.DurationVar(&timeout, "timeout", defaultTimeout, timeoutUsage)
+	set.StringVar(&progress, "progress", report.Auto, report.Usage)
+	selectionFlags(set, &selection)
+
+	if exitCode, ok := parseFlags(set, args); !ok {
+		return exitCode
+	}
+	r, err := report.New(out, progress)
+	if err != nil {
+		set.Usage()
+		return failure.Usage.ExitCode()
+	}
+	defer r.Done()
+	followColors(set, out, r)
+
+	if set.NArg() < 1 || concurrency < 1 {
+		set.Usage()
+		return failure.Usage.ExitCode()
+	}
+
+	path := set.Args()[0]
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	m, err := readManifest(path)
+	if err != nil {
+		r.Error(err)
+		return failure.ExitCode(err)
+	}
+
+	ctx, cancel := interruptible(timeout)
+	defer cancel()
+	jobs, errs := prepare(ctx, dir, selection, m, dryRun, keepOnFailure, r)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			r.Error(err)
+		}
+		r.Failed("Found %d problem(s) in '%s', nothing was created", len(errs), path)
+		return failure.ExitCode(errs[0])
+	}
+
+	// The output of the services is written as it is, in the colours of the reporter
+	exitCodes := run(ctx, dir, jobs, concurrency, dryRun, out, r.Colored())
+
+	_, _ = fmt.Fprintln(out, color.Format(r.Colored(), "<lightblue>Summary:</lightblue>"))
+	exitCode := 0
+	for i, job := range jobs {
+		if exitCodes[i] == 0 {
+			_, _ = fmt.Fprint(out, color.Format(r.Colored(), "  <green>succeeded</green> <white><bold>'%s'</bold></white>\n", job.name))
+		} else {
+			_, _ = fmt.Fprint(out, color.Format(r.Colored(), "  <red>failed (%d)</red> <white><bold>'%s'</bold></white>\n", exitCodes[i], job.name))
+			exitCode = failure.Incomplete.ExitCode()
+		}
+	}
+	return exitCode
+}
+
+func readManifest(path string) (*manifest, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, failure.Wrap(failure.Config, "manifest", err)
+	}
+	m := &manifest{}
+	if err := yaml.UnmarshalStrict(content, m); err != nil {
+		return nil, failure.Wrap(failure.Config, "manifest", fmt.Errorf("'%s': %s", path, err.Error()))
+	}
+	if len(m.Services) == 0 {
+		return nil, failure.Wrap(failure.Config, "manifest", fmt.Errorf("'%s': no services defined", path))
+	}
+	return m, nil
+}
+
+func prepare(ctx context.Context, dir string, selection config.Selection, m *manifest, dryRun, keepOnFailure bool, r report.Reporter) ([]batchJob, []error) {
+	var jobs []batchJob
+	var errs []error
+	seen := make(map[string]bool)
+	for i, entry := range m.Services {
+		if entry.Name == "" {
+			errs = append(errs, failure.Wrap(failure.Config, "manifest", fmt.Errorf("service #%d: name is required", i+1)))
+			continue
+		}
+		if seen[entry.Name] {
+			errs = append(errs, failure.Wrap(failure.Config, "manifest", fmt.Errorf("'%s': defined more than once", entry.Name)))
+			continue
+		}
+		seen[entry.Name] = true
+		job, err := prepareJob(ctx, dir, selection, entry, dryRun, keepOnFailure, r)
+		if err != nil {
+			e := *failure.Wrap(failure.Internal, "", err)
+			e.Err = fmt.Errorf("'%s': %s", entry.Name, err.Error())
+			errs = append(errs, &e)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, errs
+}
+
+func prepareJob(ctx context.Context, dir string, selection config.Selection, entry manifestEntry, dryRun, keepOnFailure bool, r report.Reporter) (batchJob, error) {
+	if entry.Stack == "" {
+		entry.Stack = "none"
+	}
+	currentStack, exists := stack.Stacks[entry.Stack]
+	if !exists {
+		return batchJob{}, failure.Wrap(failure.Config, "manifest", fmt.Errorf("stack '%s' does not exist", entry.Stack))
+	}
+	cfg, err := config.Load(dir, selection, report.Nop)
+	if err != nil {
+		return batchJob{}, failure.Wrap(failure.Config, "load-config", err)
+	}
+	if entry.Overrides != nil {
+		if err := cfg.Override(entry.Overrides); err != nil {
+			return batchJob{}, failure.Wrap(failure.Config, "load-config", err)
+		}
+	}
+	if err := cfg.ValidateConfig(); err != nil {
+		return batchJob{}, failure.Wrap(failure.Config, "validate-config", err)
+	}
+	cfg.KeepOnFailure = keepOnFailure
+	buff := &bytes.Buffer{}
+	reporter := report.Buffered(r, buff)
+	if dryRun {
+		cfg.ConfigureDryRun(report.Nop)
+	} else if err := cfg.Configure(reporter); err != nil {
+		return batchJob{}, failure.Wrap(failure.Config, "configure", err)
+	}
+	if err := cfg.Validate(ctx, entry.Name); err != nil {
+		return batchJob{}, failure.Wrap(failure.Remote, "validate", err)
+	}
+	return batchJob{name: entry.Name, stack: currentStack, cfg: cfg, out: buff, reporter: reporter}, nil
+}
+
+func run(ctx context.Context, dir string, jobs []batchJob, concurrency int, dryRun bool, out io.Writer, colored bool) []int {
+	exitCodes := make([]int, len(jobs))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	for i, job := range jobs {
+		wg.Add(1)
+		go func(i int, job batchJob) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if dryRun {
+				job.cfg.ConfigureDryRun(job.reporter)
+				exitCodes[i] = job.cfg.DryRun(ctx, dir, job.name, job.stack, job.reporter)
+			} else {
+				exitCodes[i] = job.cfg.Scaffold(ctx, dir, job.name, job.stack, job.reporter)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			// Nothing is printed for the services that succeeded with --progress quiet
+			if job.out.Len() == 0 {
+				return
+			}
+			_, _ = fmt.Fprint(out, color.Format(colored, "<lightblue>Output for </lightblue><white><bold>'%s'</bold></white>\n", job.name))
+			_, _ = io.Copy(out, job.out)
+		}(i, job)
+	}
+	wg.Wait()
+	return exitCodes
+}
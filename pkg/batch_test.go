@@ -0,0 +1,188 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/stack"
+	"github.com/buildtool/scaffold/pkg/templating"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const batchConfig = `
+ci:
+  buildkite:
+    organisation: example
+    token: abc
+vcs:
+  github:
+    organisation: example
+    token: abc
+`
+
+func TestBatch_NoArgs(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Batch(name, &out)
+
+	assert.Equal(t, 2, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "\x1b[0mUsage: scaffold batch [options] <manifest>"))
+}
+
+func TestBatch_Missing_Manifest(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Batch(name, &out, "services.yaml")
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[31mopen %s/services.yaml: no such file or directory\x1b[39m\x1b[0m\n", name), out.String())
+}
+
+func TestBatch_Empty_Manifest(t *testing.T) {
+	manifest := filepath.Join(name, "services.yaml")
+	_ = ioutil.WriteFile(manifest, []byte("services: []"), 0777)
+	defer func() { _ = os.Remove(manifest) }()
+	out := bytes.Buffer{}
+
+	exitCode := Batch(name, &out, "services.yaml")
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[31m'%s': no services defined\x1b[39m\x1b[0m\n", manifest), out.String())
+}
+
+func TestBatch_Validates_All_Entries_Up_Front(t *testing.T) {
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(batchConfig), 0777)
+	defer func() { _ = os.Remove(file) }()
+	manifest := filepath.Join(name, "services.yaml")
+	_ = ioutil.WriteFile(manifest, []byte(`
+services:
+  - name: orders
+    stack: go
+  - stack: go
+  - name: orders
+  - name: payments
+    stack: cobol
+  - name: shipping
+    overrides:
+      vcs:
+        gitlab:
+          group: group
+`), 0777)
+	defer func() { _ = os.Remove(manifest) }()
+	out := bytes.Buffer{}
+
+	exitCode := Batch(name, &out, "--dry-run", manifest)
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[31mservice #2: name is required\x1b[39m\x1b[0m\n\x1b[0m\x1b[31m'orders': defined more than once\x1b[39m\x1b[0m\n\x1b[0m\x1b[31m'payments': stack 'cobol' does not exist\x1b[39m\x1b[0m\n\x1b[0m\x1b[31m'shipping': several VCS are configured (github, gitlab), choose one with --vcs or vcs.default\x1b[39m\x1b[0m\n\x1b[0m\x1b[31mFound \x1b[39m\x1b[97m\x1b[1m4\x1b[0m\x1b[97m\x1b[39m \x1b[31mproblem(s) in \x1b[39m\x1b[97m\x1b[1m'%s'\x1b[0m\x1b[97m\x1b[39m\x1b[31m, nothing was created\x1b[39m\n\x1b[0m", manifest), out.String())
+}
+
+func TestBatch_DryRun(t *testing.T) {
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(batchConfig), 0777)
+	defer func() { _ = os.Remove(file) }()
+	manifest := filepath.Join(name, "services.yaml")
+	_ = ioutil.WriteFile(manifest, []byte(`
+services:
+  - name: orders
+    stack: go
+  - name: payments
+    overrides:
+      vcs:
+        github:
+          organisation: other
+`), 0777)
+	defer func() { _ = os.Remove(manifest) }()
+	out := bytes.Buffer{}
+
+	exitCode := Batch(name, &out, "--dry-run", "--concurrency", "2", "services.yaml")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "Output for \x1b[39m\x1b[97m\x1b[1m'orders'")
+	assert.Contains(t, out.String(), "Output for \x1b[39m\x1b[97m\x1b[1m'payments'")
+	assert.Contains(t, out.String(), "POST https://api.github.com/orgs/example/repos")
+	assert.Contains(t, out.String(), "POST https://api.github.com/orgs/other/repos")
+	assert.True(t, strings.HasSuffix(out.String(), "\x1b[0m\x1b[94mSummary:\x1b[39m\x1b[0m\n\x1b[0m  \x1b[32msucceeded\x1b[39m \x1b[97m\x1b[1m'orders'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m  \x1b[32msucceeded\x1b[39m \x1b[97m\x1b[1m'payments'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m"))
+	_, err := os.Stat(filepath.Join(name, "orders"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestBatch_Progress_Plain(t *testing.T) {
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(batchConfig), 0777)
+	defer func() { _ = os.Remove(file) }()
+	manifest := filepath.Join(name, "services.yaml")
+	_ = ioutil.WriteFile(manifest, []byte("services:\n  - name: orders\n"), 0777)
+	defer func() { _ = os.Remove(manifest) }()
+	out := bytes.Buffer{}
+
+	exitCode := Batch(name, &out, "--dry-run", "--progress", "plain", "services.yaml")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "Output for 'orders'\n")
+	assert.NotContains(t, out.String(), "\x1b[")
+	assert.True(t, strings.HasSuffix(out.String(), "Summary:\n  succeeded 'orders'\n"))
+}
+
+func TestBatch_Override_Public_False(t *testing.T) {
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(batchConfig+"    public: true\n"), 0777)
+	defer func() { _ = os.Remove(file) }()
+	manifest := filepath.Join(name, "services.yaml")
+	_ = ioutil.WriteFile(manifest, []byte(`
+services:
+  - name: orders
+    overrides:
+      vcs:
+        github:
+          public: false
+`), 0777)
+	defer func() { _ = os.Remove(manifest) }()
+	out := bytes.Buffer{}
+
+	exitCode := Batch(name, &out, "--dry-run", manifest)
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), `"private": true`)
+}
+
+func TestBatch_Reports_Failures(t *testing.T) {
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(batchConfig), 0777)
+	defer func() { _ = os.Remove(file) }()
+	manifest := filepath.Join(name, "services.yaml")
+	_ = ioutil.WriteFile(manifest, []byte(`
+services:
+  - name: orders
+  - name: payments
+    stack: failing
+`), 0777)
+	defer func() { _ = os.Remove(manifest) }()
+	stack.Stacks["failing"] = &failingStack{}
+	defer delete(stack.Stacks, "failing")
+	out := bytes.Buffer{}
+
+	exitCode := Batch(name, &out, "--dry-run", "services.yaml")
+
+	assert.Equal(t, 8, exitCode)
+	assert.True(t, strings.HasSuffix(out.String(), "\x1b[0m\x1b[94mSummary:\x1b[39m\x1b[0m\n\x1b[0m  \x1b[32msucceeded\x1b[39m \x1b[97m\x1b[1m'orders'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m  \x1b[31mfailed (7)\x1b[39m \x1b[97m\x1b[1m'payments'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m"))
+}
+
+type failingStack struct{}
+
+func (f failingStack) Scaffold(fs billy.Filesystem, data templating.TemplateData) error {
+	return errors.New("stack error")
+}
+
+func (f failingStack) Name() string {
+	return "failing"
+}
+
+var _ stack.Stack = &failingStack{}
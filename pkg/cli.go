@@ -0,0 +1,228 @@
+package pkg
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/color"
+	"github.com/buildtool/scaffold/pkg/config"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/buildtool/scaffold/pkg/version"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+type command struct {
+	name        string
+	description string
+	run         func(dir string, out io.Writer, args ...string) int
+	commands    []command
+}
+
+func Run(dir string, out io.Writer, info version.Info, args ...string) int {
+	root := command{
+		name: "scaffold",
+		commands: []command{
+			{name: "new", description: "Create a new service", run: Setup},
+			{name: "batch", description: "Create every service listed in a manifest", run: Batch},
+			{name: "adopt", description: "Scaffold CI and files into an existing clone", run: Adopt},
+			{name: "update", description: "Merge changes to the templates into a scaffolded project", run: Update},
+			{name: "stacks", description: "List the available stacks", run: Stacks},
+			{name: "config", description: "Inspect the configuration", commands: []command{
+				{name: "show", description: "Print the effective configuration", run: ShowConfig},
+				{name: "validate", description: "Check the configuration files for mistakes", run: ValidateConfig},
+				{name: "schema", description: "Print the JSON Schema of .scaffold.yaml", run: ConfigSchema},
+			}},
+			{name: "doctor", description: "Check the configuration of the providers", run: Doctor},
+			{name: "version", description: "Print the version", run: func(dir string, out io.Writer, args ...string) int {
+				return Version(out, info, args...)
+			}},
+		},
+	}
+	if len(args) > 0 && (args[0] == "-version" || args[0] == "--version") {
+		return Version(out, info)
+	}
+	// Before the commands were added a service was created with scaffold [options] <name>
+	if len(args) > 0 && !isHelp(args[0]) && root.find(args[0]) == nil {
+		report.NewText(out, report.Colored()).Warning("'scaffold [options] <name>' is deprecated and will be removed in the next release, use %s instead", "'scaffold new "+strings.Join(args, " ")+"'")
+		return Setup(dir, out, args...)
+	}
+	return root.execute(dir, out, root.name, args)
+}
+
+func isHelp(arg string) bool {
+	switch arg {
+	case "help", "-h", "-help", "--help":
+		return true
+	}
+	return false
+}
+
+func (c command) find(name string) *command {
+	for _, sub := range c.commands {
+		if sub.name == name {
+			return &sub
+		}
+	}
+	return nil
+}
+
+func (c command) execute(dir string, out io.Writer, path string, args []string) int {
+	if c.run != nil {
+		return c.run(dir, out, args...)
+	}
+	if len(args) == 0 {
+		c.usage(out, path)
+		return failure.Usage.ExitCode()
+	}
+	if isHelp(args[0]) {
+		c.usage(out, path)
+		return 0
+	}
+	if sub := c.find(args[0]); sub != nil {
+		return sub.execute(dir, out, path+" "+sub.name, args[1:])
+	}
+	_, _ = fmt.Fprint(out, color.Sprintf("<red>Unknown command </red><white><bold>'%s'</bold></white>\n\n", args[0]))
+	c.usage(out, path)
+	return failure.Usage.ExitCode()
+}
+
+func (c command) usage(out io.Writer, path string) {
+	_, _ = fmt.Fprint(out, color.Sprintf("Usage: %s <command> [options]\n\nCommands:\n", path))
+	for _, sub := range c.commands {
+		_, _ = fmt.Fprint(out, color.Sprintf("  <blue>%-8s</blue> %s\n", sub.name, sub.description))
+	}
+	_, _ = fmt.Fprint(out, color.Sprintf("\nRun <blue>`%s <command> --help`</blue> for more information on a command\n", path))
+}
+
+// selectionFlags adds the flags that choose the configuration file, and the profile and the providers from the configuration
+func selectionFlags(set *flag.FlagSet, selection *config.Selection) {
+	set.StringVar(&selection.Config, "config", "", "configuration file that takes precedence over the .scaffold.yaml files (default $SCAFFOLD_CONFIG)")
+	set.StringVar(&selection.Profile, "profile", "", "profile from the configuration to use instead of the default profile")
+	set.StringVar(&selection.VCS, "vcs", "", "VCS to use when several are configured, github or gitlab (default vcs.default from the configuration)")
+	set.StringVar(&selection.CI, "ci", "", "CI to use when several are configured, buildkite or gitlab (default ci.default from the configuration)")
+}
+
+func newFlagSet(name, arguments, description string, out io.Writer) *flag.FlagSet {
+	set := flag.NewFlagSet(name, flag.ContinueOnError)
+	set.SetOutput(out)
+	set.Usage = func() {
+		_, _ = fmt.Fprint(set.Output(), color.Sprintf("Usage: %s %s\n\n"+description+"\n", name, arguments))
+		hasFlags := false
+		set.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			_, _ = fmt.Fprint(set.Output(), "\nOptions:\n")
+			set.PrintDefaults()
+		}
+	}
+	return set
+}
+
+// followColors prints the usage of set in colour only when r prints its messages in colour
+func followColors(set *flag.FlagSet, out io.Writer, r report.Reporter) {
+	set.SetOutput(color.Writer(out, r.Colored()))
+}
+
+func parseFlags(set *flag.FlagSet, args []string) (int, bool) {
+	if err := set.Parse(args); err == flag.ErrHelp {
+		return 0, false
+	} else if err != nil {
+		return failure.Usage.ExitCode(), false
+	}
+	return 0, true
+}
+
+func Stacks(dir string, out io.Writer, args ...string) int {
+	set := newFlagSet("scaffold stacks", "", "Lists the stacks that can be used with <blue>`scaffold new --stack`</blue>", out)
+	if exitCode, ok := parseFlags(set, args); !ok {
+		return exitCode
+	}
+	for _, name := range stackNames() {
+		_, _ = fmt.Fprintln(out, name)
+	}
+	return 0
+}
+
+func Version(out io.Writer, info version.Info, args ...string) int {
+	set := newFlagSet("scaffold version", "", "Prints the version of scaffold", out)
+	if exitCode, ok := parseFlags(set, args); !ok {
+		return exitCode
+	}
+	info.Print(out)
+	return 0
+}
+
+func ShowConfig(dir string, out io.Writer, args ...string) int {
+	var selection config.Selection
+	var explain bool
+	set := newFlagSet("scaffold config show", "[options]", "Prints the configuration merged from the configuration files and the environment, with tokens redacted", out)
+	selectionFlags(set, &selection)
+	set.BoolVar(&explain, "explain", false, "annotate every value with the file and line, environment variable or flag that set it")
+	if exitCode, ok := parseFlags(set, args); !ok {
+		return exitCode
+	}
+	cfg, err := config.Load(dir, selection, report.Nop)
+	if err != nil {
+		err := failure.Wrap(failure.Config, "load-config", err)
+		failure.Print(out, err)
+		return failure.ExitCode(err)
+	}
+	show := cfg.Show
+	if explain {
+		show = cfg.Explain
+	}
+	if err := show(out); err != nil {
+		err := failure.Wrap(failure.Config, "show-config", err)
+		failure.Print(out, err)
+		return failure.ExitCode(err)
+	}
+	return 0
+}
+
+func ValidateConfig(dir string, out io.Writer, args ...string) int {
+	var selection config.Selection
+	set := newFlagSet("scaffold config validate", "[options] [file...]", "Checks the given configuration files, or all .scaffold.yaml files merged for the current directory, and prints every problem with its file and line", out)
+	selectionFlags(set, &selection)
+	if exitCode, ok := parseFlags(set, args); !ok {
+		return exitCode
+	}
+	files := set.Args()
+	for i, file := range files {
+		if !filepath.IsAbs(file) {
+			files[i] = filepath.Join(dir, file)
+		}
+	}
+	problems, err := config.Check(dir, selection, files...)
+	if err != nil {
+		err := failure.Wrap(failure.Filesystem, "validate-config", err)
+		failure.Print(out, err)
+		return failure.ExitCode(err)
+	}
+	r := report.NewText(out, report.Colored())
+	for _, problem := range problems {
+		_, _ = fmt.Fprintln(out, problem)
+	}
+	if len(problems) > 0 {
+		r.Failed("Found %d problem(s) in the configuration", len(problems))
+		return failure.Config.ExitCode()
+	}
+	r.Succeeded("No problems found in the configuration")
+	return 0
+}
+
+func ConfigSchema(dir string, out io.Writer, args ...string) int {
+	set := newFlagSet("scaffold config schema", "", "Prints the JSON Schema of .scaffold.yaml, for validation and completion in editors", out)
+	if exitCode, ok := parseFlags(set, args); !ok {
+		return exitCode
+	}
+	content, err := json.MarshalIndent(config.ConfigSchema(), "", "  ")
+	if err != nil {
+		err := failure.Wrap(failure.Internal, "schema", err)
+		failure.Print(out, err)
+		return failure.ExitCode(err)
+	}
+	_, _ = fmt.Fprintf(out, "%s\n", content)
+	return 0
+}
@@ -0,0 +1,352 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/version"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var info = version.Info{Version: "1.0.0", Commit: "abc", Date: "2019-10-01"}
+
+func TestRun_NoArgs(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info)
+
+	assert.Equal(t, 2, exitCode)
+	assert.Equal(t, "\x1b[0mUsage: scaffold <command> [options]\n\nCommands:\n\x1b[0m\x1b[0m  \x1b[34mnew     \x1b[39m Create a new service\n\x1b[0m\x1b[0m  \x1b[34mbatch   \x1b[39m Create every service listed in a manifest\n\x1b[0m\x1b[0m  \x1b[34madopt   \x1b[39m Scaffold CI and files into an existing clone\n\x1b[0m\x1b[0m  \x1b[34mupdate  \x1b[39m Merge changes to the templates into a scaffolded project\n\x1b[0m\x1b[0m  \x1b[34mstacks  \x1b[39m List the available stacks\n\x1b[0m\x1b[0m  \x1b[34mconfig  \x1b[39m Inspect the configuration\n\x1b[0m\x1b[0m  \x1b[34mdoctor  \x1b[39m Check the configuration of the providers\n\x1b[0m\x1b[0m  \x1b[34mversion \x1b[39m Print the version\n\x1b[0m\x1b[0m\nRun \x1b[34m`scaffold <command> --help`\x1b[39m for more information on a command\n\x1b[0m", out.String())
+}
+
+func TestRun_NoArgs_No_Color(t *testing.T) {
+	_ = os.Setenv("NO_COLOR", "1")
+	defer func() { _ = os.Unsetenv("NO_COLOR") }()
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info)
+
+	assert.Equal(t, 2, exitCode)
+	assert.NotContains(t, out.String(), "\x1b[")
+	assert.True(t, strings.HasPrefix(out.String(), "Usage: scaffold <command> [options]\n\nCommands:\n  new      Create a new service\n"), out.String())
+}
+
+func TestRun_Help(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "--help")
+
+	assert.Equal(t, 0, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "\x1b[0mUsage: scaffold <command> [options]\n"))
+}
+
+func TestRun_Unknown_Command(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "config", "create")
+
+	assert.Equal(t, 2, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "\x1b[0m\x1b[31mUnknown command \x1b[39m\x1b[97m\x1b[1m'create'\x1b[0m\x1b[97m\x1b[39m\n\n\x1b[0m\x1b[0mUsage: scaffold config <command> [options]\n"))
+}
+
+func TestRun_Deprecated_New(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "--stack", "missing", "project")
+
+	assert.Equal(t, 2, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[33m'scaffold [options] <name>' is deprecated and will be removed in the next release, use \x1b[39m\x1b[97m\x1b[1m'scaffold new --stack missing project'\x1b[0m\x1b[97m\x1b[39m \x1b[33minstead\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mProvided stack does not exist yet. Available stacks are: \x1b[39m\x1b[97m\x1b[1m(go, none, scala)\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", out.String())
+}
+
+func TestRun_Config_Without_Subcommand(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "config")
+
+	assert.Equal(t, 2, exitCode)
+	assert.Equal(t, "\x1b[0mUsage: scaffold config <command> [options]\n\nCommands:\n\x1b[0m\x1b[0m  \x1b[34mshow    \x1b[39m Print the effective configuration\n\x1b[0m\x1b[0m  \x1b[34mvalidate\x1b[39m Check the configuration files for mistakes\n\x1b[0m\x1b[0m  \x1b[34mschema  \x1b[39m Print the JSON Schema of .scaffold.yaml\n\x1b[0m\x1b[0m\nRun \x1b[34m`scaffold config <command> --help`\x1b[39m for more information on a command\n\x1b[0m", out.String())
+}
+
+func TestRun_New_Help(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "new", "--help")
+
+	assert.Equal(t, 0, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "\x1b[0mUsage: scaffold new [options] <name>\n"))
+}
+
+func TestRun_New_Unknown_Flag(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "new", "--unknown", "project")
+
+	assert.Equal(t, 2, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "flag provided but not defined: -unknown\n\x1b[0mUsage: scaffold new [options] <name>\n"))
+}
+
+func TestRun_Stacks(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "stacks")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "go\nnone\nscala\n", out.String())
+}
+
+func TestRun_Stacks_Help(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "stacks", "-h")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "\x1b[0mUsage: scaffold stacks \n\nLists the stacks that can be used with \x1b[34m`scaffold new --stack`\x1b[39m\n\x1b[0m", out.String())
+}
+
+func TestRun_Version(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "version")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "Version: 1.0.0, commit abc, built at 2019-10-01\n", out.String())
+}
+
+func TestRun_Version_Flag(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "--version")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "Version: 1.0.0, commit abc, built at 2019-10-01\n", out.String())
+}
+
+func TestRun_Config_Show(t *testing.T) {
+	yaml := `
+ci:
+  buildkite:
+    organisation: example
+    token: abc
+vcs:
+  github:
+    organisation: example
+    token: abc
+registry: registry.example.com
+`
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "config", "show")
+
+	assert.Equal(t, 0, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "# VCS: Github\n# CI: Buildkite\nvcs:\n  github:\n    token: '********'\n    organisation: example\n"))
+	assert.NotContains(t, out.String(), "abc")
+	assert.Contains(t, out.String(), "registry: registry.example.com\n")
+}
+
+func TestRun_Config_Show_Profile(t *testing.T) {
+	yaml := `
+vcs:
+  github:
+    token: abc
+ci:
+  buildkite:
+    token: abc
+profiles:
+  work:
+    vcs:
+      gitlab:
+        group: work
+        token: abc
+`
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "config", "show", "--profile", "work")
+
+	assert.Equal(t, 0, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "# VCS: Gitlab\n# CI: Buildkite\n"), out.String())
+	assert.Contains(t, out.String(), "profile: work\n")
+	assert.NotContains(t, out.String(), "profiles:")
+	assert.NotContains(t, out.String(), "abc")
+}
+
+func TestRun_Config_Show_Unknown_Profile(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "config", "show", "--profile", "work")
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[31mprofile 'work' does not exist, no profiles are configured\x1b[39m\x1b[0m\n", out.String())
+}
+
+func TestRun_Config_Show_Selected_Providers(t *testing.T) {
+	yaml := `
+vcs:
+  github:
+    token: abc
+  gitlab:
+    group: group
+    token: abc
+ci:
+  buildkite:
+    token: abc
+  gitlab:
+    token: abc
+`
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "config", "show", "--vcs", "gitlab", "--ci", "buildkite")
+
+	assert.Equal(t, 0, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "# VCS: Gitlab\n# CI: Buildkite\n"), out.String())
+	assert.Contains(t, out.String(), "  default: gitlab\n")
+}
+
+func TestRun_Config_Show_Several_Providers(t *testing.T) {
+	yaml := `
+vcs:
+  github:
+    token: abc
+  gitlab:
+    group: group
+    token: abc
+`
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "config", "show")
+
+	assert.Equal(t, 0, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "# VCS: none, several VCS are configured (github, gitlab), choose one with --vcs or vcs.default\n# CI: none\n"), out.String())
+	assert.Contains(t, out.String(), "  gitlab:\n    group: group\n")
+}
+
+func TestRun_Config_Show_Explain(t *testing.T) {
+	yaml := `
+vcs:
+  github:
+    token: abc
+ci:
+  buildkite:
+    token: abc
+`
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "config", "show", "--explain", "--ci", "buildkite")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "    token: '********'  # "+file+":4\n")
+	assert.Contains(t, out.String(), "    public: false  # default\n")
+	assert.Contains(t, out.String(), "  default: buildkite  # flag --ci\n")
+	assert.NotContains(t, out.String(), "abc")
+}
+
+func TestRun_Config_Show_Broken_Config(t *testing.T) {
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte("ci: ["), 0777)
+	defer func() { _ = os.Remove(file) }()
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "config", "show")
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[31m"+file+":1: did not find expected node content\x1b[39m\x1b[0m\n", out.String())
+}
+
+func TestRun_Config_Validate(t *testing.T) {
+	yaml := `
+vcs:
+  github:
+    token: abc
+    public: maybe
+ci:
+  buildkit:
+    token: abc
+`
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "config", "validate")
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, file+":5: 'vcs.github.public' must be true or false\n"+
+		file+":7: unknown key 'ci.buildkit', allowed keys are: buildkite, default, gitlab\n"+
+		"\x1b[0m\x1b[31mFound \x1b[39m\x1b[97m\x1b[1m2\x1b[0m\x1b[97m\x1b[39m \x1b[31mproblem(s) in the configuration\x1b[39m\n\x1b[0m", out.String())
+}
+
+func TestRun_Config_Validate_Valid(t *testing.T) {
+	yaml := `
+vcs:
+  github:
+    token: abc
+ci:
+  buildkite:
+    token: abc
+`
+	file := filepath.Join(name, "other.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "config", "validate", "other.yaml")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[32mNo problems found in the configuration\x1b[39m\n\x1b[0m", out.String())
+}
+
+func TestRun_Config_Schema(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "config", "schema")
+
+	assert.Equal(t, 0, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "{\n  \"$schema\": \"http://json-schema.org/draft-07/schema#\",\n"), out.String())
+}
+
+func TestRun_Doctor_NoVCS(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "doctor")
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[31mno VCS configured\x1b[39m\x1b[0m\n", out.String())
+}
+
+func TestRun_Doctor_Several_VCS(t *testing.T) {
+	yaml := `
+vcs:
+  github:
+    token: abc
+  gitlab:
+    group: group
+    token: abc
+`
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+	out := bytes.Buffer{}
+
+	exitCode := Run(name, &out, info, "doctor")
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0mParsing config from file: \x1b[32m'%s'\x1b[39m\x1b[0m\n\x1b[0m\x1b[31mno CI configured\x1b[39m\x1b[0m\n", file), out.String())
+}
@@ -0,0 +1,48 @@
+package color
+
+import (
+	"github.com/liamg/tml"
+	"io"
+	"os"
+	"regexp"
+)
+
+var escapes = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// Enabled tells if colours are wanted, they are unless NO_COLOR is set
+func Enabled() bool {
+	return os.Getenv("NO_COLOR") == ""
+}
+
+// Sprintf formats like tml.Sprintf, without the colours when they are not wanted
+func Sprintf(format string, args ...interface{}) string {
+	return Format(Enabled(), format, args...)
+}
+
+// Format formats like tml.Sprintf, in colour only when enabled
+func Format(enabled bool, format string, args ...interface{}) string {
+	s := tml.Sprintf(format, args...)
+	if !enabled {
+		return escapes.ReplaceAllString(s, "")
+	}
+	return s
+}
+
+// Writer returns out when enabled, and otherwise a writer that leaves out the colours of what is written to out
+func Writer(out io.Writer, enabled bool) io.Writer {
+	if enabled {
+		return out
+	}
+	return plain{out}
+}
+
+type plain struct {
+	out io.Writer
+}
+
+func (p plain) Write(b []byte) (int, error) {
+	if _, err := p.out.Write(escapes.ReplaceAll(b, nil)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
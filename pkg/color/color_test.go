@@ -0,0 +1,36 @@
+package color
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestSprintf(t *testing.T) {
+	_ = os.Unsetenv("NO_COLOR")
+
+	assert.Equal(t, "\x1b[0m\x1b[31mfailed\x1b[39m 'name'\x1b[0m", Sprintf("<red>failed</red> '%s'", "name"))
+}
+
+func TestFormat(t *testing.T) {
+	_ = os.Unsetenv("NO_COLOR")
+
+	assert.Equal(t, "failed 'name'", Format(false, "<red>failed</red> '%s'", "name"))
+}
+
+func TestWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	_, _ = fmt.Fprint(Writer(out, false), Sprintf("<red>failed</red> '%s'\n", "name"))
+
+	assert.Equal(t, "failed 'name'\n", out.String())
+}
+
+func TestSprintf_No_Color(t *testing.T) {
+	_ = os.Setenv("NO_COLOR", "1")
+	defer func() { _ = os.Unsetenv("NO_COLOR") }()
+
+	assert.Equal(t, "failed 'name'", Sprintf("<red>failed</red> '%s'", "name"))
+}
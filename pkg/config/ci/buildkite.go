@@ -1,18 +1,27 @@
 package ci
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/buildkite/go-buildkite/buildkite"
+	"github.com/buildtool/scaffold/pkg/failure"
 	"github.com/buildtool/scaffold/pkg/file"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/buildtool/scaffold/pkg/retry"
 	"github.com/buildtool/scaffold/pkg/templating"
 	"github.com/buildtool/scaffold/pkg/wrappers"
+	"gopkg.in/src-d/go-billy.v4"
+	"net/http"
+	"path"
 	"path/filepath"
+	"sync"
 )
 
 type pipelineService interface {
 	Create(org string, p *buildkite.CreatePipeline) (*buildkite.Pipeline, *buildkite.Response, error)
 	Get(org string, slug string) (*buildkite.Pipeline, *buildkite.Response, error)
+	Delete(org string, slug string) (*buildkite.Response, error)
 }
 
 type userService interface {
@@ -29,6 +38,7 @@ type Buildkite struct {
 	pipelineService     pipelineService
 	userService         userService
 	organizationService organizationService
+	transport           *contextTransport
 }
 
 var _ CI = &Buildkite{}
@@ -44,50 +54,95 @@ func (c *Buildkite) ValidateConfig() error {
 	return nil
 }
 
-func (c *Buildkite) Validate(name string) error {
-	if _, _, err := c.userService.Get(); err != nil {
-		return err
+func (c *Buildkite) Validate(ctx context.Context, name string) error {
+	if response, err := c.call(ctx, func() (*buildkite.Response, error) {
+		_, response, err := c.userService.Get()
+		return response, err
+	}); err != nil {
+		return c.apiError(response, err)
 	}
-	if _, _, err := c.organizationService.Get(c.Organisation); err != nil {
-		return err
+	if response, err := c.call(ctx, func() (*buildkite.Response, error) {
+		_, response, err := c.organizationService.Get(c.Organisation)
+		return response, err
+	}); err != nil {
+		return c.organisationError(response, err)
 	}
-	pipeline, response, err := c.pipelineService.Get(c.Organisation, name)
+	var pipeline *buildkite.Pipeline
+	response, err := c.call(ctx, func() (response *buildkite.Response, err error) {
+		pipeline, response, err = c.pipelineService.Get(c.Organisation, name)
+		return response, err
+	})
 	if err != nil {
 		if response == nil || response.StatusCode != 404 {
-			return err
+			return c.apiError(response, err)
 		}
 	}
 	if pipeline != nil {
-		return fmt.Errorf("pipeline named '%s/%s' already exists at Buildkite", c.Organisation, name)
+		return &failure.Error{
+			Kind:     failure.Conflict,
+			Provider: c.Name(),
+			Hint:     "choose another name or remove the existing pipeline",
+			Err:      fmt.Errorf("pipeline named '%s/%s' already exists at Buildkite", c.Organisation, name),
+		}
 	}
 
 	return nil
 }
 
-func (c *Buildkite) Scaffold(dir string, data templating.TemplateData) (*string, error) {
-	if err := file.Write(filepath.Join(dir, ".buildkite"), "pipeline.yml", pipelineYml); err != nil {
-		return nil, err
+// Adopt checks that there is no pipeline for the repository yet, the organisation does not depend on the repository
+func (c *Buildkite) Adopt(ctx context.Context, repoPath string) error {
+	return c.Validate(ctx, path.Base(repoPath))
+}
+
+func (c *Buildkite) Check(ctx context.Context, report func(check string, err error)) {
+	response, err := c.call(ctx, func() (*buildkite.Response, error) {
+		_, response, err := c.userService.Get()
+		return response, err
+	})
+	if err != nil {
+		report("token", c.apiError(response, err))
+		return
 	}
-	if err := file.Append(filepath.Join(dir, ".dockerignore"), ".buildkite"); err != nil {
-		return nil, err
+	report("token", nil)
+	response, err = c.call(ctx, func() (*buildkite.Response, error) {
+		_, response, err := c.organizationService.Get(c.Organisation)
+		return response, err
+	})
+	if err != nil {
+		report(fmt.Sprintf("organisation '%s'", c.Organisation), c.organisationError(response, err))
+		return
+	}
+	report(fmt.Sprintf("organisation '%s'", c.Organisation), nil)
+}
+
+func (c *Buildkite) Scaffold(ctx context.Context, fs billy.Filesystem, data templating.TemplateData) (*string, error) {
+	if err := file.Write(fs, filepath.Join(".buildkite", "pipeline.yml"), pipelineYml); err != nil {
+		return nil, failure.Wrap(failure.Filesystem, "", err)
+	}
+	if err := file.Append(fs, ".dockerignore", ".buildkite"); err != nil {
+		return nil, failure.Wrap(failure.Filesystem, "", err)
 	}
 	provider := getProviderFromRepositoryHost(data.RepositoryHost)
-	pipeline, _, err := c.pipelineService.Create(c.Organisation, &buildkite.CreatePipeline{
-		Name:       data.ProjectName,
-		Repository: data.RepositoryUrl,
-		Steps: []buildkite.Step{
-			{
-				Type:    buildkite.String("script"),
-				Name:    buildkite.String("Setup :package:"),
-				Command: buildkite.String("buildkite-agent pipeline upload"),
+	var pipeline *buildkite.Pipeline
+	response, err := c.call(ctx, func() (response *buildkite.Response, err error) {
+		pipeline, response, err = c.pipelineService.Create(c.Organisation, &buildkite.CreatePipeline{
+			Name:       data.ProjectName,
+			Repository: data.RepositoryUrl,
+			Steps: []buildkite.Step{
+				{
+					Type:    buildkite.String("script"),
+					Name:    buildkite.String("Setup :package:"),
+					Command: buildkite.String("buildkite-agent pipeline upload"),
+				},
 			},
-		},
-		ProviderSettings:          provider,
-		SkipQueuedBranchBuilds:    true,
-		CancelRunningBranchBuilds: true,
+			ProviderSettings:          provider,
+			SkipQueuedBranchBuilds:    true,
+			CancelRunningBranchBuilds: true,
+		})
+		return response, err
 	})
 	if err != nil {
-		return nil, err
+		return nil, c.apiError(response, err)
 	}
 
 	var hookUrl *string
@@ -97,10 +152,14 @@ func (c *Buildkite) Scaffold(dir string, data templating.TemplateData) (*string,
 	return hookUrl, err
 }
 
-func (c *Buildkite) Badges(name string) ([]templating.Badge, error) {
-	pipeline, _, err := c.pipelineService.Get(c.Organisation, name)
+func (c *Buildkite) Badges(ctx context.Context, name string) ([]templating.Badge, error) {
+	var pipeline *buildkite.Pipeline
+	response, err := c.call(ctx, func() (response *buildkite.Response, err error) {
+		pipeline, response, err = c.pipelineService.Get(c.Organisation, name)
+		return response, err
+	})
 	if err != nil {
-		return nil, err
+		return nil, c.apiError(response, err)
 	}
 	badges := []templating.Badge{
 		{
@@ -112,12 +171,23 @@ func (c *Buildkite) Badges(name string) ([]templating.Badge, error) {
 	return badges, nil
 }
 
-func (c *Buildkite) Configure() error {
-	config, err := buildkite.NewTokenConfig(c.Token, false)
+func (c *Buildkite) DeletePipeline(ctx context.Context, name string) error {
+	_, err := c.call(ctx, func() (*buildkite.Response, error) {
+		return c.pipelineService.Delete(c.Organisation, name)
+	})
+	return err
+}
+
+func (c *Buildkite) Configure(r report.Reporter) error {
+	client, err := c.client(r)
 	if err != nil {
-		return err
+		return &failure.Error{
+			Kind:     failure.Config,
+			Provider: c.Name(),
+			Hint:     "set ci.buildkite.token in .scaffold.yaml or BUILDKITE_TOKEN",
+			Err:      err,
+		}
 	}
-	client := buildkite.NewClient(config.Client())
 
 	c.pipelineService = client.Pipelines
 	c.userService = client.User
@@ -126,6 +196,80 @@ func (c *Buildkite) Configure() error {
 	return nil
 }
 
+func (c *Buildkite) client(r report.Reporter) (*buildkite.Client, error) {
+	config, err := buildkite.NewTokenConfig(c.Token, false)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := config.Client()
+	c.transport = &contextTransport{base: retry.New(httpClient.Transport, c.Name(), r)}
+	httpClient.Transport = c.transport
+	return buildkite.NewClient(httpClient), nil
+}
+
+// call runs call with the requests it sends bound to ctx, as the Buildkite client does not take a context itself
+func (c *Buildkite) call(ctx context.Context, call func() (*buildkite.Response, error)) (*buildkite.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.transport != nil {
+		c.transport.use(ctx)
+		defer c.transport.use(nil)
+	}
+	response, err := call()
+	if err != nil && ctx.Err() != nil {
+		return response, ctx.Err()
+	}
+	return response, err
+}
+
+// contextTransport sends the requests with the context of the current call instead of the background context the
+// Buildkite client creates them with, so that they and the waits of the retries stop on an interrupt or the timeout
+type contextTransport struct {
+	base http.RoundTripper
+	lock sync.Mutex
+	ctx  context.Context
+}
+
+func (t *contextTransport) use(ctx context.Context) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.ctx = ctx
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.lock.Lock()
+	ctx := t.ctx
+	t.lock.Unlock()
+	if ctx != nil {
+		req = req.WithContext(ctx)
+	}
+	return t.base.RoundTrip(req)
+}
+
+func (c *Buildkite) organisationError(response *buildkite.Response, err error) *failure.Error {
+	e := c.apiError(response, err)
+	if e.Status == http.StatusNotFound {
+		e.Kind = failure.Config
+		e.Hint = fmt.Sprintf("check that the organisation '%s' exists and that the token can access it", c.Organisation)
+	}
+	return e
+}
+
+func (c *Buildkite) apiError(response *buildkite.Response, err error) *failure.Error {
+	code := 0
+	if response != nil && response.Response != nil {
+		code = response.StatusCode
+	}
+	return failure.API(c.Name(), code, err)
+}
+
+func (c *Buildkite) DryRun(r report.Reporter) {
+	c.pipelineService = &dryRunPipelines{reporter: r, pipelines: make(map[string]*buildkite.Pipeline)}
+	c.userService = &dryRunUser{}
+	c.organizationService = &dryRunOrganizations{}
+}
+
 func getProviderFromRepositoryHost(host string) buildkite.ProviderSettings {
 	if host == "github.com" {
 		return &buildkite.GitHubSettings{
@@ -1,17 +1,25 @@
 package ci
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/buildkite/go-buildkite/buildkite"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/report"
 	"github.com/buildtool/scaffold/pkg/templating"
 	"github.com/buildtool/scaffold/pkg/wrappers"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/osfs"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestBuildkite_Name(t *testing.T) {
@@ -37,32 +45,116 @@ func TestBuildkite_ValidateConfig(t *testing.T) {
 func TestBuildkite_ConfigureError(t *testing.T) {
 	ci := &Buildkite{}
 
-	err := ci.Configure()
+	err := ci.Configure(report.Nop)
 	assert.EqualError(t, err, "Invalid token, empty string supplied")
 }
 
 func TestBuildkite_Configure(t *testing.T) {
 	ci := &Buildkite{Token: "abc"}
 
-	err := ci.Configure()
+	err := ci.Configure(report.Nop)
 	assert.NoError(t, err)
 }
 
+func TestBuildkite_Configure_Retries_Rate_Limited(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"name":"user"}`)
+	}))
+	defer server.Close()
+	out := &bytes.Buffer{}
+	client, err := (&Buildkite{Token: "abc"}).client(report.NewText(out, true))
+	assert.NoError(t, err)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	user, _, err := client.User.Get()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user", *user.Name)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "\x1b[0m\x1b[33mRate limit of \x1b[39m\x1b[97m\x1b[1mBuildkite\x1b[0m\x1b[97m\x1b[39m \x1b[33mreached, retrying in \x1b[39m\x1b[97m\x1b[1m0s\x1b[0m\x1b[97m\x1b[39m \x1b[33m(attempt \x1b[39m\x1b[97m\x1b[1m2\x1b[0m\x1b[97m\x1b[39m \x1b[33mof \x1b[39m\x1b[97m\x1b[1m5\x1b[0m\x1b[97m\x1b[39m\x1b[33m)\x1b[39m\n\x1b[0m", out.String())
+}
+
 func TestBuildkite_Validate_User_Not_Exist(t *testing.T) {
 	ci := &Buildkite{userService: &mockUserService{err: errors.New("unauthorized")}}
 
-	err := ci.Validate("Project")
+	err := ci.Validate(context.Background(), "Project")
 
 	assert.EqualError(t, err, "unauthorized")
 }
 
+func TestBuildkite_Validate_Cancelled(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	ci := &Buildkite{userService: &mockUserService{block: block}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := ci.Validate(ctx, "Project")
+
+	assert.EqualError(t, err, "context canceled")
+	assert.Equal(t, failure.Interrupted, err.(*failure.Error).Kind)
+}
+
+func TestBuildkite_Validate_Cancels_Request(t *testing.T) {
+	cancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	defer server.Close()
+	ci := &Buildkite{Token: "abc"}
+	client, _ := ci.client(report.Nop)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	ci.userService = client.User
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := ci.Validate(ctx, "Project")
+
+	assert.EqualError(t, err, "context deadline exceeded")
+	assert.Equal(t, failure.Remote, err.(*failure.Error).Kind)
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("request was not cancelled")
+	}
+}
+
+func TestBuildkite_Validate_Cancels_Retry_Wait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	ci := &Buildkite{Token: "abc"}
+	client, _ := ci.client(report.Nop)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	ci.userService = client.User
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+
+	err := ci.Validate(ctx, "Project")
+
+	assert.EqualError(t, err, "context canceled")
+	assert.Equal(t, failure.Interrupted, err.(*failure.Error).Kind)
+	assert.True(t, time.Since(start) < 10*time.Second)
+}
+
 func TestBuildkite_Validate_Organisation_Not_Exist(t *testing.T) {
 	ci := &Buildkite{
 		userService:         &mockUserService{},
 		organizationService: &mockOrganizationService{err: errors.New("not found")},
 	}
 
-	err := ci.Validate("Project")
+	err := ci.Validate(context.Background(), "Project")
 
 	assert.EqualError(t, err, "not found")
 }
@@ -77,7 +169,7 @@ func TestBuildkite_Validate_Error_Getting_Pipeline(t *testing.T) {
 		},
 	}
 
-	err := ci.Validate("Project")
+	err := ci.Validate(context.Background(), "Project")
 
 	assert.EqualError(t, err, "error")
 }
@@ -92,7 +184,23 @@ func TestBuildkite_Validate_Pipeline_Already_Exists(t *testing.T) {
 		},
 	}
 
-	err := ci.Validate("Project")
+	err := ci.Validate(context.Background(), "Project")
+
+	assert.EqualError(t, err, "pipeline named 'org/Project' already exists at Buildkite")
+	assert.Equal(t, 5, failure.ExitCode(err))
+}
+
+func TestBuildkite_Adopt_Pipeline_Already_Exists(t *testing.T) {
+	ci := &Buildkite{
+		Organisation:        "org",
+		userService:         &mockUserService{},
+		organizationService: &mockOrganizationService{},
+		pipelineService: &mockPipelineService{
+			pipeline: pipeline("", "", ""),
+		},
+	}
+
+	err := ci.Adopt(context.Background(), "group/Project")
 
 	assert.EqualError(t, err, "pipeline named 'org/Project' already exists at Buildkite")
 }
@@ -107,7 +215,7 @@ func TestBuildkite_Validate_Ok(t *testing.T) {
 		},
 	}
 
-	err := ci.Validate("Project")
+	err := ci.Validate(context.Background(), "Project")
 
 	assert.NoError(t, err)
 }
@@ -121,7 +229,7 @@ func TestBuildkite_Scaffold_WriteError_PipelineYml(t *testing.T) {
 	name := filepath.Join(dir, ".buildkite")
 	_ = ioutil.WriteFile(name, []byte("abc"), 0666)
 
-	_, err := ci.Scaffold(dir, templating.TemplateData{})
+	_, err := ci.Scaffold(context.Background(), osfs.New(dir), templating.TemplateData{})
 
 	assert.EqualError(t, err, fmt.Sprintf("mkdir %s: not a directory", name))
 }
@@ -135,7 +243,7 @@ func TestBuildkite_Scaffold_WriteError_DockerIgnore(t *testing.T) {
 	name := filepath.Join(dir, ".dockerignore")
 	_ = os.MkdirAll(name, 0777)
 
-	_, err := ci.Scaffold(dir, templating.TemplateData{})
+	_, err := ci.Scaffold(context.Background(), osfs.New(dir), templating.TemplateData{})
 
 	assert.EqualError(t, err, fmt.Sprintf("open %s: is a directory", name))
 }
@@ -146,7 +254,7 @@ func TestBuildkite_Scaffold_CreateError(t *testing.T) {
 	dir, _ := ioutil.TempDir(os.TempDir(), "scaffold")
 	defer func() { _ = os.RemoveAll(dir) }()
 
-	_, err := ci.Scaffold(dir, templating.TemplateData{})
+	_, err := ci.Scaffold(context.Background(), osfs.New(dir), templating.TemplateData{})
 
 	assert.EqualError(t, err, "create error")
 }
@@ -163,7 +271,7 @@ func TestBuildkite_Scaffold_Create_Github(t *testing.T) {
 		RepositoryHost: "github.com",
 		RepositoryUrl:  "git@repo/",
 	}
-	hook, err := ci.Scaffold(dir, data)
+	hook, err := ci.Scaffold(context.Background(), osfs.New(dir), data)
 
 	assert.NoError(t, err)
 	expected := &buildkite.CreatePipeline{
@@ -203,7 +311,7 @@ func TestBuildkite_Scaffold_Create_Other(t *testing.T) {
 		RepositoryHost: "gitlab.com",
 		RepositoryUrl:  "git@repo/",
 	}
-	hook, err := ci.Scaffold(dir, data)
+	hook, err := ci.Scaffold(context.Background(), osfs.New(dir), data)
 
 	assert.NoError(t, err)
 	expected := &buildkite.CreatePipeline{
@@ -227,7 +335,7 @@ func TestBuildkite_Scaffold_Create_Other(t *testing.T) {
 func TestBadges_Buildkite_Error(t *testing.T) {
 	ci := &Buildkite{pipelineService: &mockPipelineService{getErr: errors.New("get error")}}
 
-	_, err := ci.Badges("Project")
+	_, err := ci.Badges(context.Background(), "Project")
 
 	assert.EqualError(t, err, "get error")
 }
@@ -235,7 +343,7 @@ func TestBadges_Buildkite_Error(t *testing.T) {
 func TestBadges_Buildkite(t *testing.T) {
 	ci := &Buildkite{pipelineService: &mockPipelineService{pipeline: pipeline("https://hookUrl", "https://img", "https://link")}}
 
-	badges, err := ci.Badges("Project")
+	badges, err := ci.Badges(context.Background(), "Project")
 
 	expected := []templating.Badge{
 		{
@@ -248,6 +356,16 @@ func TestBadges_Buildkite(t *testing.T) {
 	assert.Equal(t, expected, badges)
 }
 
+func TestBuildkite_DeletePipeline(t *testing.T) {
+	service := &mockPipelineService{deleteErr: errors.New("delete error")}
+	ci := &Buildkite{Organisation: "org", pipelineService: service}
+
+	err := ci.DeletePipeline(context.Background(), "project")
+
+	assert.EqualError(t, err, "delete error")
+	assert.Equal(t, "org/project", service.deleted)
+}
+
 func pipeline(hookUrl, badgeUrl, webUrl string) *buildkite.Pipeline {
 	return &buildkite.Pipeline{
 		BadgeURL: wrappers.String(badgeUrl),
@@ -256,12 +374,69 @@ func pipeline(hookUrl, badgeUrl, webUrl string) *buildkite.Pipeline {
 	}
 }
 
+func TestBuildkite_Configure_Error_Has_Hint(t *testing.T) {
+	ci := &Buildkite{}
+
+	err := ci.Configure(report.Nop)
+
+	assert.Equal(t, 3, failure.ExitCode(err))
+	assert.Equal(t, "set ci.buildkite.token in .scaffold.yaml or BUILDKITE_TOKEN", err.(*failure.Error).Hint)
+}
+
+func TestBuildkite_Validate_Unauthorized(t *testing.T) {
+	response := &buildkite.Response{Response: &http.Response{StatusCode: http.StatusUnauthorized}}
+	ci := &Buildkite{userService: &mockUserService{err: errors.New("401 Authentication required"), response: response}}
+
+	err := ci.Validate(context.Background(), "Project")
+
+	assert.Equal(t, &failure.Error{
+		Kind:     failure.Auth,
+		Provider: "Buildkite",
+		Status:   http.StatusUnauthorized,
+		Hint:     "the token for Buildkite is invalid or has expired",
+		Err:      errors.New("401 Authentication required"),
+	}, err)
+}
+
+func TestBuildkite_Check_Invalid_Token(t *testing.T) {
+	ci := &Buildkite{userService: &mockUserService{err: errors.New("unauthorized")}}
+
+	assert.Equal(t, []string{"token: unauthorized"}, checks(ci))
+}
+
+func TestBuildkite_Check(t *testing.T) {
+	ci := &Buildkite{
+		Organisation:        "org",
+		userService:         &mockUserService{},
+		organizationService: &mockOrganizationService{err: errors.New("not found")},
+	}
+
+	assert.Equal(t, []string{"token: ok", "organisation 'org': not found"}, checks(ci))
+}
+
+func checks(c CI) []string {
+	var result []string
+	c.Check(context.Background(), func(check string, err error) {
+		if err != nil {
+			result = append(result, fmt.Sprintf("%s: %s", check, err.Error()))
+		} else {
+			result = append(result, fmt.Sprintf("%s: ok", check))
+		}
+	})
+	return result
+}
+
 type mockUserService struct {
-	err error
+	err      error
+	response *buildkite.Response
+	block    chan struct{}
 }
 
 func (m mockUserService) Get() (*buildkite.User, *buildkite.Response, error) {
-	return nil, nil, m.err
+	if m.block != nil {
+		<-m.block
+	}
+	return nil, m.response, m.err
 }
 
 var _ userService = &mockUserService{}
@@ -279,8 +454,10 @@ var _ organizationService = &mockOrganizationService{}
 type mockPipelineService struct {
 	createErr error
 	getErr    error
+	deleteErr error
 	pipeline  *buildkite.Pipeline
 	create    *buildkite.CreatePipeline
+	deleted   string
 	response  *buildkite.Response
 }
 
@@ -293,4 +470,9 @@ func (m *mockPipelineService) Get(org string, slug string) (*buildkite.Pipeline,
 	return m.pipeline, m.response, m.getErr
 }
 
+func (m *mockPipelineService) Delete(org string, slug string) (*buildkite.Response, error) {
+	m.deleted = fmt.Sprintf("%s/%s", org, slug)
+	return m.response, m.deleteErr
+}
+
 var _ pipelineService = &mockPipelineService{}
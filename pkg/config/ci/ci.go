@@ -1,14 +1,23 @@
 package ci
 
 import (
+	"context"
+	"github.com/buildtool/scaffold/pkg/report"
 	"github.com/buildtool/scaffold/pkg/templating"
+	"gopkg.in/src-d/go-billy.v4"
 )
 
 type CI interface {
 	Name() string
 	ValidateConfig() error
-	Validate(name string) error
-	Scaffold(dir string, data templating.TemplateData) (*string, error)
-	Badges(name string) ([]templating.Badge, error)
-	Configure() error
+	Validate(ctx context.Context, name string) error
+	// Adopt checks that a build pipeline can be added to the existing repository at path, group/name, and takes the
+	// settings that depend on the repository from path
+	Adopt(ctx context.Context, path string) error
+	Check(ctx context.Context, report func(check string, err error))
+	Scaffold(ctx context.Context, fs billy.Filesystem, data templating.TemplateData) (*string, error)
+	Badges(ctx context.Context, name string) ([]templating.Badge, error)
+	DeletePipeline(ctx context.Context, name string) error
+	Configure(r report.Reporter) error
+	DryRun(r report.Reporter)
 }
@@ -0,0 +1,102 @@
+package ci
+
+import (
+	"errors"
+	"fmt"
+	"github.com/buildkite/go-buildkite/buildkite"
+	"github.com/buildtool/scaffold/pkg/dryrun"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/buildtool/scaffold/pkg/wrappers"
+	"github.com/xanzy/go-gitlab"
+	"net/http"
+)
+
+const buildkiteApi = "https://api.buildkite.com/v2/"
+
+type dryRunPipelines struct {
+	reporter  report.Reporter
+	pipelines map[string]*buildkite.Pipeline
+}
+
+func (p *dryRunPipelines) Create(org string, pipeline *buildkite.CreatePipeline) (*buildkite.Pipeline, *buildkite.Response, error) {
+	dryrun.Request(p.reporter, http.MethodPost, fmt.Sprintf("%sorganizations/%s/pipelines", buildkiteApi, org), pipeline)
+	created := &buildkite.Pipeline{
+		Name:     wrappers.String(pipeline.Name),
+		WebURL:   wrappers.String(fmt.Sprintf("https://buildkite.com/%s/%s", org, pipeline.Name)),
+		BadgeURL: wrappers.String(fmt.Sprintf("https://badge.buildkite.com/%s.svg", pipeline.Name)),
+		Provider: &buildkite.Provider{WebhookURL: wrappers.String("https://webhook.buildkite.com/deliver/dry-run")},
+	}
+	p.pipelines[pipeline.Name] = created
+	return created, buildkiteResponse(http.StatusCreated), nil
+}
+
+func (p *dryRunPipelines) Get(org string, slug string) (*buildkite.Pipeline, *buildkite.Response, error) {
+	if pipeline, exists := p.pipelines[slug]; exists {
+		return pipeline, buildkiteResponse(http.StatusOK), nil
+	}
+	return nil, buildkiteResponse(http.StatusNotFound), errors.New("404 Not Found")
+}
+
+func (p *dryRunPipelines) Delete(org string, slug string) (*buildkite.Response, error) {
+	dryrun.Request(p.reporter, http.MethodDelete, fmt.Sprintf("%sorganizations/%s/pipelines/%s", buildkiteApi, org, slug), nil)
+	delete(p.pipelines, slug)
+	return buildkiteResponse(http.StatusNoContent), nil
+}
+
+var _ pipelineService = &dryRunPipelines{}
+
+type dryRunUser struct{}
+
+func (u *dryRunUser) Get() (*buildkite.User, *buildkite.Response, error) {
+	return &buildkite.User{}, buildkiteResponse(http.StatusOK), nil
+}
+
+var _ userService = &dryRunUser{}
+
+type dryRunOrganizations struct{}
+
+func (o *dryRunOrganizations) Get(slug string) (*buildkite.Organization, *buildkite.Response, error) {
+	return &buildkite.Organization{Slug: wrappers.String(slug)}, buildkiteResponse(http.StatusOK), nil
+}
+
+var _ organizationService = &dryRunOrganizations{}
+
+func buildkiteResponse(status int) *buildkite.Response {
+	return &buildkite.Response{Response: &http.Response{StatusCode: status}}
+}
+
+type dryRunBadges struct{}
+
+func (b *dryRunBadges) ListProjectBadges(pid interface{}, opt *gitlab.ListProjectBadgesOptions, options ...gitlab.OptionFunc) ([]*gitlab.ProjectBadge, *gitlab.Response, error) {
+	return nil, gitlabResponse(http.StatusOK), nil
+}
+
+var _ badgesService = &dryRunBadges{}
+
+type dryRunUsers struct{}
+
+func (u *dryRunUsers) CurrentUser(options ...gitlab.OptionFunc) (*gitlab.User, *gitlab.Response, error) {
+	return &gitlab.User{}, gitlabResponse(http.StatusOK), nil
+}
+
+var _ usersService = &dryRunUsers{}
+
+type dryRunGroups struct{}
+
+func (g *dryRunGroups) GetGroup(gid interface{}, options ...gitlab.OptionFunc) (*gitlab.Group, *gitlab.Response, error) {
+	return &gitlab.Group{FullPath: fmt.Sprint(gid)}, gitlabResponse(http.StatusOK), nil
+}
+
+var _ groupsService = &dryRunGroups{}
+
+type dryRunProjects struct{}
+
+func (p *dryRunProjects) GetProject(pid interface{}, opt *gitlab.GetProjectOptions, options ...gitlab.OptionFunc) (*gitlab.Project, *gitlab.Response, error) {
+	return nil, gitlabResponse(http.StatusNotFound), errors.New("404 Project Not Found")
+}
+
+var _ projectsService = &dryRunProjects{}
+
+func gitlabResponse(status int) *gitlab.Response {
+	return &gitlab.Response{Response: &http.Response{StatusCode: status}}
+}
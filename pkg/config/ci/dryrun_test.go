@@ -0,0 +1,57 @@
+package ci
+
+import (
+	"bytes"
+	"context"
+	"github.com/buildtool/scaffold/pkg/file"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/buildtool/scaffold/pkg/templating"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"testing"
+)
+
+func TestBuildkite_DryRun(t *testing.T) {
+	out := &bytes.Buffer{}
+	ci := &Buildkite{Organisation: "org"}
+	ci.DryRun(report.NewText(out, true))
+	fs := memfs.New()
+
+	assert.NoError(t, ci.Validate(context.Background(), "project"))
+	hook, err := ci.Scaffold(context.Background(), fs, templating.TemplateData{ProjectName: "project", RepositoryHost: "github.com", RepositoryUrl: "git@github.com:org/project.git"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://webhook.buildkite.com/deliver/dry-run", *hook)
+	badges, err := ci.Badges(context.Background(), "project")
+	assert.NoError(t, err)
+	assert.Equal(t, []templating.Badge{{Title: "Build status", ImageUrl: "https://badge.buildkite.com/project.svg", LinkUrl: "https://buildkite.com/org/project"}}, badges)
+
+	assert.NoError(t, ci.DeletePipeline(context.Background(), "project"))
+
+	assert.Contains(t, out.String(), "POST https://api.buildkite.com/v2/organizations/org/pipelines")
+	assert.Contains(t, out.String(), "DELETE https://api.buildkite.com/v2/organizations/org/pipelines/project")
+	assert.Contains(t, out.String(), "\"repository\": \"git@github.com:org/project.git\"")
+	content, err := file.Read(fs, ".buildkite/pipeline.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, pipelineYml[1:], content)
+}
+
+func TestGitlab_DryRun(t *testing.T) {
+	out := &bytes.Buffer{}
+	ci := &Gitlab{Group: "group"}
+	ci.DryRun(report.NewText(out, true))
+	fs := memfs.New()
+
+	assert.NoError(t, ci.Validate(context.Background(), "project"))
+	hook, err := ci.Scaffold(context.Background(), fs, templating.TemplateData{ProjectName: "Project"})
+	assert.NoError(t, err)
+	assert.Nil(t, hook)
+	badges, err := ci.Badges(context.Background(), "project")
+	assert.NoError(t, err)
+	assert.Empty(t, badges)
+	assert.NoError(t, ci.DeletePipeline(context.Background(), "project"))
+
+	assert.Equal(t, "", out.String())
+	content, err := file.Read(fs, ".gitlab-ci.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, expectedGitlabCiYml, content)
+}
@@ -1,11 +1,18 @@
 package ci
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/buildtool/scaffold/pkg/failure"
 	"github.com/buildtool/scaffold/pkg/file"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/buildtool/scaffold/pkg/retry"
 	"github.com/buildtool/scaffold/pkg/templating"
 	"github.com/xanzy/go-gitlab"
+	"gopkg.in/src-d/go-billy.v4"
+	"net/http"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -48,41 +55,75 @@ func (c *Gitlab) ValidateConfig() error {
 	return nil
 }
 
-func (c *Gitlab) Validate(name string) error {
-	_, _, err := c.usersService.CurrentUser()
-	if err != nil {
-		return err
-	}
-	_, _, err = c.groupsService.GetGroup(c.Group)
-	if err != nil {
+func (c *Gitlab) Validate(ctx context.Context, name string) error {
+	if err := c.access(ctx); err != nil {
 		return err
 	}
 	path := filepath.Join(c.Group, name)
-	project, response, err := c.projectsService.GetProject(path, nil)
+	project, response, err := c.projectsService.GetProject(path, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		if response == nil || response.StatusCode != 404 {
-			return err
+			return c.apiError(response, err)
 		}
 	}
 	if project != nil {
-		return fmt.Errorf("project named '%s/%s' already exists at Gitlab", c.Group, name)
+		return &failure.Error{
+			Kind:     failure.Conflict,
+			Provider: c.Name(),
+			Hint:     "choose another name or remove the existing project",
+			Err:      fmt.Errorf("project named '%s/%s' already exists at Gitlab", c.Group, name),
+		}
 	}
 	return nil
 }
 
-func (c *Gitlab) Scaffold(dir string, data templating.TemplateData) (*string, error) {
-	if err := file.WriteTemplated(dir, ".gitlab-ci.yml", gitlabCiYml, data); err != nil {
-		return nil, err
+// Adopt uses the group of the repository, the project is the repository itself so it exists already
+func (c *Gitlab) Adopt(ctx context.Context, repoPath string) error {
+	c.Group = path.Dir(repoPath)
+	return c.access(ctx)
+}
+
+// access checks that the token is valid and can access the group
+func (c *Gitlab) access(ctx context.Context) error {
+	_, response, err := c.usersService.CurrentUser(gitlab.WithContext(ctx))
+	if err != nil {
+		return c.apiError(response, err)
+	}
+	_, response, err = c.groupsService.GetGroup(c.Group, gitlab.WithContext(ctx))
+	if err != nil {
+		return c.apiError(response, err)
+	}
+	return nil
+}
+
+func (c *Gitlab) Check(ctx context.Context, report func(check string, err error)) {
+	_, response, err := c.usersService.CurrentUser(gitlab.WithContext(ctx))
+	if err != nil {
+		report("token", c.apiError(response, err))
+		return
+	}
+	report("token", nil)
+	_, response, err = c.groupsService.GetGroup(c.Group, gitlab.WithContext(ctx))
+	if err != nil {
+		report(fmt.Sprintf("group '%s'", c.Group), c.apiError(response, err))
+		return
+	}
+	report(fmt.Sprintf("group '%s'", c.Group), nil)
+}
+
+func (c *Gitlab) Scaffold(ctx context.Context, fs billy.Filesystem, data templating.TemplateData) (*string, error) {
+	if err := file.WriteTemplated(fs, ".gitlab-ci.yml", gitlabCiYml, data); err != nil {
+		return nil, failure.Wrap(failure.Filesystem, "", err)
 	}
 	return nil, nil
 }
 
-func (c *Gitlab) Badges(name string) ([]templating.Badge, error) {
+func (c *Gitlab) Badges(ctx context.Context, name string) ([]templating.Badge, error) {
 	path := filepath.Join(c.Group, name)
 
-	badges, _, err := c.badgesService.ListProjectBadges(path, nil)
+	badges, response, err := c.badgesService.ListProjectBadges(path, nil, gitlab.WithContext(ctx))
 	if err != nil {
-		return nil, err
+		return nil, c.apiError(response, err)
 	}
 	result := make([]templating.Badge, len(badges))
 	for i, b := range badges {
@@ -101,8 +142,20 @@ func (c *Gitlab) Badges(name string) ([]templating.Badge, error) {
 	return result, nil
 }
 
-func (c *Gitlab) Configure() error {
-	git := gitlab.NewClient(nil, c.Token)
+func (c *Gitlab) apiError(response *gitlab.Response, err error) *failure.Error {
+	code := 0
+	if response != nil && response.Response != nil {
+		code = response.StatusCode
+	}
+	return failure.API(c.Name(), code, err)
+}
+
+func (c *Gitlab) DeletePipeline(ctx context.Context, name string) error {
+	return nil
+}
+
+func (c *Gitlab) Configure(r report.Reporter) error {
+	git := c.client(r)
 	c.badgesService = git.ProjectBadges
 	c.usersService = git.Users
 	c.groupsService = git.Groups
@@ -110,6 +163,17 @@ func (c *Gitlab) Configure() error {
 	return nil
 }
 
+func (c *Gitlab) client(r report.Reporter) *gitlab.Client {
+	return gitlab.NewClient(&http.Client{Transport: retry.New(nil, c.Name(), r)}, c.Token)
+}
+
+func (c *Gitlab) DryRun(r report.Reporter) {
+	c.badgesService = &dryRunBadges{}
+	c.usersService = &dryRunUsers{}
+	c.groupsService = &dryRunGroups{}
+	c.projectsService = &dryRunProjects{}
+}
+
 var gitlabCiYml = `
 stages:
   - build
@@ -1,13 +1,18 @@
 package ci
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"github.com/buildtool/scaffold/pkg/report"
 	"github.com/buildtool/scaffold/pkg/templating"
 	"github.com/stretchr/testify/assert"
 	"github.com/xanzy/go-gitlab"
+	"gopkg.in/src-d/go-billy.v4/osfs"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -38,14 +43,38 @@ func TestGitlab_ValidateConfig(t *testing.T) {
 func TestGitlab_Configure(t *testing.T) {
 	ci := &Gitlab{}
 
-	err := ci.Configure()
+	err := ci.Configure(report.Nop)
 	assert.NoError(t, err)
 }
 
+func TestGitlab_Configure_Retries_Rate_Limited(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"username":"user"}`)
+	}))
+	defer server.Close()
+	out := &bytes.Buffer{}
+	client := (&Gitlab{Token: "abc"}).client(report.NewText(out, true))
+	_ = client.SetBaseURL(server.URL)
+
+	user, _, err := client.Users.CurrentUser()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user", user.Username)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "\x1b[0m\x1b[33mRate limit of \x1b[39m\x1b[97m\x1b[1mGitlab\x1b[0m\x1b[97m\x1b[39m \x1b[33mreached, retrying in \x1b[39m\x1b[97m\x1b[1m0s\x1b[0m\x1b[97m\x1b[39m \x1b[33m(attempt \x1b[39m\x1b[97m\x1b[1m2\x1b[0m\x1b[97m\x1b[39m \x1b[33mof \x1b[39m\x1b[97m\x1b[1m5\x1b[0m\x1b[97m\x1b[39m\x1b[33m)\x1b[39m\n\x1b[0m", out.String())
+}
+
 func TestGitlab_Validate_User_Not_Exist(t *testing.T) {
 	ci := &Gitlab{usersService: &mockUsersService{err: errors.New("unauthorized")}}
 
-	err := ci.Validate("Project")
+	err := ci.Validate(context.Background(), "Project")
 
 	assert.EqualError(t, err, "unauthorized")
 }
@@ -56,7 +85,7 @@ func TestGitlab_Validate_Organisation_Not_Exist(t *testing.T) {
 		groupsService: &mockGroups{err: errors.New("not found")},
 	}
 
-	err := ci.Validate("Project")
+	err := ci.Validate(context.Background(), "Project")
 
 	assert.EqualError(t, err, "not found")
 }
@@ -71,7 +100,7 @@ func TestGitlab_Validate_Error_Getting_Pipeline(t *testing.T) {
 		},
 	}
 
-	err := ci.Validate("Project")
+	err := ci.Validate(context.Background(), "Project")
 
 	assert.EqualError(t, err, "error")
 }
@@ -86,11 +115,29 @@ func TestGitlab_Validate_Pipeline_Already_Exists(t *testing.T) {
 		},
 	}
 
-	err := ci.Validate("Project")
+	err := ci.Validate(context.Background(), "Project")
 
 	assert.EqualError(t, err, "project named 'org/Project' already exists at Gitlab")
 }
 
+func TestGitlab_Adopt_Existing_Project(t *testing.T) {
+	groups := &mockGroups{}
+	ci := &Gitlab{
+		Group:         "configured",
+		usersService:  &mockUsersService{},
+		groupsService: groups,
+		projectsService: &mockProjects{
+			project: &gitlab.Project{},
+		},
+	}
+
+	err := ci.Adopt(context.Background(), "other/sub/project")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "other/sub", ci.Group)
+	assert.Equal(t, "other/sub", groups.gid)
+}
+
 func TestGitlab_Validate_Ok(t *testing.T) {
 	ci := &Gitlab{
 		usersService:  &mockUsersService{},
@@ -101,7 +148,7 @@ func TestGitlab_Validate_Ok(t *testing.T) {
 		},
 	}
 
-	err := ci.Validate("Project")
+	err := ci.Validate(context.Background(), "Project")
 
 	assert.NoError(t, err)
 }
@@ -115,7 +162,7 @@ func TestGitlab_Scaffold_Error(t *testing.T) {
 
 	ci := &Gitlab{}
 
-	_, err := ci.Scaffold(name, templating.TemplateData{})
+	_, err := ci.Scaffold(context.Background(), osfs.New(name), templating.TemplateData{})
 	assert.EqualError(t, err, fmt.Sprintf("mkdir %s: not a directory", name))
 }
 
@@ -125,7 +172,7 @@ func TestGitlab_Scaffold(t *testing.T) {
 
 	ci := &Gitlab{}
 
-	_, err := ci.Scaffold(dir, templating.TemplateData{ProjectName: "Project"})
+	_, err := ci.Scaffold(context.Background(), osfs.New(dir), templating.TemplateData{ProjectName: "Project"})
 	assert.NoError(t, err)
 
 	buff, err := ioutil.ReadFile(filepath.Join(dir, ".gitlab-ci.yml"))
@@ -136,7 +183,7 @@ func TestGitlab_Scaffold(t *testing.T) {
 func TestGitlab_Badges_Error(t *testing.T) {
 	ci := &Gitlab{badgesService: &mockBadges{err: errors.New("badge error")}}
 
-	_, err := ci.Badges("project")
+	_, err := ci.Badges(context.Background(), "project")
 	assert.EqualError(t, err, "badge error")
 }
 
@@ -151,7 +198,7 @@ func TestGitlab_Badges(t *testing.T) {
 		},
 	}
 
-	badges, err := ci.Badges("project")
+	badges, err := ci.Badges(context.Background(), "project")
 	assert.NoError(t, err)
 	expected := []templating.Badge{
 		{Title: "Build status", ImageUrl: "https://buildimg", LinkUrl: "https://buildlink"},
@@ -161,6 +208,30 @@ func TestGitlab_Badges(t *testing.T) {
 	assert.Equal(t, expected, badges)
 }
 
+func TestGitlab_DeletePipeline(t *testing.T) {
+	ci := &Gitlab{}
+
+	err := ci.DeletePipeline(context.Background(), "project")
+
+	assert.NoError(t, err)
+}
+
+func TestGitlab_Check_Invalid_Token(t *testing.T) {
+	ci := &Gitlab{usersService: &mockUsersService{err: errors.New("unauthorized")}}
+
+	assert.Equal(t, []string{"token: unauthorized"}, checks(ci))
+}
+
+func TestGitlab_Check(t *testing.T) {
+	ci := &Gitlab{
+		Group:         "group",
+		usersService:  &mockUsersService{},
+		groupsService: &mockGroups{},
+	}
+
+	assert.Equal(t, []string{"token: ok", "group 'group': ok"}, checks(ci))
+}
+
 type mockUsersService struct {
 	err error
 }
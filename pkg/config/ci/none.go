@@ -0,0 +1,49 @@
+package ci
+
+import (
+	"context"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/buildtool/scaffold/pkg/templating"
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+// None creates no build pipeline and writes no CI files
+type None struct{}
+
+var _ CI = &None{}
+
+func (c *None) Name() string {
+	return "None"
+}
+
+func (c *None) ValidateConfig() error {
+	return nil
+}
+
+func (c *None) Validate(ctx context.Context, name string) error {
+	return nil
+}
+
+func (c *None) Adopt(ctx context.Context, path string) error {
+	return nil
+}
+
+func (c *None) Check(ctx context.Context, report func(check string, err error)) {}
+
+func (c *None) Scaffold(ctx context.Context, fs billy.Filesystem, data templating.TemplateData) (*string, error) {
+	return nil, nil
+}
+
+func (c *None) Badges(ctx context.Context, name string) ([]templating.Badge, error) {
+	return nil, nil
+}
+
+func (c *None) DeletePipeline(ctx context.Context, name string) error {
+	return nil
+}
+
+func (c *None) Configure(r report.Reporter) error {
+	return nil
+}
+
+func (c *None) DryRun(r report.Reporter) {}
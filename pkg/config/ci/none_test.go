@@ -0,0 +1,27 @@
+package ci
+
+import (
+	"context"
+	"github.com/buildtool/scaffold/pkg/templating"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"testing"
+)
+
+func TestNone_Scaffold(t *testing.T) {
+	fs := memfs.New()
+
+	webhook, err := (&None{}).Scaffold(context.Background(), fs, templating.TemplateData{ProjectName: "project"})
+
+	assert.NoError(t, err)
+	assert.Nil(t, webhook)
+	files, _ := fs.ReadDir("")
+	assert.Empty(t, files)
+}
+
+func TestNone_Badges(t *testing.T) {
+	badges, err := (&None{}).Badges(context.Background(), "project")
+
+	assert.NoError(t, err)
+	assert.Empty(t, badges)
+}
@@ -1,144 +1,595 @@
 package config
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/buildtool/scaffold/pkg/config/ci"
 	"github.com/buildtool/scaffold/pkg/config/vcs"
+	"github.com/buildtool/scaffold/pkg/dryrun"
+	"github.com/buildtool/scaffold/pkg/events"
+	"github.com/buildtool/scaffold/pkg/failure"
 	"github.com/buildtool/scaffold/pkg/file"
+	"github.com/buildtool/scaffold/pkg/report"
 	"github.com/buildtool/scaffold/pkg/stack"
 	"github.com/buildtool/scaffold/pkg/templating"
 	"github.com/caarlos0/env"
 	"github.com/imdario/mergo"
-	"github.com/liamg/tml"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/osfs"
 	"gopkg.in/yaml.v2"
-	"io"
-	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 )
 
 type Config struct {
-	VCS          *VCSConfig `yaml:"vcs"`
-	CI           *CIConfig  `yaml:"ci"`
-	RegistryUrl  string     `yaml:"registry" env:"REGISTRY"`
-	Organisation string     `yaml:"organisation"`
-	CurrentCI    ci.CI
-	CurrentVCS   vcs.VCS
+	VCS           *VCSConfig          `yaml:"vcs"`
+	CI            *CIConfig           `yaml:"ci"`
+	RegistryUrl   string              `yaml:"registry" env:"REGISTRY"`
+	Organisation  string              `yaml:"organisation"`
+	Profile       string              `yaml:"profile,omitempty"`
+	Profiles      map[string]*Profile `yaml:"profiles,omitempty"`
+	KeepOnFailure bool                `yaml:"-"`
+	Commit        vcs.Commit          `yaml:"-"`
+	Listener      events.Listener     `yaml:"-"`
+	CurrentCI     ci.CI               `yaml:"-"`
+	CurrentVCS    vcs.VCS             `yaml:"-"`
+	journal       *journal
+	sources       sources
+	references    map[string]string
+	dir           string
+	trusted       map[string]bool
+	local         bool
 }
 
 type VCSConfig struct {
-	Github *vcs.Github `yaml:"github"`
-	Gitlab *vcs.Gitlab `yaml:"gitlab"`
+	Github  *vcs.Github `yaml:"github"`
+	Gitlab  *vcs.Gitlab `yaml:"gitlab"`
+	Default string      `yaml:"default,omitempty"`
 }
 
 type CIConfig struct {
 	Buildkite *ci.Buildkite `yaml:"buildkite"`
 	Gitlab    *ci.Gitlab    `yaml:"gitlab"`
+	Default   string        `yaml:"default,omitempty"`
 }
 
-func (c *Config) Configure() error {
-	c.CurrentVCS.Configure()
-	return c.CurrentCI.Configure()
+// Selection holds the choices made on the command line, empty fields fall back to the configuration
+type Selection struct {
+	Config  string
+	Profile string
+	VCS     string
+	CI      string
 }
 
+func (c *Config) Configure(r report.Reporter) error {
+	c.CurrentVCS.Configure(r)
+	return c.CurrentCI.Configure(r)
+}
+
+// ValidateConfig picks the providers to create the project with, which unlike Load fails when the choice between several is not made,
+// and resolves their tokens
 func (c *Config) ValidateConfig() error {
+	if !c.local && c.CI != nil && c.VCS != nil {
+		if _, err := selectProvider("CI", "ci", c.CI, c.CI.Default); err != nil {
+			return err
+		}
+		if _, err := selectProvider("VCS", "vcs", c.VCS, c.VCS.Default); err != nil {
+			return err
+		}
+	}
 	if c.CurrentVCS == nil {
 		return errors.New("no VCS configured")
 	}
 	if c.CurrentCI == nil {
 		return errors.New("no CI configured")
 	}
-	return nil
+	return c.ResolveSecrets([]vcs.VCS{c.CurrentVCS}, []ci.CI{c.CurrentCI})
 }
 
-func Load(dir string, out io.Writer) (*Config, error) {
+// Load merges the configuration files, see configFiles and mergeLayers, applies the profile and picks the providers chosen in selection
+// when the choice is clear, and then applies the environment. Tokens that refer to a file, variable or command are left to
+// ResolveSecrets.
+func Load(dir string, selection Selection, r report.Reporter) (*Config, error) {
 	cfg := InitEmptyConfig()
+	cfg.sources = sources{}
+	cfg.dir = dir
+	cfg.trusted = map[string]bool{userConfig(): true}
+	if explicit, err := explicitConfig(dir, selection.Config); err == nil && explicit != "" {
+		cfg.trusted[explicit] = true
+	}
 
-	err := parseConfigFiles(dir, out, func(dir string) error {
-		return parseConfigFile(dir, cfg)
-	})
+	layers, err := readLayers(dir, selection.Config, r)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := mergeLayers(layers, cfg.sources)
+	if err != nil {
+		return nil, err
+	}
+	content, err := yaml.Marshal(doc)
 	if err != nil {
 		return nil, err
 	}
+	if err := parseConfig(content, cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.UseProfile(selection.Profile); err != nil {
+		return nil, err
+	}
+	if selection.Profile != "" {
+		cfg.sources.set("profile", "flag --profile")
+	}
+	if selection.VCS != "" {
+		cfg.VCS.Default = selection.VCS
+		cfg.sources.set("vcs.default", "flag --vcs")
+	}
+	if selection.CI != "" {
+		cfg.CI.Default = selection.CI
+		cfg.sources.set("ci.default", "flag --ci")
+	}
+	if err := selectProviders(cfg); err != nil {
+		return nil, err
+	}
 
-	err = env.Parse(cfg)
+	if err := env.Parse(cfg); err != nil {
+		return nil, err
+	}
+	cfg.sources.environment("", reflect.ValueOf(cfg).Elem())
+	cfg.findReferences()
+	return cfg, nil
+}
+
+// Override applies the values set in overrides, a document in the format of .scaffold.yaml. Unlike the files merged by
+// Load every value that is set wins, also false and empty ones.
+func (c *Config) Override(overrides yaml.MapSlice) error {
+	content, err := yaml.Marshal(overrides)
+	if err != nil {
+		return err
+	}
+	temp := &Config{}
+	if err := yaml.UnmarshalStrict(content, temp); err != nil {
+		return err
+	}
+	override(reflect.ValueOf(c).Elem(), reflect.ValueOf(temp).Elem(), overrides)
+	// The values no longer come from the files, and the manifest is as trusted as the file given with --config
+	for _, path := range leaves("", overrides) {
+		c.sources.clear(path)
+	}
+	c.findReferences()
+	return selectProviders(c)
+}
 
-	return cfg, err
+// override sets the fields of dst that are set in doc to the ones of src, which doc was decoded into
+func override(dst, src reflect.Value, doc yaml.MapSlice) {
+	for _, item := range doc {
+		if item.Value == nil {
+			continue
+		}
+		for i := 0; i < dst.NumField(); i++ {
+			if strings.Split(dst.Type().Field(i).Tag.Get("yaml"), ",")[0] != fmt.Sprint(item.Key) {
+				continue
+			}
+			field := dst.Field(i)
+			nested, ok := item.Value.(yaml.MapSlice)
+			if ok && field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct {
+				if field.IsNil() {
+					field.Set(reflect.New(field.Type().Elem()))
+				}
+				override(field.Elem(), src.Field(i).Elem(), nested)
+			} else {
+				field.Set(src.Field(i))
+			}
+		}
+	}
 }
 
-func (c *Config) Validate(name string) error {
-	if err := c.CurrentVCS.Validate(name); err != nil {
+// Providers returns every configured VCS and CI, whether it is the one chosen or not
+func (c *Config) Providers() ([]vcs.VCS, []ci.CI) {
+	var vcss []vcs.VCS
+	configured, names := configuredProviders(c.VCS)
+	for _, name := range names {
+		vcss = append(vcss, configured[name].(vcs.VCS))
+	}
+	var cis []ci.CI
+	configured, names = configuredProviders(c.CI)
+	for _, name := range names {
+		cis = append(cis, configured[name].(ci.CI))
+	}
+	return vcss, cis
+}
+
+// Local replaces the providers so that nothing is created remotely, the project gets a new local git repository with module as its origin
+func (c *Config) Local(module string) {
+	c.CurrentVCS = &vcs.Local{Module: module}
+	c.CurrentCI = &ci.None{}
+	c.local = true
+}
+
+func (c *Config) Events() events.Listener {
+	if c.Listener == nil {
+		return events.Nop
+	}
+	return c.Listener
+}
+
+func (c *Config) Validate(ctx context.Context, name string) error {
+	// The checks guard against name conflicts, which a resumed run has already passed
+	if c.journal != nil && c.journal.completed(stepRepository) {
+		return nil
+	}
+	if err := c.CurrentVCS.Validate(ctx, name); err != nil {
 		return err
 	}
-	return c.CurrentCI.Validate(name)
+	return c.CurrentCI.Validate(ctx, name)
 }
 
-func (c *Config) Scaffold(dir, name string, stack stack.Stack, out io.Writer) int {
-	_, _ = fmt.Fprint(out, tml.Sprintf("<lightblue>Creating new service </lightblue><white><bold>'%s'</bold></white> <lightblue>using stack </lightblue><white><bold>'%s'</bold></white>\n", name, stack.Name()))
-	_, _ = fmt.Fprint(out, tml.Sprintf("<lightblue>Creating repository at </lightblue><white><bold>'%s'</bold></white>\n", c.CurrentVCS.Name()))
-	repository, err := c.CurrentVCS.Scaffold(name)
+func (c *Config) Resume(dir, name string) error {
+	journal, err := loadJournal(journalPath(dir, name))
 	if err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -7
+		return err
 	}
-	_, _ = fmt.Fprint(out, tml.Sprintf("<green>Created repository </green><white><bold>'%s'</bold></white>\n", repository.SSHURL))
-	if err := c.CurrentVCS.Clone(dir, name, repository.SSHURL, out); err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -8
+	c.journal = journal
+	return nil
+}
+
+func (c *Config) ConfigureDryRun(r report.Reporter) {
+	c.CurrentVCS.DryRun(r)
+	c.CurrentCI.DryRun(r)
+}
+
+func (c *Config) Scaffold(ctx context.Context, dir, name string, stack stack.Stack, r report.Reporter) int {
+	projectDir := filepath.Join(dir, name)
+	journal := c.journal
+	if journal == nil {
+		journal = newJournal(journalPath(dir, name))
 	}
+	return c.scaffold(ctx, name, stack, r, file.Record(osfs.New(projectDir), journal.record), journal, workspace{
+		clone: func(repository *vcs.RepositoryInfo, rollback *rollback) error {
+			if _, err := os.Stat(projectDir); os.IsNotExist(err) {
+				rollback.add(stepClone, fmt.Sprintf("local clone '%s'", projectDir), func() error {
+					return os.RemoveAll(projectDir)
+				})
+			}
+			return c.CurrentVCS.Clone(ctx, dir, name, repository.SSHURL, r.Output())
+		},
+		commit: func(files []string, commit vcs.Commit) error {
+			r.Started("Committing generated files in '%s'", projectDir)
+			return c.CurrentVCS.Commit(dir, name, files, commit)
+		},
+		push: func(repository *vcs.RepositoryInfo) error {
+			r.Started("Pushing to '%s'", repository.SSHURL)
+			return c.CurrentVCS.Push(ctx, dir, name, r.Output())
+		},
+	})
+}
+
+func (c *Config) DryRun(ctx context.Context, dir, name string, stack stack.Stack, r report.Reporter) int {
 	projectDir := filepath.Join(dir, name)
-	_, _ = fmt.Fprint(out, tml.Sprintf("<lightblue>Creating build pipeline for </lightblue><white><bold>'%s'</bold></white>\n", name))
-	badges, err := c.CurrentCI.Badges(name)
-	if err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -9
+	fs := memfs.New()
+	journal := newJournal("")
+	if c.journal != nil {
+		*journal = *c.journal
+		journal.path = ""
 	}
-	parsedUrl, err := url.Parse(repository.HTTPURL)
-	if err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -10
-	}
-	data := templating.TemplateData{
-		ProjectName:    name,
-		Badges:         badges,
-		Organisation:   c.Organisation,
-		RegistryUrl:    c.RegistryUrl,
-		RepositoryUrl:  repository.SSHURL,
-		RepositoryHost: parsedUrl.Host,
-		RepositoryPath: strings.Replace(parsedUrl.Path, ".git", "", 1),
-	}
-	webhook, err := c.CurrentCI.Scaffold(projectDir, data)
-	if err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -11
+	exitCode := c.scaffold(ctx, name, stack, r, file.Record(fs, journal.record), journal, workspace{
+		clone: func(repository *vcs.RepositoryInfo, rollback *rollback) error {
+			r.Warning("Would clone '%s' into '%s'", repository.SSHURL, projectDir)
+			return nil
+		},
+		commit: func(files []string, commit vcs.Commit) error {
+			r.Warning("Would commit %d files with message '%s'", len(files), commit.Message)
+			return nil
+		},
+		push: func(repository *vcs.RepositoryInfo) error {
+			r.Warning("Would push to '%s'", repository.SSHURL)
+			return nil
+		},
+	})
+	if exitCode != 0 {
+		return exitCode
 	}
-	if err := addWebhook(name, webhook, c.CurrentVCS); err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -12
+	if err := dryrun.Files(r, fs, projectDir); err != nil {
+		err := failure.Wrap(failure.Filesystem, "dry-run", err)
+		r.Error(err)
+		return failure.ExitCode(err)
 	}
-	if err := createDotfiles(projectDir); err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -13
+	return 0
+}
+
+// Adopt scaffolds into the existing clone at root instead of creating and cloning a new repository, keeping files that already exist.
+// The generated files are left uncommitted.
+func (c *Config) Adopt(ctx context.Context, root, name string, repository *vcs.RepositoryInfo, stack stack.Stack, r report.Reporter) int {
+	journal := &journal{Steps: []string{stepRepository, stepClone}, Repository: repository}
+	fs := file.NoClobber(file.Record(osfs.New(root), journal.record), func(name string) {
+		r.Warning("Keeping existing '%s'", name)
+	})
+	return c.scaffold(ctx, name, stack, r, fs, journal, workspace{
+		clone: func(*vcs.RepositoryInfo, *rollback) error {
+			return nil
+		},
+	})
+}
+
+// workspace is where the generated files end up, they are only committed and pushed when commit and push are set
+type workspace struct {
+	clone  func(repository *vcs.RepositoryInfo, rollback *rollback) error
+	commit func(files []string, commit vcs.Commit) error
+	push   func(repository *vcs.RepositoryInfo) error
+}
+
+const (
+	stepRepository   = "repository"
+	stepClone        = "clone"
+	stepTemplateData = "template-data"
+	stepPipeline     = "pipeline"
+	stepBadges       = "badges"
+	stepWebhook      = "webhook"
+	stepDotfiles     = "dotfiles"
+	stepReadme       = "readme"
+	stepDeployment   = "deployment"
+	stepStack        = "stack"
+	stepState        = "state"
+	stepCommit       = "commit"
+	stepPush         = "push"
+	stepPullRequest  = "pull-request"
+)
+
+type step struct {
+	name     string
+	kind     failure.Kind
+	provider interface{ Name() string }
+	run      func() error
+}
+
+func (c *Config) scaffold(ctx context.Context, name string, stack stack.Stack, r report.Reporter, fs billy.Filesystem, journal *journal, workspace workspace) int {
+	rollback := &rollback{}
+	var current *events.Step
+	steps := []step{
+		{stepRepository, failure.Remote, c.CurrentVCS, func() (err error) {
+			r.Started("Creating repository at '%s'", c.CurrentVCS.Name())
+			if journal.Repository, err = c.CurrentVCS.Scaffold(ctx, name); err != nil {
+				return err
+			}
+			rollback.add(stepRepository, fmt.Sprintf("repository '%s' at %s", name, c.CurrentVCS.Name()), func() error {
+				return c.CurrentVCS.DeleteRepository(ctx, name)
+			})
+			current.Resources = []string{journal.Repository.SSHURL, journal.Repository.HTTPURL}
+			r.Created("repository", journal.Repository.SSHURL)
+			return nil
+		}},
+		{stepClone, failure.Remote, c.CurrentVCS, func() error {
+			return workspace.clone(journal.Repository, rollback)
+		}},
+		{stepTemplateData, failure.Remote, c.CurrentVCS, func() error {
+			if !c.local {
+				r.Started("Creating build pipeline for '%s'", name)
+			}
+			parsedUrl, err := url.Parse(journal.Repository.HTTPURL)
+			if err != nil {
+				return err
+			}
+			journal.Data = templating.TemplateData{
+				ProjectName:    name,
+				Organisation:   c.Organisation,
+				RegistryUrl:    c.RegistryUrl,
+				RepositoryUrl:  journal.Repository.SSHURL,
+				RepositoryHost: parsedUrl.Host,
+				RepositoryPath: strings.Replace(parsedUrl.Path, ".git", "", 1),
+			}
+			return nil
+		}},
+		{stepPipeline, failure.Remote, c.CurrentCI, func() (err error) {
+			if journal.Webhook, err = c.CurrentCI.Scaffold(ctx, fs, journal.Data); err != nil {
+				return err
+			}
+			rollback.add(stepPipeline, fmt.Sprintf("build pipeline '%s' at %s", name, c.CurrentCI.Name()), func() error {
+				return c.CurrentCI.DeletePipeline(ctx, name)
+			})
+			return nil
+		}},
+		// Badges can only be fetched once the pipeline has been created
+		{stepBadges, failure.Remote, c.CurrentCI, func() (err error) {
+			journal.Data.Badges, err = c.CurrentCI.Badges(ctx, name)
+			current.Badges = journal.Data.Badges
+			return err
+		}},
+		{stepWebhook, failure.Remote, c.CurrentVCS, func() error {
+			if err := addWebhook(ctx, name, journal.Webhook, c.CurrentVCS); err != nil {
+				return err
+			}
+			if journal.Webhook != nil {
+				current.Resources = []string{*journal.Webhook}
+				rollback.add(stepWebhook, fmt.Sprintf("webhook '%s'", *journal.Webhook), func() error {
+					return c.CurrentVCS.DeleteWebhook(ctx, name)
+				})
+			}
+			return nil
+		}},
+		{stepDotfiles, failure.Filesystem, nil, func() error { return createDotfiles(fs) }},
+		{stepReadme, failure.Filesystem, nil, func() error { return createReadme(fs, journal.Data) }},
+		{stepDeployment, failure.Filesystem, nil, func() error { return createDeployment(fs, journal.Data) }},
+		{stepStack, failure.Filesystem, nil, func() error { return stack.Scaffold(fs, journal.Data) }},
+		{stepState, failure.Filesystem, nil, func() error {
+			state, err := newState(fs, stack.Name(), pipelineName(c.CurrentCI), journal.Data, journal.Files)
+			if err != nil {
+				return err
+			}
+			return state.save(fs)
+		}},
+	}
+	if workspace.commit != nil {
+		commit := c.commit()
+		steps = append(steps, []step{
+			{stepCommit, failure.Filesystem, nil, func() error {
+				return workspace.commit(append(journal.Files, stateFile), commit)
+			}},
+			{stepPush, failure.Remote, c.CurrentVCS, func() error {
+				return workspace.push(journal.Repository)
+			}},
+			{stepPullRequest, failure.Remote, c.CurrentVCS, func() error {
+				if commit.Branch == "" {
+					return nil
+				}
+				r.Started("Opening pull request from '%s'", commit.Branch)
+				url, err := c.CurrentVCS.PullRequest(ctx, name, commit.Branch, fmt.Sprintf("Scaffold %s", name), pullRequestBody(stack, c.CurrentCI, journal))
+				if err != nil {
+					return err
+				}
+				current.Resources = []string{url}
+				r.Succeeded("Opened pull request '%s'", url)
+				return nil
+			}},
+		}...)
 	}
-	if err := createReadme(projectDir, data); err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -14
+
+	if c.local {
+		steps = withoutRemoteSteps(steps)
 	}
-	if err := createDeployment(projectDir, data); err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -15
+
+	r.Started("Creating new service '%s' using stack '%s'", name, stack.Name())
+	// The VCS learns the owner of the repository when creating it, which a resumed run has skipped
+	if journal.completed(stepRepository) && journal.Repository != nil {
+		if err := c.CurrentVCS.Adopt(journal.Repository); err != nil {
+			err := failure.Wrap(failure.Config, stepRepository, err)
+			r.Error(err)
+			return failure.ExitCode(err)
+		}
 	}
-	if err := stack.Scaffold(projectDir, data); err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -16
+	resumed := len(journal.Steps)
+	for _, step := range steps {
+		current = &events.Step{Name: step.name}
+		if journal.completed(step.name) {
+			r.Warning("Skipping completed step '%s'", step.name)
+			current.Status = events.Skipped
+			c.Events().Step(*current)
+			continue
+		}
+		start := time.Now()
+		// A step without calls to the providers would not notice the interrupt
+		err := ctx.Err()
+		if err == nil {
+			err = step.run()
+		}
+		if err != nil {
+			err = step.fail(err)
+		} else if err = journal.complete(step.name); err != nil {
+			err = failure.Wrap(failure.Filesystem, step.name, err)
+		}
+		current.DurationMs = events.Since(start)
+		if err != nil {
+			current.Fail(err)
+			c.Events().Step(*current)
+			r.Error(err)
+			if ctx.Err() != nil {
+				// Removing needs the providers as well, so everything created is kept together with the journal to resume from
+				rollback.keep(r)
+				r.Warning("Stopped before completing '%s'", step.name)
+			} else if c.KeepOnFailure {
+				rollback.keep(r)
+			} else if journal.completed(stepPush) {
+				// The repository already holds the generated files, removing it would throw away more than it cleans up
+				rollback.keep(r)
+				r.Warning("The generated files are pushed, continue with %s", "--resume")
+			} else if removed, err := rollback.run(r); err != nil {
+				// The journal still points at what is left, so that it is not lost when nothing else remembers it
+				r.Warning("Not everything could be removed, continue with %s or remove what is left by hand", "--resume")
+				if err := journal.forget(removedSteps(steps, removed, rollback)...); err != nil {
+					r.Error(err)
+				}
+			} else if err := journal.reset(resumed); err != nil {
+				r.Error(err)
+			}
+			return failure.ExitCode(err)
+		}
+		current.Status = events.Succeeded
+		c.Events().Step(*current)
+	}
+	if err := journal.remove(); err != nil {
+		r.Error(err)
 	}
 	return 0
 }
 
+// withoutRemoteSteps leaves out the steps that need a CI or a remote repository, which a local project does not have
+func withoutRemoteSteps(steps []step) []step {
+	var local []step
+	for _, step := range steps {
+		switch step.name {
+		case stepPipeline, stepBadges, stepWebhook, stepPush:
+		default:
+			local = append(local, step)
+		}
+	}
+	return local
+}
+
+// removedSteps returns the steps to perform again after a partial rollback: the ones from the first step whose resource
+// was removed, as they depend on it, except the ones whose resources are left
+func removedSteps(steps []step, removed []string, rollback *rollback) []string {
+	var forget []string
+	from := false
+	for _, step := range steps {
+		for _, r := range removed {
+			from = from || r == step.name
+		}
+		if from && !rollback.left(step.name) {
+			forget = append(forget, step.name)
+		}
+	}
+	return forget
+}
+
+const DefaultCommitMessage = "Initial commit from scaffold"
+
+func (c *Config) commit() vcs.Commit {
+	commit := c.Commit
+	if commit.Message == "" {
+		commit.Message = DefaultCommitMessage
+	}
+	// A local repository has no protected branch, so nothing to open a pull request against
+	if !c.local {
+		commit.Branch = vcs.InitialBranch
+	}
+	return commit
+}
+
+func pullRequestBody(stack stack.Stack, ci ci.CI, journal *journal) string {
+	body := &strings.Builder{}
+	_, _ = fmt.Fprintf(body, "Adds the files generated by scaffold for `%s` using the `%s` stack.\n\n", journal.Data.ProjectName, stack.Name())
+	_, _ = fmt.Fprintf(body, "**Pipeline:** %s", ci.Name())
+	if journal.Webhook != nil {
+		_, _ = fmt.Fprintf(body, ", triggered by the webhook `%s`", *journal.Webhook)
+	}
+	_, _ = fmt.Fprint(body, "\n\n")
+	if len(journal.Data.Badges) > 0 {
+		_, _ = fmt.Fprint(body, "**Badges:**\n\n")
+		for _, badge := range journal.Data.Badges {
+			_, _ = fmt.Fprintf(body, "[![%s](%s)](%s)\n", badge.Title, badge.ImageUrl, badge.LinkUrl)
+		}
+		_, _ = fmt.Fprint(body, "\n")
+	}
+	_, _ = fmt.Fprint(body, "**Files:**\n\n")
+	for _, f := range append(journal.Files, stateFile) {
+		_, _ = fmt.Fprintf(body, "- `%s`\n", f)
+	}
+	return body.String()
+}
+
+func (s step) fail(err error) *failure.Error {
+	e := failure.Wrap(s.kind, s.name, err)
+	if e.Provider == "" && s.provider != nil {
+		e.Provider = s.provider.Name()
+	}
+	return e
+}
+
 func InitEmptyConfig() *Config {
 	return &Config{
 		VCS: &VCSConfig{
@@ -152,15 +603,15 @@ func InitEmptyConfig() *Config {
 	}
 }
 
-func addWebhook(name string, url *string, vcs vcs.VCS) error {
+func addWebhook(ctx context.Context, name string, url *string, vcs vcs.VCS) error {
 	if url != nil {
-		return vcs.Webhook(name, *url)
+		return vcs.Webhook(ctx, name, *url)
 	}
 	return nil
 }
 
-func createDotfiles(dir string) error {
-	if err := file.Write(dir, ".gitignore", ""); err != nil {
+func createDotfiles(fs billy.Filesystem) error {
+	if err := file.Write(fs, ".gitignore", ""); err != nil {
 		return err
 	}
 	editorconfig := `
@@ -172,7 +623,7 @@ insert_final_newline = true
 charset = utf-8
 trim_trailing_whitespace = true
 `
-	if err := file.Write(dir, ".editorconfig", editorconfig); err != nil {
+	if err := file.Write(fs, ".editorconfig", editorconfig); err != nil {
 		return err
 	}
 	dockerignore := `
@@ -181,23 +632,23 @@ trim_trailing_whitespace = true
 Dockerfile
 README.md
 `
-	if err := file.Write(dir, ".dockerignore", dockerignore); err != nil {
+	if err := file.Write(fs, ".dockerignore", dockerignore); err != nil {
 		return err
 	}
 	return nil
 }
 
-func createReadme(dir string, data templating.TemplateData) error {
+func createReadme(fs billy.Filesystem, data templating.TemplateData) error {
 	content := `
 | README.md
 # {{.ProjectName}}
 {{range .Badges}}[![{{.Title}}]({{.ImageUrl}})]({{.LinkUrl}}){{end}}
 `
-	return file.WriteTemplated(dir, "README.md", content, data)
+	return file.WriteTemplated(fs, "README.md", content, data)
 }
 
-func createDeployment(dir string, data templating.TemplateData) error {
-	return file.WriteTemplated(dir, filepath.Join("k8s", "deploy.yaml"), deployment, data)
+func createDeployment(fs billy.Filesystem, data templating.TemplateData) error {
+	return file.WriteTemplated(fs, filepath.Join("k8s", "deploy.yaml"), deployment, data)
 }
 
 var deployment = `
@@ -268,41 +719,65 @@ spec:
 
 var abs = filepath.Abs
 
-func parseConfigFiles(dir string, out io.Writer, fn func(string) error) error {
+// configFiles returns the configuration files by precedence: explicit, or $SCAFFOLD_CONFIG when empty, the .scaffold.yaml
+// files in dir and its parents, closest first, and last the configuration of the user
+func configFiles(dir, explicit string) ([]string, error) {
+	var files []string
+	explicit, err := explicitConfig(dir, explicit)
+	if err != nil {
+		return nil, err
+	}
+	if explicit != "" {
+		files = append(files, explicit)
+	}
 	parent, err := abs(dir)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var files []string
 	for parent != "/" {
 		filename := filepath.Join(parent, ".scaffold.yaml")
-		if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		if _, err := os.Stat(filename); !os.IsNotExist(err) && !contains(files, filename) {
 			files = append(files, filename)
 		}
 
 		parent = filepath.Dir(parent)
 	}
-	for i, file := range files {
-		if i == 0 {
-			_, _ = fmt.Fprintln(out, tml.Sprintf("Parsing config from file: <green>'%s'</green>", file))
-		} else {
-			_, _ = fmt.Fprintln(out, tml.Sprintf("Merging with config from file: <green>'%s'</green>", file))
-		}
-		if err := fn(file); err != nil {
-			return err
+	if filename := userConfig(); filename != "" && !contains(files, filename) {
+		if _, err := os.Stat(filename); !os.IsNotExist(err) {
+			files = append(files, filename)
 		}
 	}
-
-	return nil
+	return files, nil
 }
 
-func parseConfigFile(filename string, cfg *Config) error {
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return err
+// explicitConfig returns the file given with --config, or in SCAFFOLD_CONFIG when the flag is not used, empty when there is none
+func explicitConfig(dir, explicit string) (string, error) {
+	if explicit == "" {
+		explicit = os.Getenv("SCAFFOLD_CONFIG")
 	}
+	if explicit == "" {
+		return "", nil
+	}
+	if !filepath.IsAbs(explicit) {
+		explicit = filepath.Join(dir, explicit)
+	}
+	if _, err := os.Stat(explicit); os.IsNotExist(err) {
+		return "", fmt.Errorf("config file '%s' does not exist", explicit)
+	}
+	return filepath.Clean(explicit), nil
+}
 
-	return parseConfig(data, cfg)
+// userConfig returns the path of the configuration of the user, $XDG_CONFIG_HOME/scaffold/config.yaml
+func userConfig() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "scaffold", "config.yaml")
 }
 
 func parseConfig(content []byte, config *Config) error {
@@ -310,34 +785,75 @@ func parseConfig(content []byte, config *Config) error {
 	if err := yaml.UnmarshalStrict(content, temp); err != nil {
 		return err
 	} else {
-		if err := mergo.Merge(config, temp); err != nil {
-			return err
-		}
-		return validate(config)
+		return mergo.Merge(config, temp)
 	}
 }
 
-func validate(config *Config) error {
-	elem := reflect.ValueOf(config.CI).Elem()
+// selectProviders picks the CI and VCS among the configured providers, the one named by default when there are several.
+// Several providers without a choice leave the current one unset, commands only showing the configuration do not need one
+// and ValidateConfig insists on the choice.
+func selectProviders(config *Config) error {
+	currentCI, err := selectProvider("CI", "ci", config.CI, config.CI.Default)
+	if _, several := err.(severalProviders); err != nil && !several {
+		return err
+	}
+	currentVCS, err := selectProvider("VCS", "vcs", config.VCS, config.VCS.Default)
+	if _, several := err.(severalProviders); err != nil && !several {
+		return err
+	}
+	config.CurrentCI, _ = currentCI.(ci.CI)
+	config.CurrentVCS, _ = currentVCS.(vcs.VCS)
+	return nil
+}
+
+// configuredProviders returns the providers of section, a *CIConfig or *VCSConfig, with all required settings by name,
+// and their names sorted
+func configuredProviders(section interface{}) (map[string]interface{}, []string) {
+	elem := reflect.ValueOf(section).Elem()
+	configured := make(map[string]interface{})
+	var names []string
 	for i := 0; i < elem.NumField(); i++ {
-		currentCI := elem.Field(i).Interface().(ci.CI)
-		if currentCI.ValidateConfig() == nil {
-			if config.CurrentCI != nil && config.CurrentCI != currentCI {
-				return fmt.Errorf("scaffold CI already defined, please check configuration")
-			}
-			config.CurrentCI = currentCI
+		field := elem.Field(i)
+		provider, ok := field.Interface().(interface{ ValidateConfig() error })
+		if !ok || field.IsNil() || provider.ValidateConfig() != nil {
+			continue
 		}
+		name := strings.Split(elem.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		configured[name] = provider
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return configured, names
+}
 
-	elem = reflect.ValueOf(config.VCS).Elem()
-	for i := 0; i < elem.NumField(); i++ {
-		currentVCS := elem.Field(i).Interface().(vcs.VCS)
-		if currentVCS.ValidateConfig() == nil {
-			if config.CurrentVCS != nil && config.CurrentVCS != currentVCS {
-				return fmt.Errorf("scaffold VCS already defined, please check configuration")
-			}
-			config.CurrentVCS = currentVCS
+// severalProviders is the error of selectProvider when there is no choice between several providers
+type severalProviders struct {
+	kind, key string
+	names     []string
+}
+
+func (e severalProviders) Error() string {
+	return fmt.Sprintf("several %s are configured (%s), choose one with --%s or %s.default", e.kind, strings.Join(e.names, ", "), e.key, e.key)
+}
+
+// selectProvider returns the provider called choice among the configured providers of section, a *CIConfig or *VCSConfig.
+// Without a choice the provider is only picked when it is the single one configured.
+func selectProvider(kind, key string, section interface{}, choice string) (interface{}, error) {
+	configured, names := configuredProviders(section)
+	if choice != "" {
+		if provider, exists := configured[choice]; exists {
+			return provider, nil
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("%s '%s' is not configured, no %s is configured", kind, choice, kind)
 		}
+		return nil, fmt.Errorf("%s '%s' is not configured, available %s are: %s", kind, choice, kind, strings.Join(names, ", "))
 	}
-	return nil
+	switch len(names) {
+	case 0:
+		return nil, nil
+	case 1:
+		return configured[names[0]], nil
+	}
+	return nil, severalProviders{kind: kind, key: key, names: names}
 }
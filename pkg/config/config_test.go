@@ -2,18 +2,26 @@ package config
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/buildtool/scaffold/pkg/config/ci"
 	"github.com/buildtool/scaffold/pkg/config/vcs"
+	"github.com/buildtool/scaffold/pkg/events"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/report"
 	"github.com/buildtool/scaffold/pkg/stack"
 	"github.com/buildtool/scaffold/pkg/templating"
 	"github.com/buildtool/scaffold/pkg/wrappers"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/yaml.v2"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -54,7 +62,7 @@ func TestConfigure(t *testing.T) {
 	cfg.CurrentCI = &mockCi{}
 	cfg.CurrentVCS = &mockVcs{}
 
-	err := cfg.Configure()
+	err := cfg.Configure(report.Nop)
 
 	assert.NoError(t, err)
 }
@@ -64,7 +72,7 @@ func TestValidate_VCS_Error(t *testing.T) {
 	cfg.CurrentCI = &mockCi{}
 	cfg.CurrentVCS = &mockVcs{validateErr: errors.New("validate error")}
 
-	err := cfg.Validate("project")
+	err := cfg.Validate(context.Background(), "project")
 
 	assert.EqualError(t, err, "validate error")
 }
@@ -74,7 +82,7 @@ func TestValidate_CI_Error(t *testing.T) {
 	cfg.CurrentCI = &mockCi{validateErr: errors.New("validate error")}
 	cfg.CurrentVCS = &mockVcs{}
 
-	err := cfg.Validate("project")
+	err := cfg.Validate(context.Background(), "project")
 
 	assert.EqualError(t, err, "validate error")
 }
@@ -86,9 +94,9 @@ func TestScaffold_VcsScaffold_Error(t *testing.T) {
 
 	out := &bytes.Buffer{}
 
-	exitCode := cfg.Scaffold(name, "project", &stack.None{}, out)
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
 
-	assert.Equal(t, -7, exitCode)
+	assert.Equal(t, 6, exitCode)
 	assert.Equal(t, "\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31merror\x1b[39m\x1b[0m\n", out.String())
 }
 
@@ -101,10 +109,10 @@ func TestScaffold_VcsClone_Error(t *testing.T) {
 
 	out := &bytes.Buffer{}
 
-	exitCode := cfg.Scaffold(name, "project", &stack.None{}, out)
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
 
-	assert.Equal(t, -8, exitCode)
-	assert.Equal(t, "\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31merror\x1b[39m\x1b[0m\n", out.String())
+	assert.Equal(t, 6, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31merror\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mlocal clone '%s/project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", name), out.String())
 }
 
 func TestScaffold_Badges_Error(t *testing.T) {
@@ -117,10 +125,10 @@ func TestScaffold_Badges_Error(t *testing.T) {
 
 	out := &bytes.Buffer{}
 
-	exitCode := cfg.Scaffold(name, "project", &stack.None{}, out)
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
 
-	assert.Equal(t, -9, exitCode)
-	assert.Equal(t, "\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31merror\x1b[39m\x1b[0m\n", out.String())
+	assert.Equal(t, 6, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31merror\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mbuild pipeline 'project' at mockCi\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mlocal clone '%s/project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", name), out.String())
 }
 
 func TestScaffold_Not_Parsable_Repository_Url(t *testing.T) {
@@ -133,10 +141,10 @@ func TestScaffold_Not_Parsable_Repository_Url(t *testing.T) {
 
 	out := &bytes.Buffer{}
 
-	exitCode := cfg.Scaffold(name, "project", &stack.None{}, out)
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
 
-	assert.Equal(t, -10, exitCode)
-	assert.Equal(t, "\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mparse http://192.168.0.%31/: invalid URL escape \"%31\"\x1b[39m\x1b[0m\n", out.String())
+	assert.Equal(t, 6, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mparse http://192.168.0.%%31/: invalid URL escape \"%%31\"\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mlocal clone '%s/project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", name), out.String())
 }
 
 func TestScaffold_CiScaffold_Error(t *testing.T) {
@@ -149,10 +157,10 @@ func TestScaffold_CiScaffold_Error(t *testing.T) {
 
 	out := &bytes.Buffer{}
 
-	exitCode := cfg.Scaffold(name, "project", &stack.None{}, out)
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
 
-	assert.Equal(t, -11, exitCode)
-	assert.Equal(t, "\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31merror\x1b[39m\x1b[0m\n", out.String())
+	assert.Equal(t, 6, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31merror\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mlocal clone '%s/project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", name), out.String())
 }
 
 func TestScaffold_Webhook_Error(t *testing.T) {
@@ -165,10 +173,197 @@ func TestScaffold_Webhook_Error(t *testing.T) {
 
 	out := &bytes.Buffer{}
 
-	exitCode := cfg.Scaffold(name, "project", &stack.None{}, out)
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
 
-	assert.Equal(t, -12, exitCode)
-	assert.Equal(t, "\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31merror\x1b[39m\x1b[0m\n", out.String())
+	assert.Equal(t, 6, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31merror\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mbuild pipeline 'project' at mockCi\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mlocal clone '%s/project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", name), out.String())
+}
+
+func TestScaffold_Commit_Error(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	listener := &recordingListener{}
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{commitErr: &failure.Error{Kind: failure.Config, Err: errors.New("no author")}}
+	cfg.CurrentCI = &mockCi{}
+	cfg.Listener = listener
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(&bytes.Buffer{}, true))
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, events.Step{Name: "commit", Status: events.Failed, Error: "no author", ErrorKind: "config", ExitCode: 3}, listener.steps[len(listener.steps)-1])
+	_, err := os.Stat(filepath.Join(name, "project"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestScaffold_Push_Error(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{pushErr: errors.New("rejected")}
+	cfg.CurrentCI = &mockCi{}
+	out := &bytes.Buffer{}
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
+
+	assert.Equal(t, 6, exitCode)
+	assert.Contains(t, out.String(), "\x1b[31mrejected\x1b[39m")
+	assert.Contains(t, out.String(), "\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n")
+}
+
+func TestScaffold_PullRequest_Error(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{pullErr: errors.New("branch is protected")}
+	cfg.CurrentCI = &mockCi{}
+	out := &bytes.Buffer{}
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
+
+	assert.Equal(t, 6, exitCode)
+	assert.Contains(t, out.String(), "\x1b[31mbranch is protected\x1b[39m")
+	assert.Contains(t, out.String(), "\x1b[33mKeeping \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n")
+	assert.NotContains(t, out.String(), "Removing")
+	journal, err := loadJournal(journalPath(name, "project"))
+	assert.NoError(t, err)
+	assert.True(t, journal.completed(stepPush))
+}
+
+func TestPullRequestBody(t *testing.T) {
+	journal := &journal{
+		Data: templating.TemplateData{
+			ProjectName: "project",
+			Badges:      []templating.Badge{{Title: "Build status", ImageUrl: "https://example.org/badge.svg", LinkUrl: "https://example.org/builds"}},
+		},
+		Webhook: wrappers.String("https://example.org/hook"),
+		Files:   []string{".buildkite/pipeline.yml", "README.md"},
+	}
+
+	body := pullRequestBody(&stack.None{}, &mockCi{}, journal)
+
+	assert.Equal(t, "Adds the files generated by scaffold for `project` using the `none` stack.\n\n**Pipeline:** mockCi, triggered by the webhook `https://example.org/hook`\n\n**Badges:**\n\n[![Build status](https://example.org/badge.svg)](https://example.org/builds)\n\n**Files:**\n\n- `.buildkite/pipeline.yml`\n- `README.md`\n- `.scaffold-state.yaml`\n", body)
+}
+
+func TestScaffold_Interrupted_Keeps_Resources(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{}
+	cfg.CurrentCI = &mockCi{}
+	cfg.Listener = &cancelAfter{step: "pipeline", cancel: cancel}
+	out := &bytes.Buffer{}
+
+	exitCode := cfg.Scaffold(ctx, name, "project", &stack.None{}, report.NewText(out, true))
+
+	assert.Equal(t, 9, exitCode)
+	assert.Contains(t, out.String(), "\x1b[31mcontext canceled\x1b[39m")
+	assert.Contains(t, out.String(), "\x1b[33mKeeping \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n")
+	assert.Contains(t, out.String(), "\x1b[33mKeeping \x1b[39m\x1b[97m\x1b[1mbuild pipeline 'project' at mockCi\x1b[0m\x1b[97m\x1b[39m\n")
+	assert.Contains(t, out.String(), "\x1b[33mStopped before completing \x1b[39m\x1b[97m\x1b[1m'badges'\x1b[0m\x1b[97m\x1b[39m\n")
+	assert.NotContains(t, out.String(), "Removing")
+	journal, err := loadJournal(journalPath(name, "project"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"repository", "clone", "template-data", "pipeline"}, journal.Steps)
+}
+
+func TestScaffold_Timeout(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{}
+	cfg.CurrentCI = &mockCi{}
+	out := &bytes.Buffer{}
+
+	exitCode := cfg.Scaffold(ctx, name, "project", &stack.None{}, report.NewText(out, true))
+
+	assert.Equal(t, 6, exitCode)
+	assert.Contains(t, out.String(), "\x1b[31mcontext deadline exceeded\x1b[39m")
+	assert.Contains(t, out.String(), "\x1b[33mHint: it did not finish within --timeout, try again or increase it\x1b[39m")
+	assert.Contains(t, out.String(), "\x1b[33mStopped before completing \x1b[39m\x1b[97m\x1b[1m'repository'\x1b[0m\x1b[97m\x1b[39m\n")
+}
+
+func TestScaffold_Commit_Message(t *testing.T) {
+	cfg := InitEmptyConfig()
+	assert.Equal(t, vcs.Commit{Message: "Initial commit from scaffold", Branch: "scaffold/initial"}, cfg.commit())
+
+	cfg.Commit = vcs.Commit{Message: "Add service", Name: "Test", Email: "test@example.com"}
+	assert.Equal(t, vcs.Commit{Message: "Add service", Name: "Test", Email: "test@example.com", Branch: "scaffold/initial"}, cfg.commit())
+
+	cfg.Local("")
+	assert.Equal(t, vcs.Commit{Message: "Add service", Name: "Test", Email: "test@example.com"}, cfg.commit())
+}
+
+func TestAdopt_Keeps_Existing_Files(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	root := filepath.Join(name, "existing")
+	_ = os.MkdirAll(root, 0777)
+	_ = ioutil.WriteFile(filepath.Join(root, "README.md"), []byte("existing readme"), 0666)
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{scaffoldErr: errors.New("must not be called"), cloneErr: errors.New("must not be called")}
+	cfg.CurrentCI = &mockCi{webhookUrl: wrappers.String("https://example.org")}
+	out := &bytes.Buffer{}
+
+	exitCode := cfg.Adopt(context.Background(), root, "project", &vcs.RepositoryInfo{SSHURL: "git@github.com:org/project.git", HTTPURL: "https://github.com/org/project.git"}, &stack.None{}, report.NewText(out, true))
+
+	assert.Equal(t, 0, exitCode)
+	readme, _ := ioutil.ReadFile(filepath.Join(root, "README.md"))
+	assert.Equal(t, "existing readme", string(readme))
+	assert.FileExists(t, filepath.Join(root, ".gitignore"))
+	assert.FileExists(t, filepath.Join(root, "k8s", "deploy.yaml"))
+	assert.Contains(t, out.String(), "\x1b[33mSkipping completed step \x1b[39m\x1b[97m\x1b[1m'repository'\x1b[0m\x1b[97m\x1b[39m\n")
+	assert.Contains(t, out.String(), "\x1b[33mKeeping existing \x1b[39m\x1b[97m\x1b[1m'README.md'\x1b[0m\x1b[97m\x1b[39m\n")
+	files, _ := ioutil.ReadDir(name)
+	assert.Len(t, files, 1)
+	state, err := loadState(osfs.New(root))
+	assert.NoError(t, err)
+	assert.NotContains(t, state.Files, "README.md")
+	assert.Contains(t, state.Files, ".gitignore")
+}
+
+func TestAdopt_Gitlab_CI(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	root := filepath.Join(name, "existing")
+	_ = os.MkdirAll(root, 0777)
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{scaffoldErr: errors.New("must not be called"), cloneErr: errors.New("must not be called")}
+	gitlabCI := &ci.Gitlab{Group: "configured"}
+	gitlabCI.DryRun(report.Nop)
+	cfg.CurrentCI = gitlabCI
+	repository := &vcs.RepositoryInfo{SSHURL: "git@gitlab.com:other/sub/project.git", HTTPURL: "https://gitlab.com/other/sub/project.git"}
+	out := &bytes.Buffer{}
+
+	assert.NoError(t, cfg.CurrentCI.Adopt(context.Background(), "other/sub/project"))
+	exitCode := cfg.Adopt(context.Background(), root, "project", repository, &stack.None{}, report.NewText(out, true))
+
+	assert.Equal(t, 0, exitCode, out.String())
+	assert.Equal(t, "other/sub", gitlabCI.Group)
+	assert.FileExists(t, filepath.Join(root, ".gitlab-ci.yml"))
+	assert.NotContains(t, out.String(), "Keeping existing")
+}
+
+func TestScaffold_Webhook_Error_With_Hint(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{webhookErr: &failure.Error{Kind: failure.Auth, Status: 404, Hint: "token lacks admin:repo_hook scope", Err: errors.New("failed to create webhook 404 Not Found")}}
+	cfg.CurrentCI = &mockCi{webhookUrl: wrappers.String("https://example.org")}
+	listener := &recordingListener{}
+	cfg.Listener = listener
+
+	out := &bytes.Buffer{}
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
+
+	assert.Equal(t, 4, exitCode)
+	assert.Contains(t, out.String(), "\x1b[0m\x1b[31mfailed to create webhook 404 Not Found\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mHint: token lacks admin:repo_hook scope\x1b[39m\x1b[0m\n")
+	assert.Equal(t, events.Step{Name: "webhook", Status: events.Failed, Error: "failed to create webhook 404 Not Found", ErrorKind: "auth", Provider: "mockVcs", HTTPStatus: 404, Hint: "token lacks admin:repo_hook scope", ExitCode: 4}, listener.steps[len(listener.steps)-1])
 }
 
 func TestScaffold_Error_Writing_Gitignore(t *testing.T) {
@@ -183,10 +378,10 @@ func TestScaffold_Error_Writing_Gitignore(t *testing.T) {
 	_ = os.MkdirAll(filename, 0777)
 	out := &bytes.Buffer{}
 
-	exitCode := cfg.Scaffold(name, "project", &errorStack{}, out)
-	assert.Equal(t, -13, exitCode)
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &errorStack{}, report.NewText(out, true))
+	assert.Equal(t, 7, exitCode)
 
-	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'error-stack'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mopen %s: is a directory\x1b[39m\x1b[0m\n", filename), out.String())
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'error-stack'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mopen %s: is a directory\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mbuild pipeline 'project' at mockCi\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", filename), out.String())
 }
 func TestScaffold_Error_Writing_Editorconfig(t *testing.T) {
 	defer func() { _ = os.RemoveAll(name) }()
@@ -200,10 +395,10 @@ func TestScaffold_Error_Writing_Editorconfig(t *testing.T) {
 	_ = os.MkdirAll(filename, 0777)
 	out := &bytes.Buffer{}
 
-	exitCode := cfg.Scaffold(name, "project", &errorStack{}, out)
-	assert.Equal(t, -13, exitCode)
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &errorStack{}, report.NewText(out, true))
+	assert.Equal(t, 7, exitCode)
 
-	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'error-stack'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mopen %s: is a directory\x1b[39m\x1b[0m\n", filename), out.String())
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'error-stack'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mopen %s: is a directory\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mbuild pipeline 'project' at mockCi\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", filename), out.String())
 }
 
 func TestScaffold_Error_Writing_Dockerignore(t *testing.T) {
@@ -218,10 +413,10 @@ func TestScaffold_Error_Writing_Dockerignore(t *testing.T) {
 	_ = os.MkdirAll(filename, 0777)
 	out := &bytes.Buffer{}
 
-	exitCode := cfg.Scaffold(name, "project", &errorStack{}, out)
-	assert.Equal(t, -13, exitCode)
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &errorStack{}, report.NewText(out, true))
+	assert.Equal(t, 7, exitCode)
 
-	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'error-stack'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mopen %s: is a directory\x1b[39m\x1b[0m\n", filename), out.String())
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'error-stack'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mopen %s: is a directory\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mbuild pipeline 'project' at mockCi\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", filename), out.String())
 }
 
 func TestScaffold_Error_Writing_Readme(t *testing.T) {
@@ -236,10 +431,10 @@ func TestScaffold_Error_Writing_Readme(t *testing.T) {
 	_ = os.MkdirAll(filename, 0777)
 	out := &bytes.Buffer{}
 
-	exitCode := cfg.Scaffold(name, "project", &errorStack{}, out)
-	assert.Equal(t, -14, exitCode)
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &errorStack{}, report.NewText(out, true))
+	assert.Equal(t, 7, exitCode)
 
-	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'error-stack'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mopen %s: is a directory\x1b[39m\x1b[0m\n", filename), out.String())
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'error-stack'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mopen %s: is a directory\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mbuild pipeline 'project' at mockCi\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", filename), out.String())
 }
 
 func TestScaffold_Error_Writing_Deployment(t *testing.T) {
@@ -254,10 +449,10 @@ func TestScaffold_Error_Writing_Deployment(t *testing.T) {
 	_ = os.MkdirAll(filename, 0777)
 	out := &bytes.Buffer{}
 
-	exitCode := cfg.Scaffold(name, "project", &errorStack{}, out)
-	assert.Equal(t, -15, exitCode)
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &errorStack{}, report.NewText(out, true))
+	assert.Equal(t, 7, exitCode)
 
-	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'error-stack'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mopen %s: is a directory\x1b[39m\x1b[0m\n", filename), out.String())
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'error-stack'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mopen %s: is a directory\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mbuild pipeline 'project' at mockCi\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", filename), out.String())
 }
 
 func TestScaffold_StackError(t *testing.T) {
@@ -270,10 +465,78 @@ func TestScaffold_StackError(t *testing.T) {
 
 	out := &bytes.Buffer{}
 
-	exitCode := cfg.Scaffold(name, "project", &errorStack{}, out)
-	assert.Equal(t, -16, exitCode)
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &errorStack{}, report.NewText(out, true))
+	assert.Equal(t, 7, exitCode)
+
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'error-stack'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31merror\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mbuild pipeline 'project' at mockCi\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mlocal clone '%s/project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", name), out.String())
+}
+
+func TestScaffold_Rollback_Removes_Clone(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{deleteErr: errors.New("delete error")}
+	cfg.CurrentCI = &mockCi{scaffoldErr: errors.New("error")}
+
+	out := &bytes.Buffer{}
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
+
+	assert.Equal(t, 6, exitCode)
+	_, err := os.Stat(filepath.Join(name, "project"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Contains(t, out.String(), "\x1b[31mFailed to remove repository 'project' at mockVcs: delete error\x1b[39m")
+}
+
+func TestScaffold_Failed_Rollback_Keeps_Journal(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{deleteErr: errors.New("delete error")}
+	cfg.CurrentCI = &mockCi{scaffoldErr: errors.New("error")}
+	out := &bytes.Buffer{}
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, false))
 
-	assert.Equal(t, "\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'error-stack'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31merror\x1b[39m\x1b[0m\n", out.String())
+	assert.Equal(t, 6, exitCode)
+	journal, err := loadJournal(journalPath(name, "project"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"repository"}, journal.Steps)
+	assert.True(t, strings.HasSuffix(out.String(), "Failed to remove repository 'project' at mockVcs: delete error\nNot everything could be removed, continue with --resume or remove what is left by hand\n"))
+}
+
+func TestScaffold_Failed_Rollback_Forgets_Removed_Steps(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{}
+	cfg.CurrentCI = &mockCi{deleteErr: errors.New("delete error")}
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &errorStack{}, report.NewText(&bytes.Buffer{}, false))
+
+	assert.Equal(t, 7, exitCode)
+	journal, err := loadJournal(journalPath(name, "project"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pipeline"}, journal.Steps)
+}
+
+func TestScaffold_KeepOnFailure(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{}
+	cfg.CurrentCI = &mockCi{}
+	cfg.RegistryUrl = "dockerhub"
+	cfg.KeepOnFailure = true
+
+	out := &bytes.Buffer{}
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &errorStack{}, report.NewText(out, true))
+
+	assert.Equal(t, 7, exitCode)
+	_, err := os.Stat(filepath.Join(name, "project"))
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'error-stack'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31merror\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mKeeping \x1b[39m\x1b[97m\x1b[1mrepository 'project' at mockVcs\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mKeeping \x1b[39m\x1b[97m\x1b[1mlocal clone '%s/project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mKeeping \x1b[39m\x1b[97m\x1b[1mbuild pipeline 'project' at mockCi\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", name), out.String())
 }
 
 func TestScaffold_Ok(t *testing.T) {
@@ -286,10 +549,252 @@ func TestScaffold_Ok(t *testing.T) {
 
 	out := &bytes.Buffer{}
 
-	exitCode := cfg.Scaffold(name, "project", &stack.None{}, out)
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
+	assert.Equal(t, 0, exitCode)
+
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCommitting generated files in \x1b[39m\x1b[97m\x1b[1m'%s/project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mPushing to \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mOpening pull request from \x1b[39m\x1b[97m\x1b[1m'scaffold/initial'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mOpened pull request \x1b[39m\x1b[97m\x1b[1m'https://example.org/project/pull/1'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", name), out.String())
+}
+
+func TestScaffold_Ok_Removes_Journal(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{}
+	cfg.CurrentCI = &mockCi{}
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(&bytes.Buffer{}, true))
+
+	assert.Equal(t, 0, exitCode)
+	_, err := os.Stat(journalPath(name, "project"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestScaffold_Rollback_Removes_Journal(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{}
+	cfg.CurrentCI = &mockCi{}
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &errorStack{}, report.NewText(&bytes.Buffer{}, true))
+
+	assert.Equal(t, 7, exitCode)
+	_, err := os.Stat(journalPath(name, "project"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestScaffold_KeepOnFailure_Writes_Journal(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{httpUrl: "https://example.com/org/project.git"}
+	cfg.CurrentCI = &mockCi{webhookUrl: wrappers.String("https://webhook")}
+	cfg.KeepOnFailure = true
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &errorStack{}, report.NewText(&bytes.Buffer{}, true))
+
+	assert.Equal(t, 7, exitCode)
+	journal, err := loadJournal(journalPath(name, "project"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"repository", "clone", "template-data", "pipeline", "badges", "webhook", "dotfiles", "readme", "deployment"}, journal.Steps)
+	assert.Equal(t, &vcs.RepositoryInfo{SSHURL: "file:///tmp", HTTPURL: "https://example.com/org/project.git"}, journal.Repository)
+	assert.Equal(t, "https://webhook", *journal.Webhook)
+	assert.Equal(t, "/org/project", journal.Data.RepositoryPath)
+}
+
+func TestResume_Missing_Journal(t *testing.T) {
+	cfg := InitEmptyConfig()
+
+	err := cfg.Resume(name, "project")
+
+	assert.EqualError(t, err, fmt.Sprintf("no previous run to resume, '%s/.project.scaffold-journal' does not exist", name))
+}
+
+func TestValidate_Skipped_When_Resuming(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = newJournal(journalPath(name, "project")).complete("repository")
+	cfg := InitEmptyConfig()
+	cfg.CurrentCI = &mockCi{validateErr: errors.New("validate error")}
+	cfg.CurrentVCS = &mockVcs{validateErr: errors.New("validate error")}
+
+	assert.NoError(t, cfg.Resume(name, "project"))
+	err := cfg.Validate(context.Background(), "project")
+
+	assert.NoError(t, err)
+}
+
+func TestScaffold_Resume(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	journal := newJournal(journalPath(name, "project"))
+	journal.Repository = &vcs.RepositoryInfo{SSHURL: "git@example.com:org/project.git", HTTPURL: "https://example.com/org/project.git"}
+	_ = journal.complete("repository")
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{scaffoldErr: errors.New("already exists"), deleteErr: errors.New("must not be removed")}
+	cfg.CurrentCI = &mockCi{}
+
+	assert.NoError(t, cfg.Resume(name, "project"))
+	out := &bytes.Buffer{}
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[33mSkipping completed step \x1b[39m\x1b[97m\x1b[1m'repository'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCommitting generated files in \x1b[39m\x1b[97m\x1b[1m'%s/project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mPushing to \x1b[39m\x1b[97m\x1b[1m'git@example.com:org/project.git'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mOpening pull request from \x1b[39m\x1b[97m\x1b[1m'scaffold/initial'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mOpened pull request \x1b[39m\x1b[97m\x1b[1m'https://example.org/project/pull/1'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", name), out.String())
+	content, err := ioutil.ReadFile(filepath.Join(name, "project", "README.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "| README.md\n# project\n", string(content))
+	_, err = os.Stat(journalPath(name, "project"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestScaffold_Resume_Github(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	journal := newJournal(journalPath(name, "project"))
+	journal.Repository = &vcs.RepositoryInfo{SSHURL: "git@github.com:org/project.git", HTTPURL: "https://github.com/org/project.git"}
+	_ = journal.complete("repository")
+	_ = journal.complete("clone")
+	out := &bytes.Buffer{}
+	github := &vcs.Github{Token: "abc"}
+	github.DryRun(report.NewText(out, false))
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = github
+	cfg.CurrentCI = &mockCi{webhookUrl: wrappers.String("https://webhook")}
+
+	assert.NoError(t, cfg.Resume(name, "project"))
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &errorStack{}, report.NewText(out, false))
+
+	assert.Equal(t, 7, exitCode)
+	assert.Contains(t, out.String(), "POST https://api.github.com/repos/org/project/hooks")
+	assert.Contains(t, out.String(), "DELETE https://api.github.com/repos/org/project/hooks/1")
+}
+
+func TestScaffold_Resume_Failure_Keeps_Previous_Steps(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	journal := newJournal(journalPath(name, "project"))
+	journal.Repository = &vcs.RepositoryInfo{SSHURL: "git@example.com:org/project.git", HTTPURL: "https://example.com/org/project.git"}
+	_ = journal.complete("repository")
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{}
+	cfg.CurrentCI = &mockCi{scaffoldErr: errors.New("token expired")}
+
+	assert.NoError(t, cfg.Resume(name, "project"))
+	out := &bytes.Buffer{}
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
+
+	assert.Equal(t, 6, exitCode)
+	assert.NotContains(t, out.String(), "repository 'project' at mockVcs")
+	journal, err := loadJournal(journalPath(name, "project"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"repository"}, journal.Steps)
+}
+
+func TestDryRun_Resume(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	journal := newJournal(journalPath(name, "project"))
+	journal.Repository = &vcs.RepositoryInfo{SSHURL: "git@example.com:org/project.git", HTTPURL: "https://example.com/org/project.git"}
+	_ = journal.complete("repository")
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{scaffoldErr: errors.New("already exists")}
+	cfg.CurrentCI = &mockCi{}
+
+	assert.NoError(t, cfg.Resume(name, "project"))
+	exitCode := cfg.DryRun(context.Background(), name, "project", &stack.None{}, report.NewText(&bytes.Buffer{}, true))
+
+	assert.Equal(t, 0, exitCode)
+	journal, err := loadJournal(journalPath(name, "project"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"repository"}, journal.Steps)
+}
+
+func TestScaffold_Events(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	listener := &recordingListener{}
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{httpUrl: "https://example.com/org/project.git"}
+	cfg.CurrentCI = &mockCi{webhookUrl: wrappers.String("https://webhook")}
+	cfg.Listener = listener
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &errorStack{}, report.NewText(&bytes.Buffer{}, true))
+
+	assert.Equal(t, 7, exitCode)
+	assert.Equal(t, []events.Step{
+		{Name: "repository", Status: events.Succeeded, Resources: []string{"file:///tmp", "https://example.com/org/project.git"}},
+		{Name: "clone", Status: events.Succeeded},
+		{Name: "template-data", Status: events.Succeeded},
+		{Name: "pipeline", Status: events.Succeeded},
+		{Name: "badges", Status: events.Succeeded},
+		{Name: "webhook", Status: events.Succeeded, Resources: []string{"https://webhook"}},
+		{Name: "dotfiles", Status: events.Succeeded},
+		{Name: "readme", Status: events.Succeeded},
+		{Name: "deployment", Status: events.Succeeded},
+		{Name: "stack", Status: events.Failed, Error: "error", ErrorKind: "filesystem", ExitCode: 7},
+	}, listener.steps)
+}
+
+func TestScaffold_Events_Skipped(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	journal := newJournal(journalPath(name, "project"))
+	journal.Repository = &vcs.RepositoryInfo{SSHURL: "git@example.com:org/project.git", HTTPURL: "https://example.com/org/project.git"}
+	_ = journal.complete("repository")
+	listener := &recordingListener{}
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{}
+	cfg.CurrentCI = &mockCi{}
+	cfg.Listener = listener
+
+	assert.NoError(t, cfg.Resume(name, "project"))
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(&bytes.Buffer{}, true))
+
 	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, events.Step{Name: "repository", Status: events.Skipped}, listener.steps[0])
+	assert.Equal(t, 14, len(listener.steps))
+}
 
-	assert.Equal(t, "\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mockVcs'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", out.String())
+func TestConfigureDryRun(t *testing.T) {
+	cfg := InitEmptyConfig()
+	cfg.CurrentCI = &mockCi{}
+	cfg.CurrentVCS = &mockVcs{}
+
+	cfg.ConfigureDryRun(report.NewText(&bytes.Buffer{}, true))
+}
+
+func TestDryRun_Error(t *testing.T) {
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{}
+	cfg.CurrentCI = &mockCi{scaffoldErr: errors.New("error")}
+
+	out := &bytes.Buffer{}
+
+	exitCode := cfg.DryRun(context.Background(), name, "project", &stack.None{}, report.NewText(out, true))
+
+	assert.Equal(t, 6, exitCode)
+	assert.Contains(t, out.String(), fmt.Sprintf("Would clone \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m \x1b[33minto \x1b[39m\x1b[97m\x1b[1m'%s/project'", name))
+	assert.NotContains(t, out.String(), "Would write file")
+}
+
+func TestDryRun_Ok(t *testing.T) {
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{httpUrl: "https://github.com/org/project.git"}
+	cfg.CurrentCI = &mockCi{}
+	cfg.RegistryUrl = "dockerhub"
+
+	out := &bytes.Buffer{}
+
+	exitCode := cfg.DryRun(context.Background(), name, "project", &stack.Go{}, report.NewText(out, true))
+
+	assert.Equal(t, 0, exitCode)
+	for _, file := range []string{".dockerignore", ".editorconfig", ".gitignore", "README.md", "go.mod", "k8s/deploy.yaml"} {
+		assert.Contains(t, out.String(), fmt.Sprintf("Would write file \x1b[39m\x1b[97m\x1b[1m'%s/project/%s'", name, file))
+	}
+	assert.Contains(t, out.String(), "module github.com/org/project\n")
+	assert.Contains(t, out.String(), "image: dockerhub/project:${COMMIT}\n")
+	assert.Contains(t, out.String(), "\x1b[33mWould commit \x1b[39m\x1b[97m\x1b[1m7\x1b[0m\x1b[97m\x1b[39m \x1b[33mfiles with message \x1b[39m\x1b[97m\x1b[1m'Initial commit from scaffold'\x1b[0m\x1b[97m\x1b[39m\n")
+	assert.Contains(t, out.String(), "\x1b[33mWould push to \x1b[39m\x1b[97m\x1b[1m'file:///tmp'\x1b[0m\x1b[97m\x1b[39m\n")
+	_, err := os.Stat(filepath.Join(name, "project"))
+	assert.True(t, os.IsNotExist(err))
 }
 
 func TestLoad_YAML_Scaffold_Multiple_CI(t *testing.T) {
@@ -306,8 +811,10 @@ ci:
 	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(yaml), 0777)
 
 	out := &bytes.Buffer{}
-	_, err := Load(name, out)
-	assert.EqualError(t, err, "scaffold CI already defined, please check configuration")
+	cfg, err := Load(name, Selection{}, report.NewText(out, true))
+	assert.NoError(t, err)
+	assert.Nil(t, cfg.CurrentCI)
+	assert.EqualError(t, cfg.ValidateConfig(), "several CI are configured (buildkite, gitlab), choose one with --ci or ci.default")
 	assert.Equal(t, fmt.Sprintf("\x1b[0mParsing config from file: \x1b[32m'%s/.scaffold.yaml'\x1b[39m\x1b[0m\n", name), out.String())
 }
 
@@ -324,14 +831,389 @@ vcs:
 	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(yaml), 0777)
 
 	out := &bytes.Buffer{}
-	_, err := Load(name, out)
-	assert.EqualError(t, err, "scaffold VCS already defined, please check configuration")
+	cfg, err := Load(name, Selection{}, report.NewText(out, true))
+	assert.NoError(t, err)
+	assert.Nil(t, cfg.CurrentVCS)
+	assert.EqualError(t, cfg.ValidateConfig(), "several VCS are configured (github, gitlab), choose one with --vcs or vcs.default")
 	assert.Equal(t, fmt.Sprintf("\x1b[0mParsing config from file: \x1b[32m'%s/.scaffold.yaml'\x1b[39m\x1b[0m\n", name), out.String())
 }
 
+const providers = `
+vcs:
+  github:
+    token: token
+  gitlab:
+    group: group
+    token: token
+  default: gitlab
+ci:
+  buildkite:
+    token: token
+  gitlab:
+    token: token
+  default: buildkite
+`
+
+func TestLoad_Default_Providers(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(providers), 0777)
+
+	cfg, err := Load(name, Selection{}, report.Nop)
+
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.VCS.Gitlab, cfg.CurrentVCS)
+	assert.Equal(t, cfg.CI.Buildkite, cfg.CurrentCI)
+}
+
+func TestLoad_Selected_Providers(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(providers), 0777)
+
+	cfg, err := Load(name, Selection{VCS: "github", CI: "gitlab"}, report.Nop)
+
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.VCS.Github, cfg.CurrentVCS)
+	assert.Equal(t, cfg.CI.Gitlab, cfg.CurrentCI)
+}
+
+func TestLoad_Selected_Provider_Not_Configured(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	yaml := `
+vcs:
+  github:
+    token: token
+ci:
+  buildkite:
+    token: token
+`
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(yaml), 0777)
+
+	_, err := Load(name, Selection{VCS: "gitlab"}, report.Nop)
+	assert.EqualError(t, err, "VCS 'gitlab' is not configured, available VCS are: github")
+
+	_, err = Load(name, Selection{CI: "travis"}, report.Nop)
+	assert.EqualError(t, err, "CI 'travis' is not configured, available CI are: buildkite")
+}
+
+func TestLoad_Selected_Provider_Nothing_Configured(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+
+	_, err := Load(name, Selection{CI: "gitlab"}, report.Nop)
+
+	assert.EqualError(t, err, "CI 'gitlab' is not configured, no CI is configured")
+}
+
+const profiles = `
+registry: registry.example.com
+organisation: example
+vcs:
+  github:
+    token: token
+ci:
+  buildkite:
+    token: token
+profile: work
+profiles:
+  work:
+    organisation: work
+    vcs:
+      gitlab:
+        group: work
+        token: token
+    ci:
+      gitlab:
+        token: token
+  oss:
+    registry: ghcr.io
+`
+
+func TestLoad_Default_Profile(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(profiles), 0777)
+
+	cfg, err := Load(name, Selection{}, report.Nop)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "work", cfg.Profile)
+	assert.Equal(t, "Gitlab", cfg.CurrentVCS.Name())
+	assert.Equal(t, "Gitlab", cfg.CurrentCI.Name())
+	assert.Equal(t, "work", cfg.VCS.Gitlab.Group)
+	assert.Equal(t, "", cfg.VCS.Github.Token)
+	assert.Equal(t, "registry.example.com", cfg.RegistryUrl)
+	assert.Equal(t, "work", cfg.Organisation)
+}
+
+func TestLoad_Selected_Profile(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(profiles), 0777)
+
+	cfg, err := Load(name, Selection{Profile: "oss"}, report.Nop)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "oss", cfg.Profile)
+	assert.Equal(t, "Github", cfg.CurrentVCS.Name())
+	assert.Equal(t, "Buildkite", cfg.CurrentCI.Name())
+	assert.Equal(t, "ghcr.io", cfg.RegistryUrl)
+	assert.Equal(t, "example", cfg.Organisation)
+}
+
+func TestLoad_Unknown_Profile(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(profiles), 0777)
+
+	_, err := Load(name, Selection{Profile: "home"}, report.Nop)
+
+	assert.EqualError(t, err, "profile 'home' does not exist, available profiles are: oss, work")
+}
+
+func TestLoad_Profile_Without_Profiles(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+
+	_, err := Load(name, Selection{Profile: "work"}, report.Nop)
+
+	assert.EqualError(t, err, "profile 'work' does not exist, no profiles are configured")
+}
+
+func TestExplain(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	project := filepath.Join(name, "project")
+	_ = os.Mkdir(project, 0777)
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(`
+registry: registry.example.com
+organisation: example
+ci:
+  buildkite: {organisation: example, token: abc}
+`), 0777)
+	_ = ioutil.WriteFile(filepath.Join(project, ".scaffold.yaml"), []byte(`
+organisation: project
+vcs:
+  github:
+    token: abc
+profiles:
+  oss:
+    registry: ghcr.io
+`), 0777)
+	_ = os.Setenv("GITHUB_ORG", "env")
+	defer func() { _ = os.Unsetenv("GITHUB_ORG") }()
+	cfg, err := Load(project, Selection{Profile: "oss"}, report.Nop)
+	assert.NoError(t, err)
+	out := &bytes.Buffer{}
+
+	err = cfg.Explain(out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`# VCS: Github
+# CI: Buildkite
+vcs:
+  github:
+    token: '********'  # %[2]s/.scaffold.yaml:5
+    organisation: env  # env GITHUB_ORG
+    public: false  # default
+  gitlab:
+    group: ""  # default
+    token: ""  # default
+    visibility: ""  # default
+ci:
+  buildkite:
+    organisation: example  # %[1]s/.scaffold.yaml:5
+    token: '********'  # %[1]s/.scaffold.yaml:5
+  gitlab:
+    group: ""  # default
+    token: ""  # default
+registry: ghcr.io  # %[2]s/.scaffold.yaml:8
+organisation: project  # %[2]s/.scaffold.yaml:2
+profile: oss  # flag --profile
+`, name, project), out.String())
+}
+
+func TestExplain_Profile_Providers(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(profiles), 0777)
+	cfg, _ := Load(name, Selection{VCS: "gitlab"}, report.Nop)
+
+	assert.Equal(t, "flag --vcs", cfg.sources["vcs.default"])
+	assert.Equal(t, filepath.Join(name, ".scaffold.yaml")+":16", cfg.sources["vcs.gitlab.group"])
+	assert.Equal(t, filepath.Join(name, ".scaffold.yaml")+":13", cfg.sources["organisation"])
+	assert.Equal(t, filepath.Join(name, ".scaffold.yaml")+":2", cfg.sources["registry"])
+	assert.NotContains(t, cfg.sources, "vcs.github.token")
+}
+
+func TestLoad_Config_Precedence(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	project := filepath.Join(name, "project")
+	user := filepath.Join(name, "home", "scaffold")
+	_ = os.MkdirAll(project, 0777)
+	_ = os.MkdirAll(user, 0777)
+	_ = os.Setenv("XDG_CONFIG_HOME", filepath.Join(name, "home"))
+	defer func() { _ = os.Unsetenv("XDG_CONFIG_HOME") }()
+	_ = ioutil.WriteFile(filepath.Join(user, "config.yaml"), []byte("registry: user\norganisation: user\nprofile: user\n"), 0777)
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte("registry: parent\norganisation: parent\n"), 0777)
+	_ = ioutil.WriteFile(filepath.Join(project, ".scaffold.yaml"), []byte("registry: project\n"), 0777)
+	_ = ioutil.WriteFile(filepath.Join(name, "explicit.yaml"), []byte("profiles:\n  user:\n    registry: explicit\n"), 0777)
+	out := &bytes.Buffer{}
+
+	cfg, err := Load(project, Selection{Config: "../explicit.yaml"}, report.NewText(out, false))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "explicit", cfg.RegistryUrl)
+	assert.Equal(t, "parent", cfg.Organisation)
+	assert.Equal(t, "user", cfg.Profile)
+	assert.Equal(t, "Parsing config from file: '"+filepath.Join(name, "explicit.yaml")+"'\n"+
+		"Merging with config from file: '"+filepath.Join(project, ".scaffold.yaml")+"'\n"+
+		"Merging with config from file: '"+filepath.Join(name, ".scaffold.yaml")+"'\n"+
+		"Merging with config from file: '"+filepath.Join(user, "config.yaml")+"'\n", out.String())
+}
+
+func TestLoad_Config_From_Environment(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte("registry: registry.example.com\n"), 0777)
+	_ = os.Setenv("SCAFFOLD_CONFIG", file)
+	defer func() { _ = os.Unsetenv("SCAFFOLD_CONFIG") }()
+	out := &bytes.Buffer{}
+
+	cfg, err := Load(name, Selection{}, report.NewText(out, false))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.example.com", cfg.RegistryUrl)
+	assert.Equal(t, "Parsing config from file: '"+file+"'\n", out.String())
+}
+
+func TestLoad_Config_Missing(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = os.Setenv("SCAFFOLD_CONFIG", filepath.Join(name, "unused.yaml"))
+	defer func() { _ = os.Unsetenv("SCAFFOLD_CONFIG") }()
+
+	_, err := Load(name, Selection{Config: "missing.yaml"}, report.Nop)
+
+	assert.EqualError(t, err, "config file '"+filepath.Join(name, "missing.yaml")+"' does not exist")
+}
+
+func TestOverride(t *testing.T) {
+	cfg := InitEmptyConfig()
+	_ = parseConfig([]byte(`
+registry: registry.example.com
+organisation: example
+vcs:
+  gitlab:
+    group: group
+    token: abc
+ci:
+  gitlab:
+    group: group
+    token: abc
+`), cfg)
+	var overrides yaml.MapSlice
+	_ = yaml.Unmarshal([]byte(`
+registry: registry.example.com/orders
+vcs:
+  gitlab:
+    visibility: internal
+`), &overrides)
+
+	err := cfg.Override(overrides)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.example.com/orders", cfg.RegistryUrl)
+	assert.Equal(t, "example", cfg.Organisation)
+	assert.Equal(t, &vcs.Gitlab{Group: "group", Token: "abc", Visibility: "internal"}, cfg.CurrentVCS)
+	assert.Equal(t, cfg.VCS.Gitlab, cfg.CurrentVCS)
+}
+
+func TestOverride_Second_Provider(t *testing.T) {
+	cfg := InitEmptyConfig()
+	_ = parseConfig([]byte(`
+vcs:
+  gitlab:
+    group: group
+`), cfg)
+
+	err := cfg.Override(yaml.MapSlice{{Key: "vcs", Value: yaml.MapSlice{{Key: "github", Value: yaml.MapSlice{{Key: "token", Value: "abc"}}}}}})
+
+	assert.NoError(t, err)
+	assert.EqualError(t, cfg.ValidateConfig(), "several VCS are configured (github, gitlab), choose one with --vcs or vcs.default")
+}
+
+func TestOverride_Default_Provider(t *testing.T) {
+	cfg := InitEmptyConfig()
+	_ = parseConfig([]byte(`
+vcs:
+  gitlab:
+    group: group
+`), cfg)
+
+	err := cfg.Override(yaml.MapSlice{{Key: "vcs", Value: yaml.MapSlice{
+		{Key: "github", Value: yaml.MapSlice{{Key: "token", Value: "abc"}}},
+		{Key: "default", Value: "github"},
+	}}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.VCS.Github, cfg.CurrentVCS)
+}
+
+func TestOverride_Zero_Values(t *testing.T) {
+	cfg := InitEmptyConfig()
+	_ = parseConfig([]byte(`
+organisation: example
+vcs:
+  github:
+    organisation: example
+    token: abc
+    public: true
+`), cfg)
+	var overrides yaml.MapSlice
+	_ = yaml.Unmarshal([]byte(`
+organisation: ""
+vcs:
+  github:
+    public: false
+`), &overrides)
+
+	err := cfg.Override(overrides)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", cfg.Organisation)
+	assert.Equal(t, &vcs.Github{Organisation: "example", Token: "abc", Public: false}, cfg.CurrentVCS)
+}
+
+func TestOverride_Unknown_Key(t *testing.T) {
+	cfg := InitEmptyConfig()
+
+	err := cfg.Override(yaml.MapSlice{{Key: "registy", Value: "registry.example.com"}})
+
+	assert.EqualError(t, err, "yaml: unmarshal errors:\n  line 1: field registy not found in type config.Config")
+}
+
+type recordingListener struct {
+	steps []events.Step
+}
+
+func (r *recordingListener) Step(step events.Step) {
+	step.DurationMs = 0
+	r.steps = append(r.steps, step)
+}
+
+func (r *recordingListener) Finish(name, stack string, exitCode int) {
+}
+
+var _ events.Listener = &recordingListener{}
+
 type errorStack struct{}
 
-func (e errorStack) Scaffold(dir string, data templating.TemplateData) error {
+func (e errorStack) Scaffold(fs billy.Filesystem, data templating.TemplateData) error {
 	return errors.New("error")
 }
 
@@ -346,10 +1228,11 @@ type mockCi struct {
 	validateErr error
 	badgesErr   error
 	scaffoldErr error
+	deleteErr   error
 }
 
 func (m mockCi) Name() string {
-	panic("implement me")
+	return "mockCi"
 }
 
 func (m mockCi) ValidateConfig() error {
@@ -368,25 +1251,39 @@ func (m mockCi) Commit() string {
 	panic("implement me")
 }
 
-func (m mockCi) Validate(name string) error {
+func (m mockCi) Adopt(ctx context.Context, path string) error {
 	return m.validateErr
 }
 
-func (m mockCi) Scaffold(dir string, data templating.TemplateData) (*string, error) {
+func (m mockCi) Validate(ctx context.Context, name string) error {
+	return m.validateErr
+}
+
+func (m mockCi) Scaffold(ctx context.Context, fs billy.Filesystem, data templating.TemplateData) (*string, error) {
 	if m.scaffoldErr != nil {
 		return nil, m.scaffoldErr
 	}
 	return m.webhookUrl, nil
 }
 
-func (m mockCi) Badges(name string) ([]templating.Badge, error) {
+func (m mockCi) Badges(ctx context.Context, name string) ([]templating.Badge, error) {
 	return nil, m.badgesErr
 }
 
-func (m mockCi) Configure() error {
+func (m mockCi) DryRun(r report.Reporter) {
+}
+
+func (m mockCi) DeletePipeline(ctx context.Context, name string) error {
+	return m.deleteErr
+}
+
+func (m mockCi) Configure(r report.Reporter) error {
 	return nil
 }
 
+func (m mockCi) Check(ctx context.Context, report func(check string, err error)) {
+}
+
 func (m mockCi) Configured() bool {
 	return true
 }
@@ -398,7 +1295,11 @@ type mockVcs struct {
 	validateErr error
 	scaffoldErr error
 	cloneErr    error
+	commitErr   error
+	pushErr     error
+	pullErr     error
 	webhookErr  error
+	deleteErr   error
 	httpUrl     string
 }
 
@@ -410,14 +1311,20 @@ func (m mockVcs) ValidateConfig() error {
 	panic("implement me")
 }
 
-func (m mockVcs) Configure() {
+func (m mockVcs) Configure(r report.Reporter) {
+}
+
+func (m mockVcs) Check(ctx context.Context, report func(check string, err error)) {
 }
 
-func (m mockVcs) Validate(name string) error {
+func (m mockVcs) DryRun(r report.Reporter) {
+}
+
+func (m mockVcs) Validate(ctx context.Context, name string) error {
 	return m.validateErr
 }
 
-func (m mockVcs) Scaffold(name string) (*vcs.RepositoryInfo, error) {
+func (m mockVcs) Scaffold(ctx context.Context, name string) (*vcs.RepositoryInfo, error) {
 	if m.scaffoldErr != nil {
 		return nil, m.scaffoldErr
 	}
@@ -427,11 +1334,23 @@ func (m mockVcs) Scaffold(name string) (*vcs.RepositoryInfo, error) {
 	}, nil
 }
 
-func (m mockVcs) Webhook(name, url string) error {
+func (m mockVcs) Adopt(repository *vcs.RepositoryInfo) error {
+	return nil
+}
+
+func (m mockVcs) Webhook(ctx context.Context, name, url string) error {
 	return m.webhookErr
 }
 
-func (m mockVcs) Clone(dir, name, url string, out io.Writer) error {
+func (m mockVcs) DeleteRepository(ctx context.Context, name string) error {
+	return m.deleteErr
+}
+
+func (m mockVcs) DeleteWebhook(ctx context.Context, name string) error {
+	return nil
+}
+
+func (m mockVcs) Clone(ctx context.Context, dir, name, url string, out io.Writer) error {
 	if m.cloneErr != nil {
 		return m.cloneErr
 	}
@@ -441,4 +1360,34 @@ func (m mockVcs) Clone(dir, name, url string, out io.Writer) error {
 	return nil
 }
 
+func (m mockVcs) Commit(dir, name string, files []string, commit vcs.Commit) error {
+	return m.commitErr
+}
+
+func (m mockVcs) Push(ctx context.Context, dir, name string, out io.Writer) error {
+	return m.pushErr
+}
+
+func (m mockVcs) PullRequest(ctx context.Context, name, branch, title, body string) (string, error) {
+	if m.pullErr != nil {
+		return "", m.pullErr
+	}
+	return fmt.Sprintf("https://example.org/%s/pull/1", name), nil
+}
+
 var _ vcs.VCS = &mockVcs{}
+
+type cancelAfter struct {
+	step   string
+	cancel context.CancelFunc
+}
+
+func (c *cancelAfter) Step(step events.Step) {
+	if step.Name == c.step {
+		c.cancel()
+	}
+}
+
+func (c *cancelAfter) Finish(name, stack string, exitCode int) {}
+
+var _ events.Listener = &cancelAfter{}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+var variable = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// expand replaces ${VAR} and ${VAR:-default} in the string values of value with the environment variable, or default
+// when it is unset or empty, and $$ with $. An expanded value stays a string unless schema wants a bool or a number.
+func (c *checker) expand(schema *Schema, path string, value interface{}) interface{} {
+	switch value := value.(type) {
+	case yaml.MapSlice:
+		prefix := ""
+		if path != "" {
+			prefix = path + "."
+		}
+		expanded := make(yaml.MapSlice, len(value))
+		for i, item := range value {
+			key := fmt.Sprint(item.Key)
+			expanded[i] = yaml.MapItem{Key: item.Key, Value: c.expand(schema.property(key), prefix+key, item.Value)}
+		}
+		return expanded
+	case []interface{}:
+		expanded := make([]interface{}, len(value))
+		for i, item := range value {
+			expanded[i] = c.expand(nil, path, item)
+		}
+		return expanded
+	case string:
+		if !variable.MatchString(value) {
+			return value
+		}
+		expanded := variable.ReplaceAllStringFunc(value, func(reference string) string {
+			match := variable.FindStringSubmatch(reference)
+			if match[1] == "" {
+				return "$"
+			}
+			if v, exists := os.LookupEnv(match[1]); exists && (v != "" || match[2] == "") {
+				return v
+			} else if match[2] != "" {
+				return match[2][2:]
+			}
+			c.report(path, "'%s' uses the environment variable %s, which is not set", path, match[1])
+			return reference
+		})
+		if schema == nil {
+			return expanded
+		}
+		switch schema.Type {
+		case "boolean":
+			var b bool
+			if err := yaml.Unmarshal([]byte(expanded), &b); err == nil && expanded != "" {
+				return b
+			}
+		case "integer":
+			if i, err := strconv.Atoi(expanded); err == nil {
+				return i
+			}
+		}
+		return expanded
+	}
+	return value
+}
+
+// property returns the schema of key in s, nil when s does not know the key
+func (s *Schema) property(key string) *Schema {
+	if s == nil {
+		return nil
+	}
+	if property, exists := s.Properties[key]; exists {
+		return property
+	}
+	additional, _ := s.AdditionalProperties.(*Schema)
+	return additional
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_Interpolation(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = os.Setenv("REGISTRY_HOST", "registry.example.com")
+	_ = os.Setenv("TEAM", "")
+	_ = os.Setenv("PUBLIC", "true")
+	defer func() {
+		_ = os.Unsetenv("REGISTRY_HOST")
+		_ = os.Unsetenv("TEAM")
+		_ = os.Unsetenv("PUBLIC")
+	}()
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(`
+registry: ${REGISTRY_HOST}/team
+organisation: ${TEAM:-example}
+vcs:
+  github:
+    organisation: ${UNSET_ORGANISATION:-example}
+    public: ${PUBLIC}
+    token: exec:echo $${REGISTRY_HOST}
+`), 0777)
+
+	cfg, err := Load(name, Selection{}, report.Nop)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.example.com/team", cfg.RegistryUrl)
+	assert.Equal(t, "example", cfg.Organisation)
+	assert.Equal(t, "example", cfg.VCS.Github.Organisation)
+	assert.True(t, cfg.VCS.Github.Public)
+	assert.Equal(t, "exec:echo ${REGISTRY_HOST}", cfg.VCS.Github.Token)
+}
+
+func TestLoad_Interpolation_Keeps_Strings(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = os.Setenv("ORGANISATION", "yes")
+	_ = os.Setenv("GROUP", "123")
+	defer func() {
+		_ = os.Unsetenv("ORGANISATION")
+		_ = os.Unsetenv("GROUP")
+	}()
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(`
+organisation: ${ORGANISATION}
+vcs:
+  gitlab:
+    group: ${GROUP}
+    token: abc
+`), 0777)
+
+	cfg, err := Load(name, Selection{}, report.Nop)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "yes", cfg.Organisation)
+	assert.Equal(t, "123", cfg.VCS.Gitlab.Group)
+}
+
+func TestLoad_Interpolation_Unset_Variable(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte("vcs:\n  github:\n    organisation: ${UNSET_ORGANISATION}\n"), 0777)
+
+	_, err := Load(name, Selection{}, report.Nop)
+
+	assert.EqualError(t, err, file+":3: 'vcs.github.organisation' uses the environment variable UNSET_ORGANISATION, which is not set")
+}
+
+func TestCheck_Interpolated_Value(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte("vcs:\n  github:\n    public: ${PUBLIC:-maybe}\n"), 0777)
+
+	problems, err := Check(name, Selection{}, file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Problem{{File: file, Line: 3, Message: "'vcs.github.public' must be true or false"}}, problems)
+}
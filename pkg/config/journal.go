@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/config/vcs"
+	"github.com/buildtool/scaffold/pkg/templating"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+type journal struct {
+	path       string
+	Steps      []string                `yaml:"steps"`
+	Repository *vcs.RepositoryInfo     `yaml:"repository,omitempty"`
+	Data       templating.TemplateData `yaml:"data"`
+	Webhook    *string                 `yaml:"webhook,omitempty"`
+	Files      []string                `yaml:"files,omitempty"`
+}
+
+func journalPath(dir, name string) string {
+	return filepath.Join(dir, fmt.Sprintf(".%s.scaffold-journal", name))
+}
+
+func newJournal(path string) *journal {
+	return &journal{path: path}
+}
+
+func loadJournal(path string) (*journal, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no previous run to resume, '%s' does not exist", path)
+		}
+		return nil, err
+	}
+	j := newJournal(path)
+	if err := yaml.UnmarshalStrict(content, j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *journal) completed(step string) bool {
+	for _, s := range j.Steps {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+// record remembers a file written by a step, it is saved together with the step
+func (j *journal) record(name string) {
+	if name == stateFile {
+		return
+	}
+	for _, f := range j.Files {
+		if f == name {
+			return
+		}
+	}
+	j.Files = append(j.Files, name)
+}
+
+func (j *journal) complete(step string) error {
+	j.Steps = append(j.Steps, step)
+	return j.save()
+}
+
+// reset forgets steps completed after the first n, removing the journal if none remain
+func (j *journal) reset(n int) error {
+	j.Steps = j.Steps[:n]
+	if n == 0 {
+		return j.remove()
+	}
+	return j.save()
+}
+
+// forget removes steps from the completed ones, so that a resumed run performs them again
+func (j *journal) forget(steps ...string) error {
+	var kept []string
+	for _, s := range j.Steps {
+		forgotten := false
+		for _, step := range steps {
+			if s == step {
+				forgotten = true
+			}
+		}
+		if !forgotten {
+			kept = append(kept, s)
+		}
+	}
+	j.Steps = kept
+	return j.save()
+}
+
+func (j *journal) save() error {
+	if j.path == "" {
+		return nil
+	}
+	content, err := yaml.Marshal(j)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(j.path, content, 0666)
+}
+
+func (j *journal) remove() error {
+	if j.path == "" {
+		return nil
+	}
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
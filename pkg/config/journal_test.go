@@ -0,0 +1,64 @@
+package config
+
+import (
+	"github.com/buildtool/scaffold/pkg/config/vcs"
+	"github.com/buildtool/scaffold/pkg/templating"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal_Complete_Saves(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	journal := newJournal(journalPath(name, "project"))
+	journal.Repository = &vcs.RepositoryInfo{SSHURL: "git@example.com:org/project.git", HTTPURL: "https://example.com/org/project.git"}
+	journal.Data = templating.TemplateData{
+		ProjectName: "project",
+		Badges:      []templating.Badge{{Title: "Build status", ImageUrl: "https://img", LinkUrl: "https://link"}},
+	}
+
+	assert.NoError(t, journal.complete("repository"))
+
+	loaded, err := loadJournal(journalPath(name, "project"))
+	assert.NoError(t, err)
+	assert.True(t, loaded.completed("repository"))
+	assert.False(t, loaded.completed("clone"))
+	assert.Equal(t, journal, loaded)
+}
+
+func TestJournal_Load_Invalid(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = os.MkdirAll(name, 0777)
+	_ = ioutil.WriteFile(journalPath(name, "project"), []byte("unknown: true"), 0666)
+
+	_, err := loadJournal(journalPath(name, "project"))
+
+	assert.EqualError(t, err, "yaml: unmarshal errors:\n  line 1: field unknown not found in type config.journal")
+}
+
+func TestJournal_Reset(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	journal := newJournal(journalPath(name, "project"))
+	_ = journal.complete("repository")
+	_ = journal.complete("clone")
+
+	assert.NoError(t, journal.reset(1))
+	loaded, _ := loadJournal(journalPath(name, "project"))
+	assert.Equal(t, []string{"repository"}, loaded.Steps)
+
+	assert.NoError(t, journal.reset(0))
+	_, err := os.Stat(journalPath(name, "project"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestJournal_Without_Path(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	journal := newJournal("")
+
+	assert.NoError(t, journal.complete("repository"))
+	assert.NoError(t, journal.remove())
+	files, _ := filepath.Glob(filepath.Join(name, ".*"))
+	assert.Empty(t, files)
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/report"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"strings"
+)
+
+// Strategy tells how a value in a configuration file is combined with the value inherited from the files it takes
+// precedence over, the strategies of a file are declared under merge by the dotted path of the value
+type Strategy string
+
+const (
+	// Override merges mappings key by key, any other value replaces the inherited one
+	Override Strategy = "override"
+	// Append adds the items of a list after the inherited ones
+	Append Strategy = "append"
+	// Replace replaces the inherited value, also when it is a mapping
+	Replace Strategy = "replace"
+	// Unset drops the inherited value, whether the file sets the value or not
+	Unset Strategy = "unset"
+)
+
+var strategies = []string{string(Override), string(Append), string(Replace), string(Unset)}
+
+// layer is a configuration file without its merge section
+type layer struct {
+	file       string
+	doc        yaml.MapSlice
+	lines      map[string]int
+	strategies map[string]Strategy
+}
+
+// readLayers reads the configuration files, see configFiles, and returns them with the lowest precedence first
+func readLayers(dir, explicit string, r report.Reporter) ([]layer, error) {
+	files, err := configFiles(dir, explicit)
+	if err != nil {
+		return nil, err
+	}
+	layers := make([]layer, len(files))
+	for i, file := range files {
+		if i == 0 {
+			r.Info("Parsing config from file: '%s'", file)
+		} else {
+			r.Info("Merging with config from file: '%s'", file)
+		}
+		if layers[len(files)-1-i], err = readLayer(file); err != nil {
+			return nil, err
+		}
+	}
+	return layers, nil
+}
+
+func readLayer(filename string) (layer, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return layer{}, err
+	}
+	doc, problems := checkFile(filename, content)
+	if len(problems) > 0 {
+		return layer{}, Problems(problems)
+	}
+	l := layer{file: filename, lines: keyLines(content), strategies: make(map[string]Strategy)}
+	for _, item := range doc {
+		if item.Key != "merge" {
+			l.doc = append(l.doc, item)
+			continue
+		}
+		merge, _ := item.Value.(yaml.MapSlice)
+		for _, strategy := range merge {
+			l.strategies[fmt.Sprint(strategy.Key)] = Strategy(fmt.Sprint(strategy.Value))
+		}
+	}
+	return l, nil
+}
+
+// mergeLayers combines the layers, each taking precedence over the ones before it, and records in s where the values
+// came from
+func mergeLayers(layers []layer, s sources) (yaml.MapSlice, error) {
+	var merged yaml.MapSlice
+	for _, l := range layers {
+		for path, strategy := range l.strategies {
+			if strategy == Unset {
+				merged = unset(merged, strings.Split(path, "."))
+				s.clear(path)
+			}
+		}
+		var err error
+		if merged, err = l.merge(merged, l.doc, "", s); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+func (l layer) merge(inherited, doc yaml.MapSlice, prefix string, s sources) (yaml.MapSlice, error) {
+	merged := append(yaml.MapSlice{}, inherited...)
+	for _, item := range doc {
+		// A key without a value leaves the inherited value as it is
+		if item.Value == nil {
+			continue
+		}
+		path := prefix + fmt.Sprint(item.Key)
+		i := indexOf(merged, item.Key)
+		if i < 0 {
+			merged = append(merged, item)
+			l.record(path, item.Value, s)
+			continue
+		}
+		value, err := l.combine(merged[i].Value, item.Value, path, s)
+		if err != nil {
+			return nil, err
+		}
+		merged[i].Value = value
+	}
+	return merged, nil
+}
+
+func (l layer) combine(inherited, value interface{}, path string, s sources) (interface{}, error) {
+	switch l.strategies[path] {
+	case Append:
+		previous, inheritedList := inherited.([]interface{})
+		items, list := value.([]interface{})
+		if !inheritedList || !list {
+			return nil, Problems{{File: l.file, Line: lineOf(l.lines, path), Message: fmt.Sprintf("'%s' can only be appended to when it is a list", path)}}
+		}
+		l.record(path, value, s)
+		return append(append([]interface{}{}, previous...), items...), nil
+	case Replace:
+	default:
+		inheritedDoc, inheritedMapping := inherited.(yaml.MapSlice)
+		doc, mapping := value.(yaml.MapSlice)
+		if inheritedMapping && mapping {
+			return l.merge(inheritedDoc, doc, path+".", s)
+		}
+	}
+	s.clear(path)
+	l.record(path, value, s)
+	return value, nil
+}
+
+// record sets the source of value at path and of the values below it
+func (l layer) record(path string, value interface{}, s sources) {
+	if s == nil {
+		return
+	}
+	paths := []string{path}
+	if doc, ok := value.(yaml.MapSlice); ok {
+		paths = leaves(path+".", doc)
+	}
+	for _, path := range paths {
+		if line := lineOf(l.lines, path); line > 0 {
+			s[path] = fmt.Sprintf("%s:%d", l.file, line)
+		} else {
+			s[path] = l.file
+		}
+	}
+}
+
+// unset returns doc without the value at the path of keys
+func unset(doc yaml.MapSlice, keys []string) yaml.MapSlice {
+	var result yaml.MapSlice
+	for _, item := range doc {
+		if fmt.Sprint(item.Key) == keys[0] {
+			if len(keys) == 1 {
+				continue
+			}
+			if nested, ok := item.Value.(yaml.MapSlice); ok {
+				item.Value = unset(nested, keys[1:])
+			}
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func indexOf(doc yaml.MapSlice, key interface{}) int {
+	for i, item := range doc {
+		if item.Key == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"bytes"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeLayers(t *testing.T) {
+	parent := layer{file: "parent.yaml", lines: map[string]int{"steps": 1, "events": 2, "team.admin": 4}, doc: yaml.MapSlice{
+		{Key: "steps", Value: []interface{}{"build"}},
+		{Key: "events", Value: []interface{}{"push"}},
+		{Key: "team", Value: yaml.MapSlice{{Key: "admin", Value: "alice"}, {Key: "read", Value: "bob"}}},
+		{Key: "public", Value: true},
+	}}
+	project := layer{file: "project.yaml", lines: map[string]int{"steps": 1, "events": 2, "team": 3, "public": 5}, doc: yaml.MapSlice{
+		{Key: "steps", Value: []interface{}{"deploy"}},
+		{Key: "events", Value: []interface{}{"tag"}},
+		{Key: "team", Value: yaml.MapSlice{{Key: "write", Value: "carol"}}},
+		{Key: "public", Value: false},
+	}, strategies: map[string]Strategy{"steps": Append, "team": Replace}}
+	s := sources{}
+
+	doc, err := mergeLayers([]layer{parent, project}, s)
+
+	assert.NoError(t, err)
+	assert.Equal(t, yaml.MapSlice{
+		{Key: "steps", Value: []interface{}{"build", "deploy"}},
+		{Key: "events", Value: []interface{}{"tag"}},
+		{Key: "team", Value: yaml.MapSlice{{Key: "write", Value: "carol"}}},
+		{Key: "public", Value: false},
+	}, doc)
+	assert.Equal(t, sources{"steps": "project.yaml:1", "events": "project.yaml:2", "team.write": "project.yaml:3", "public": "project.yaml:5"}, s)
+}
+
+func TestMergeLayers_Unset(t *testing.T) {
+	parent := layer{file: "parent.yaml", lines: map[string]int{"team.admin": 2, "team.read": 3}, doc: yaml.MapSlice{
+		{Key: "team", Value: yaml.MapSlice{{Key: "admin", Value: "alice"}, {Key: "read", Value: "bob"}}},
+	}}
+	project := layer{file: "project.yaml", doc: yaml.MapSlice{}, strategies: map[string]Strategy{"team.read": Unset}}
+	s := sources{}
+
+	doc, err := mergeLayers([]layer{parent, project}, s)
+
+	assert.NoError(t, err)
+	assert.Equal(t, yaml.MapSlice{{Key: "team", Value: yaml.MapSlice{{Key: "admin", Value: "alice"}}}}, doc)
+	assert.Equal(t, sources{"team.admin": "parent.yaml:2"}, s)
+}
+
+func TestMergeLayers_Append_Not_A_List(t *testing.T) {
+	parent := layer{file: "parent.yaml", doc: yaml.MapSlice{{Key: "steps", Value: "build"}}}
+	project := layer{file: "project.yaml", lines: map[string]int{"steps": 3}, doc: yaml.MapSlice{
+		{Key: "steps", Value: []interface{}{"deploy"}},
+	}, strategies: map[string]Strategy{"steps": Append}}
+
+	_, err := mergeLayers([]layer{parent, project}, nil)
+
+	assert.EqualError(t, err, "project.yaml:3: 'steps' can only be appended to when it is a list")
+}
+
+func TestLoad_Merge_Strategies(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	project := filepath.Join(name, "project")
+	_ = os.MkdirAll(project, 0777)
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(`
+registry: registry.example.com
+organisation: example
+vcs:
+  github:
+    organisation: example
+    token: abc
+    public: true
+ci:
+  buildkite:
+    organisation: example
+    token: abc
+`), 0777)
+	file := filepath.Join(project, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(`
+merge:
+  registry: unset
+  vcs: replace
+vcs:
+  gitlab:
+    group: group
+    token: def
+ci:
+  buildkite:
+    token: def
+`), 0777)
+
+	cfg, err := Load(project, Selection{}, report.Nop)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", cfg.RegistryUrl)
+	assert.Equal(t, "example", cfg.Organisation)
+	assert.Equal(t, "", cfg.VCS.Github.Token)
+	assert.Equal(t, "group", cfg.VCS.Gitlab.Group)
+	assert.Equal(t, "example", cfg.CI.Buildkite.Organisation)
+	assert.Equal(t, "def", cfg.CI.Buildkite.Token)
+	out := &bytes.Buffer{}
+	assert.NoError(t, cfg.Explain(out))
+	assert.Contains(t, out.String(), "registry: \"\"  # default\n")
+	assert.Contains(t, out.String(), "    group: group  # "+file+":7\n")
+	assert.Contains(t, out.String(), "    token: '********'  # "+file+":11\n")
+}
+
+func TestLoad_Explicit_Zero_Value_Overrides(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	project := filepath.Join(name, "project")
+	_ = os.MkdirAll(project, 0777)
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte("vcs:\n  github:\n    token: abc\n    public: true\n"), 0777)
+	_ = ioutil.WriteFile(filepath.Join(project, ".scaffold.yaml"), []byte("vcs:\n  github:\n    public: false\n"), 0777)
+
+	cfg, err := Load(project, Selection{}, report.Nop)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", cfg.VCS.Github.Token)
+	assert.False(t, cfg.VCS.Github.Public)
+}
+
+func TestCheck_Unknown_Merge_Strategy(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte("merge:\n  registry: remove\n"), 0777)
+
+	problems, err := Check(name, Selection{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Problem{{File: file, Line: 2, Message: "'merge.registry' must be one of override, append, replace, unset"}}, problems)
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"github.com/imdario/mergo"
+	"sort"
+	"strings"
+)
+
+// Profile is a named set of providers and settings, the sections it defines replace the top-level ones when selected
+type Profile struct {
+	VCS          *VCSConfig `yaml:"vcs"`
+	CI           *CIConfig  `yaml:"ci"`
+	RegistryUrl  string     `yaml:"registry"`
+	Organisation string     `yaml:"organisation"`
+}
+
+// UseProfile applies the profile called name, or the default profile when name is empty
+func (c *Config) UseProfile(name string) error {
+	if name == "" {
+		name = c.Profile
+	}
+	if name == "" {
+		return nil
+	}
+	profile, exists := c.Profiles[name]
+	if !exists {
+		if len(c.Profiles) == 0 {
+			return fmt.Errorf("profile '%s' does not exist, no profiles are configured", name)
+		}
+		return fmt.Errorf("profile '%s' does not exist, available profiles are: %s", name, strings.Join(c.profileNames(), ", "))
+	}
+	c.Profile = name
+	if profile == nil {
+		return nil
+	}
+	empty := InitEmptyConfig()
+	if profile.VCS != nil {
+		if err := mergo.Merge(empty.VCS, profile.VCS, mergo.WithOverride); err != nil {
+			return err
+		}
+		c.VCS = empty.VCS
+		c.sources.inherit("vcs", "profiles."+name+".vcs")
+	}
+	if profile.CI != nil {
+		if err := mergo.Merge(empty.CI, profile.CI, mergo.WithOverride); err != nil {
+			return err
+		}
+		c.CI = empty.CI
+		c.sources.inherit("ci", "profiles."+name+".ci")
+	}
+	if profile.RegistryUrl != "" {
+		c.RegistryUrl = profile.RegistryUrl
+		c.sources.inherit("registry", "profiles."+name+".registry")
+	}
+	if profile.Organisation != "" {
+		c.Organisation = profile.Organisation
+		c.sources.inherit("organisation", "profiles."+name+".organisation")
+	}
+	return nil
+}
+
+func (c *Config) profileNames() []string {
+	var names []string
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/report"
+	"strings"
+)
+
+type rollback struct {
+	actions []rollbackAction
+}
+
+type rollbackAction struct {
+	step     string
+	resource string
+	undo     func() error
+}
+
+// add registers undo for the resource created by step
+func (r *rollback) add(step, resource string, undo func() error) {
+	r.actions = append(r.actions, rollbackAction{step: step, resource: resource, undo: undo})
+}
+
+// run removes the resources in reverse order, keeping the actions of the ones that could not be removed. It returns the
+// steps whose resources were removed and an error naming the ones that are left
+func (r *rollback) run(reporter report.Reporter) ([]string, error) {
+	var removed, failed []string
+	var left []rollbackAction
+	for i := len(r.actions) - 1; i >= 0; i-- {
+		action := r.actions[i]
+		reporter.Warning("Removing %s", action.resource)
+		if err := action.undo(); err != nil {
+			reporter.Error(fmt.Errorf("Failed to remove %s: %s", action.resource, err.Error()))
+			failed = append(failed, action.resource)
+			left = append([]rollbackAction{action}, left...)
+		} else {
+			removed = append(removed, action.step)
+		}
+	}
+	r.actions = left
+	if len(failed) > 0 {
+		return removed, fmt.Errorf("failed to remove %s", strings.Join(failed, ", "))
+	}
+	return removed, nil
+}
+
+// left tells if a resource created by step could not be removed
+func (r *rollback) left(step string) bool {
+	for _, action := range r.actions {
+		if action.step == step {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rollback) keep(reporter report.Reporter) {
+	for _, action := range r.actions {
+		reporter.Warning("Keeping %s", action.resource)
+	}
+}
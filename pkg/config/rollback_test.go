@@ -0,0 +1,44 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRollback_Run_In_Reverse_Order(t *testing.T) {
+	var undone []string
+	rollback := &rollback{}
+	rollback.add("one", "first", func() error {
+		undone = append(undone, "first")
+		return nil
+	})
+	rollback.add("two", "second", func() error {
+		undone = append(undone, "second")
+		return errors.New("remove error")
+	})
+	out := &bytes.Buffer{}
+
+	removed, err := rollback.run(report.NewText(out, true))
+
+	assert.EqualError(t, err, "failed to remove second")
+	assert.Equal(t, []string{"second", "first"}, undone)
+	assert.Equal(t, []string{"one"}, removed)
+	assert.True(t, rollback.left("two"))
+	assert.False(t, rollback.left("one"))
+	assert.Equal(t, "\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1msecond\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mFailed to remove second: remove error\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mRemoving \x1b[39m\x1b[97m\x1b[1mfirst\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", out.String())
+}
+
+func TestRollback_Keep(t *testing.T) {
+	rollback := &rollback{}
+	rollback.add("one", "first", func() error {
+		panic("should not be called")
+	})
+	out := &bytes.Buffer{}
+
+	rollback.keep(report.NewText(out, true))
+
+	assert.Equal(t, "\x1b[0m\x1b[33mKeeping \x1b[39m\x1b[97m\x1b[1mfirst\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", out.String())
+}
@@ -0,0 +1,289 @@
+package config
+
+import (
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/report"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema needed to describe .scaffold.yaml
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+}
+
+// ConfigSchema generates the schema of .scaffold.yaml from the yaml tags of Config and the providers
+func ConfigSchema() *Schema {
+	schema := schemaOf(reflect.TypeOf(Config{}))
+	// merge is taken out of every file before the files are merged, see readLayer
+	schema.Properties["merge"] = &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string", Enum: strategies}}
+	schema.Schema = "http://json-schema.org/draft-07/schema#"
+	schema.Title = ".scaffold.yaml"
+	return schema
+}
+
+func schemaOf(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem())
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem())}
+	case reflect.Struct:
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if field.PkgPath != "" || name == "" || name == "-" {
+				continue
+			}
+			schema.Properties[name] = schemaOf(field.Type)
+		}
+		// default names one of the providers next to it
+		if def, exists := schema.Properties["default"]; exists {
+			for name := range schema.Properties {
+				if name != "default" {
+					def.Enum = append(def.Enum, name)
+				}
+			}
+			sort.Strings(def.Enum)
+		}
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer"}
+	}
+	return &Schema{Type: "string"}
+}
+
+// Problem is a mistake in a configuration file, Line is 0 when it is not known
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	switch {
+	case p.File == "":
+		return p.Message
+	case p.Line == 0:
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+// Problems is returned by Load when a configuration file does not match the schema
+type Problems []Problem
+
+func (p Problems) Error() string {
+	messages := make([]string, len(p))
+	for i, problem := range p {
+		messages[i] = problem.String()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Check returns the problems in the configuration files, those Load would read when none are given.
+// For the files Load would read the merged configuration is checked as well, for missing provider settings and the
+// choice between several providers.
+func Check(dir string, selection Selection, files ...string) ([]Problem, error) {
+	walked := len(files) == 0
+	if walked {
+		var err error
+		if files, err = configFiles(dir, selection.Config); err != nil {
+			return nil, err
+		}
+	}
+	var problems []Problem
+	for _, filename := range files {
+		content, err := ioutil.ReadFile(filename)
+		if e, ok := err.(*os.PathError); ok {
+			err = e.Err
+		}
+		if err != nil {
+			problems = append(problems, Problem{File: filename, Message: err.Error()})
+			continue
+		}
+		_, found := checkFile(filename, content)
+		problems = append(problems, found...)
+	}
+	if !walked || len(problems) > 0 {
+		return problems, nil
+	}
+	cfg, err := Load(dir, selection, report.Nop)
+	if e, ok := err.(Problems); ok {
+		return e, nil
+	} else if err != nil {
+		return []Problem{{Message: err.Error()}}, nil
+	}
+	problems = append(providerProblems(cfg, "ci", cfg.CI), providerProblems(cfg, "vcs", cfg.VCS)...)
+	// The tokens are not resolved, checking the files must not run the commands they refer to
+	if _, err := selectProvider("CI", "ci", cfg.CI, cfg.CI.Default); err != nil {
+		problems = append(problems, Problem{Message: err.Error()})
+	}
+	if _, err := selectProvider("VCS", "vcs", cfg.VCS, cfg.VCS.Default); err != nil {
+		problems = append(problems, Problem{Message: err.Error()})
+	}
+	return problems, nil
+}
+
+// providerProblems reports the providers of section that are partly configured in a file but miss required settings
+func providerProblems(cfg *Config, key string, section interface{}) []Problem {
+	var problems []Problem
+	elem := reflect.ValueOf(section).Elem()
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Field(i)
+		provider, ok := field.Interface().(interface{ ValidateConfig() error })
+		if !ok || field.IsNil() {
+			continue
+		}
+		err := provider.ValidateConfig()
+		if err == nil {
+			continue
+		}
+		path := key + "." + strings.Split(elem.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		var located []Problem
+		for value := range cfg.sources {
+			if file, line := cfg.sources.location(value); file != "" && strings.HasPrefix(value, path+".") {
+				located = append(located, Problem{File: file, Line: line, Message: fmt.Sprintf("'%s' is incomplete, %s", path, err)})
+			}
+		}
+		if len(located) == 0 {
+			continue
+		}
+		sort.Slice(located, func(i, j int) bool { return located[i].String() < located[j].String() })
+		problems = append(problems, located[0])
+	}
+	return problems
+}
+
+// locate splits a source recorded for a file into the file and the line
+func locate(source string) (string, int) {
+	i := strings.LastIndex(source, ":")
+	if i < 0 {
+		return source, 0
+	}
+	line, err := strconv.Atoi(source[i+1:])
+	if err != nil {
+		return source, 0
+	}
+	return source[:i], line
+}
+
+// checkFile returns content with the environment variables expanded, see expand, and the syntax errors, unset
+// variables and the keys and values that do not match the schema
+func checkFile(filename string, content []byte) (yaml.MapSlice, []Problem) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, yamlProblems(filename, err)
+	}
+	c := checker{file: filename, lines: keyLines(content)}
+	schema := ConfigSchema()
+	doc, _ = c.expand(schema, "", doc).(yaml.MapSlice)
+	c.check(schema, "", doc)
+	return doc, c.problems
+}
+
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+func yamlProblems(filename string, err error) []Problem {
+	messages := []string{err.Error()}
+	if e, ok := err.(*yaml.TypeError); ok {
+		messages = e.Errors
+	}
+	var problems []Problem
+	for _, message := range messages {
+		if match := yamlLine.FindStringSubmatch(message); match != nil {
+			line, _ := strconv.Atoi(match[1])
+			problems = append(problems, Problem{File: filename, Line: line, Message: match[2]})
+		} else {
+			problems = append(problems, Problem{File: filename, Message: strings.TrimPrefix(message, "yaml: ")})
+		}
+	}
+	return problems
+}
+
+type checker struct {
+	file     string
+	lines    map[string]int
+	problems []Problem
+}
+
+func (c *checker) check(schema *Schema, path string, value interface{}) {
+	if value == nil {
+		return
+	}
+	switch schema.Type {
+	case "object":
+		doc, ok := value.(yaml.MapSlice)
+		if !ok {
+			c.report(path, "'%s' must be a mapping", path)
+			return
+		}
+		prefix := ""
+		if path != "" {
+			prefix = path + "."
+		}
+		for _, item := range doc {
+			key := fmt.Sprint(item.Key)
+			if property, exists := schema.Properties[key]; exists {
+				c.check(property, prefix+key, item.Value)
+			} else if additional, ok := schema.AdditionalProperties.(*Schema); ok {
+				c.check(additional, prefix+key, item.Value)
+			} else {
+				c.report(prefix+key, "unknown key '%s', allowed keys are: %s", prefix+key, strings.Join(propertyNames(schema.Properties), ", "))
+			}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			c.report(path, "'%s' must be true or false", path)
+		}
+	case "integer":
+		if _, ok := value.(int); !ok {
+			c.report(path, "'%s' must be a number", path)
+		}
+	default:
+		switch value.(type) {
+		case yaml.MapSlice, []interface{}:
+			c.report(path, "'%s' must be a single value", path)
+			return
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, fmt.Sprint(value)) {
+			c.report(path, "'%s' must be one of %s", path, strings.Join(schema.Enum, ", "))
+		}
+	}
+}
+
+func (c *checker) report(path, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{File: c.file, Line: lineOf(c.lines, path), Message: fmt.Sprintf(format, args...)})
+}
+
+func propertyNames(properties map[string]*Schema) []string {
+	var names []string
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
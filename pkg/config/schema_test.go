@@ -0,0 +1,133 @@
+package config
+
+import (
+	"encoding/json"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConfigSchema_Is_Up_To_Date(t *testing.T) {
+	content, err := json.MarshalIndent(ConfigSchema(), "", "  ")
+	assert.NoError(t, err)
+
+	committed, err := ioutil.ReadFile(filepath.Join("..", "..", "scaffold.schema.json"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, string(content)+"\n", string(committed), "regenerate with `scaffold config schema > scaffold.schema.json`")
+}
+
+func TestConfigSchema(t *testing.T) {
+	schema := ConfigSchema()
+
+	assert.Equal(t, "http://json-schema.org/draft-07/schema#", schema.Schema)
+	assert.Equal(t, false, schema.AdditionalProperties)
+	assert.Equal(t, []string{"github", "gitlab"}, schema.Properties["vcs"].Properties["default"].Enum)
+	assert.Equal(t, &Schema{Type: "boolean"}, schema.Properties["vcs"].Properties["github"].Properties["public"])
+	assert.Equal(t, schemaOf(reflect.TypeOf(Profile{})), schema.Properties["profiles"].AdditionalProperties)
+	assert.NotContains(t, schema.Properties, "KeepOnFailure")
+}
+
+func TestCheck_Problems(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(`
+vcs:
+  github:
+    tokne: abc
+    public: maybe
+  default: bitbucket
+ci:
+  buildkite: {organisation: example, token: [abc]}
+profiles:
+  work:
+    registry: registry.example.com
+    org: work
+`), 0777)
+
+	problems, err := Check(name, Selection{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Problem{
+		{File: file, Line: 4, Message: "unknown key 'vcs.github.tokne', allowed keys are: organisation, public, token"},
+		{File: file, Line: 5, Message: "'vcs.github.public' must be true or false"},
+		{File: file, Line: 6, Message: "'vcs.default' must be one of github, gitlab"},
+		{File: file, Line: 8, Message: "'ci.buildkite.token' must be a single value"},
+		{File: file, Line: 12, Message: "unknown key 'profiles.work.org', allowed keys are: ci, organisation, registry, vcs"},
+	}, problems)
+}
+
+func TestCheck_Syntax_Error(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	file := filepath.Join(name, "broken.yaml")
+	_ = ioutil.WriteFile(file, []byte("vcs:\n  github:\n token: abc\n  - x"), 0777)
+
+	problems, err := Check(name, Selection{}, file, filepath.Join(name, "missing.yaml"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Problem{
+		{File: file, Line: 2, Message: "did not find expected key"},
+		{File: filepath.Join(name, "missing.yaml"), Message: "no such file or directory"},
+	}, problems)
+}
+
+func TestCheck_Incomplete_Provider(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(`
+vcs:
+  github:
+    token: abc
+  gitlab:
+    visibility: internal
+ci:
+  buildkite:
+    token: abc
+`), 0777)
+
+	problems, err := Check(name, Selection{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Problem{{File: file, Line: 6, Message: "'vcs.gitlab' is incomplete, gitlab group must be set"}}, problems)
+}
+
+func TestCheck_Several_Providers(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(providers), 0777)
+
+	problems, err := Check(name, Selection{CI: "travis"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Problem{{Message: "CI 'travis' is not configured, available CI are: buildkite, gitlab"}}, problems)
+	assert.Equal(t, "CI 'travis' is not configured, available CI are: buildkite, gitlab", problems[0].String())
+}
+
+func TestCheck_Valid(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(profiles), 0777)
+
+	problems, err := Check(name, Selection{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestLoad_Unknown_Key(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte("registy: registry.example.com\n"), 0777)
+
+	_, err := Load(name, Selection{}, report.Nop)
+
+	assert.EqualError(t, err, file+":1: unknown key 'registy', allowed keys are: ci, merge, organisation, profile, profiles, registry, vcs")
+}
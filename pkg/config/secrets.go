@@ -0,0 +1,147 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/config/ci"
+	"github.com/buildtool/scaffold/pkg/config/vcs"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// findReferences records the tokens that are references, file:<path>, env:<variable> or exec:<command>, by their path.
+// They are only resolved by ResolveSecrets, for the providers that are used.
+func (c *Config) findReferences() {
+	c.references = make(map[string]string)
+	c.tokens(func(path string, _ interface{}, token reflect.Value) {
+		if isReference(token.String()) {
+			c.references[path] = token.String()
+		}
+	})
+}
+
+// ResolveSecrets replaces the tokens of the given providers that are references with the token they point to. A relative
+// path is relative to the file the reference is in, or to the directory the configuration was loaded for. Commands are
+// only run when the reference is in the configuration of the user, the file given with --config or in the environment,
+// not when it is in a .scaffold.yaml that could come with a cloned repository.
+func (c *Config) ResolveSecrets(vcss []vcs.VCS, cis []ci.CI) error {
+	providers := make(map[interface{}]bool)
+	for _, provider := range vcss {
+		providers[provider] = true
+	}
+	for _, provider := range cis {
+		providers[provider] = true
+	}
+	resolved := make(map[string]string)
+	var problems Problems
+	c.tokens(func(path string, provider interface{}, token reflect.Value) {
+		reference, exists := c.references[path]
+		if !exists || !providers[provider] || token.String() != reference {
+			return
+		}
+		base := c.dir
+		file, line := c.sources.location(path)
+		if file != "" {
+			base = filepath.Dir(file)
+		}
+		secret, exists := resolved[base+"\x00"+reference]
+		if !exists {
+			var err error
+			if strings.HasPrefix(reference, "exec:") && file != "" && !c.trusted[file] {
+				err = errors.New("exec: is only run from the configuration of the user or the file given with --config or SCAFFOLD_CONFIG")
+			} else {
+				secret, err = resolve(reference, base)
+			}
+			if err != nil {
+				problem := Problem{File: file, Line: line, Message: fmt.Sprintf("'%s' could not be resolved, %s", path, err)}
+				if source, exists := c.sources[path]; exists && file == "" {
+					problem.Message = fmt.Sprintf("'%s' from %s could not be resolved, %s", path, source, err)
+				}
+				problems = append(problems, problem)
+				return
+			}
+			resolved[base+"\x00"+reference] = secret
+		}
+		token.SetString(secret)
+	})
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// tokens calls f with the path, the provider and the token field of every configured provider with a token
+func (c *Config) tokens(f func(path string, provider interface{}, token reflect.Value)) {
+	for _, section := range []struct {
+		key   string
+		value interface{}
+	}{{"ci", c.CI}, {"vcs", c.VCS}} {
+		elem := reflect.ValueOf(section.value).Elem()
+		for i := 0; i < elem.NumField(); i++ {
+			provider := elem.Field(i)
+			if provider.Kind() != reflect.Ptr || provider.IsNil() {
+				continue
+			}
+			token := provider.Elem().FieldByName("Token")
+			if !token.IsValid() || token.Kind() != reflect.String {
+				continue
+			}
+			f(section.key+"."+strings.Split(elem.Type().Field(i).Tag.Get("yaml"), ",")[0]+".token", provider.Interface(), token)
+		}
+	}
+}
+
+func isReference(value string) bool {
+	return strings.HasPrefix(value, "file:") || strings.HasPrefix(value, "env:") || strings.HasPrefix(value, "exec:")
+}
+
+func resolve(reference, base string) (string, error) {
+	var secret string
+	switch {
+	case strings.HasPrefix(reference, "file:"):
+		path := strings.TrimPrefix(reference, "file:")
+		if path == "~" || strings.HasPrefix(path, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		} else if !filepath.IsAbs(path) {
+			path = filepath.Join(base, path)
+		}
+		content, err := ioutil.ReadFile(path)
+		if e, ok := err.(*os.PathError); ok {
+			return "", fmt.Errorf("cannot read '%s': %s", path, e.Err)
+		} else if err != nil {
+			return "", err
+		}
+		secret = string(content)
+	case strings.HasPrefix(reference, "env:"):
+		name := strings.TrimPrefix(reference, "env:")
+		if secret = os.Getenv(name); secret == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+	case strings.HasPrefix(reference, "exec:"):
+		command := strings.TrimPrefix(reference, "exec:")
+		cmd := exec.Command("/bin/sh", "-c", command)
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+		output, err := cmd.Output()
+		if err != nil {
+			if message := strings.TrimSpace(stderr.String()); message != "" {
+				return "", fmt.Errorf("'%s' failed: %s", command, message)
+			}
+			return "", fmt.Errorf("'%s' failed: %s", command, err)
+		}
+		secret = string(output)
+	}
+	if secret = strings.TrimSpace(secret); secret == "" {
+		return "", fmt.Errorf("'%s' is empty", reference)
+	}
+	return secret, nil
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"bytes"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_Token_References(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	home := os.Getenv("HOME")
+	_ = os.Setenv("HOME", name)
+	defer func() { _ = os.Setenv("HOME", home) }()
+	_ = os.Setenv("OTHER_TOKEN", "gitlab-token")
+	defer func() { _ = os.Unsetenv("OTHER_TOKEN") }()
+	_ = os.Mkdir(filepath.Join(name, ".tokens"), 0700)
+	_ = ioutil.WriteFile(filepath.Join(name, ".tokens", "gh"), []byte("github-token\n"), 0600)
+	_ = ioutil.WriteFile(filepath.Join(name, "buildkite"), []byte("buildkite-token"), 0600)
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(`
+vcs:
+  github:
+    token: file:~/.tokens/gh
+ci:
+  buildkite:
+    token: file:buildkite
+  gitlab:
+    token: env:OTHER_TOKEN
+  default: buildkite
+`), 0777)
+
+	cfg, err := Load(name, Selection{}, report.Nop)
+	assert.NoError(t, err)
+	assert.Equal(t, "file:~/.tokens/gh", cfg.VCS.Github.Token)
+
+	assert.NoError(t, cfg.ResolveSecrets(cfg.Providers()))
+	assert.Equal(t, "github-token", cfg.VCS.Github.Token)
+	assert.Equal(t, "buildkite-token", cfg.CI.Buildkite.Token)
+	assert.Equal(t, "gitlab-token", cfg.CI.Gitlab.Token)
+}
+
+func TestLoad_Token_Exec_Reference(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = ioutil.WriteFile(filepath.Join(name, "tokens.yaml"), []byte(`
+vcs:
+  gitlab:
+    group: group
+    token: "exec:echo gitlab-token"
+ci:
+  gitlab:
+    token: "exec:echo gitlab-token"
+`), 0777)
+
+	cfg, err := Load(name, Selection{Config: "tokens.yaml"}, report.Nop)
+
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.ValidateConfig())
+	assert.Equal(t, "gitlab-token", cfg.VCS.Gitlab.Token)
+	assert.Equal(t, "gitlab-token", cfg.CI.Gitlab.Token)
+	out := &bytes.Buffer{}
+	assert.NoError(t, cfg.Show(out))
+	assert.NotContains(t, out.String(), "token: gitlab-token")
+	assert.Contains(t, out.String(), "    token: exec:echo gitlab-token\n")
+}
+
+func TestLoad_Token_Reference_Errors(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(`
+vcs:
+  github:
+    token: file:missing
+ci:
+  buildkite:
+    token: "exec:echo denied >&2; exit 1"
+  gitlab:
+    token: env:UNSET_TOKEN
+  default: buildkite
+`), 0777)
+
+	cfg, err := Load(name, Selection{Config: file}, report.Nop)
+	assert.NoError(t, err)
+
+	err = cfg.ResolveSecrets(cfg.Providers())
+	assert.EqualError(t, err, file+":7: 'ci.buildkite.token' could not be resolved, 'echo denied >&2; exit 1' failed: denied\n"+
+		file+":9: 'ci.gitlab.token' could not be resolved, environment variable UNSET_TOKEN is not set\n"+
+		file+":4: 'vcs.github.token' could not be resolved, cannot read '"+filepath.Join(name, "missing")+"': no such file or directory")
+}
+
+func TestLoad_Token_Reference_From_Environment(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = os.Setenv("BUILDKITE_TOKEN", "exec:true")
+	defer func() { _ = os.Unsetenv("BUILDKITE_TOKEN") }()
+
+	cfg, err := Load(name, Selection{}, report.Nop)
+	assert.NoError(t, err)
+
+	err = cfg.ResolveSecrets(cfg.Providers())
+	assert.EqualError(t, err, "'ci.buildkite.token' from env BUILDKITE_TOKEN could not be resolved, 'exec:true' is empty")
+}
+
+func TestLoad_Token_Exec_Reference_Not_Trusted(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	file := filepath.Join(name, ".scaffold.yaml")
+	marker := filepath.Join(name, "marker")
+	_ = ioutil.WriteFile(file, []byte(`
+vcs:
+  github:
+    token: "exec:touch `+marker+`; echo token"
+ci:
+  buildkite:
+    token: abc
+`), 0777)
+
+	cfg, err := Load(name, Selection{}, report.Nop)
+	assert.NoError(t, err)
+
+	err = cfg.ValidateConfig()
+	assert.EqualError(t, err, file+":4: 'vcs.github.token' could not be resolved, exec: is only run from the configuration of the user or the file given with --config or SCAFFOLD_CONFIG")
+	_, err = os.Stat(marker)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLoad_Token_Only_Resolved_For_Selected_Providers(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	file := filepath.Join(name, "tokens.yaml")
+	marker := filepath.Join(name, "marker")
+	_ = ioutil.WriteFile(file, []byte(`
+vcs:
+  github:
+    token: "exec:touch `+marker+`; echo token"
+  gitlab:
+    group: group
+    token: abc
+  default: gitlab
+ci:
+  buildkite:
+    token: abc
+`), 0777)
+
+	cfg, err := Load(name, Selection{Config: file}, report.Nop)
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.ValidateConfig())
+
+	_, err = os.Stat(marker)
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, "exec:touch "+marker+"; echo token", cfg.VCS.Github.Token)
+}
+
+func TestCheck_Does_Not_Resolve_Tokens(t *testing.T) {
+	name, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(name) }()
+	marker := filepath.Join(name, "marker")
+	_ = ioutil.WriteFile(filepath.Join(name, ".scaffold.yaml"), []byte(`
+vcs:
+  github:
+    token: "exec:touch `+marker+`; echo token"
+ci:
+  buildkite:
+    token: abc
+`), 0777)
+
+	problems, err := Check(name, Selection{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+	_, err = os.Stat(marker)
+	assert.True(t, os.IsNotExist(err))
+}
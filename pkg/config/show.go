@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"strings"
+)
+
+const redacted = "********"
+
+func (c *Config) Show(out io.Writer) error {
+	content, err := c.marshal()
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(out, "%s%s", c.header(), content)
+	return nil
+}
+
+// Explain is like Show with every value followed by the file and line, environment variable or flag it was set by,
+// or default when it was not set
+func (c *Config) Explain(out io.Writer) error {
+	content, err := c.marshal()
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	paths := keyPaths(content)
+	_, _ = fmt.Fprint(out, c.header())
+	for i, line := range lines {
+		if paths[i] != "" && hasValue(line) {
+			source, exists := c.sources[paths[i]]
+			if !exists {
+				source = "default"
+			}
+			line += "  # " + source
+		}
+		_, _ = fmt.Fprintln(out, line)
+	}
+	return nil
+}
+
+func (c *Config) header() string {
+	vcsName, ciName := providerName(c.CurrentVCS), providerName(c.CurrentCI)
+	if _, err := selectProvider("VCS", "vcs", c.VCS, c.VCS.Default); err != nil {
+		vcsName += ", " + err.Error()
+	}
+	if _, err := selectProvider("CI", "ci", c.CI, c.CI.Default); err != nil {
+		ciName += ", " + err.Error()
+	}
+	return fmt.Sprintf("# VCS: %s\n# CI: %s\n", vcsName, ciName)
+}
+
+// marshal returns the effective configuration as yaml, with the tokens redacted
+func (c *Config) marshal() ([]byte, error) {
+	effective := *c
+	effective.Profiles = nil
+	content, err := yaml.Marshal(&effective)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(redact(doc, "", c.references))
+}
+
+// redact hides the tokens in doc, the tokens resolved from a reference show the reference instead
+func redact(doc yaml.MapSlice, prefix string, references map[string]string) yaml.MapSlice {
+	for i, item := range doc {
+		path := fmt.Sprintf("%s%v", prefix, item.Key)
+		switch value := item.Value.(type) {
+		case yaml.MapSlice:
+			doc[i].Value = redact(value, path+".", references)
+		case string:
+			if reference, exists := references[path]; exists {
+				doc[i].Value = reference
+			} else if item.Key == "token" && value != "" {
+				doc[i].Value = redacted
+			}
+		}
+	}
+	return doc
+}
+
+func providerName(provider interface{ Name() string }) string {
+	if provider == nil {
+		return "none"
+	}
+	return provider.Name()
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// sources tells where the values of the configuration were set, keyed by the dotted yaml path of the value
+type sources map[string]string
+
+// location returns the file and line path was set at, or an empty file when it was not set in a file
+func (s sources) location(path string) (string, int) {
+	source, exists := s[path]
+	if !exists || strings.HasPrefix(source, "env ") || strings.HasPrefix(source, "flag ") {
+		return "", 0
+	}
+	return locate(source)
+}
+
+func (s sources) set(path, source string) {
+	if s != nil {
+		s[path] = source
+	}
+}
+
+// clear forgets the sources of the values at and below path
+func (s sources) clear(path string) {
+	for p := range s {
+		if p == path || strings.HasPrefix(p, path+".") {
+			delete(s, p)
+		}
+	}
+}
+
+// inherit replaces the sources of the values at and below to with the ones at and below from
+func (s sources) inherit(to, from string) {
+	if s == nil {
+		return
+	}
+	s.clear(to)
+	for path, source := range s {
+		if path == from || strings.HasPrefix(path, from+".") {
+			s[to+strings.TrimPrefix(path, from)] = source
+		}
+	}
+}
+
+func parent(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// environment records the values of v and the structs it points to that env.Parse takes from the environment
+func (s sources) environment(prefix string, v reflect.Value) {
+	if s == nil {
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		value := v.Field(i)
+		if value.Kind() == reflect.Ptr && !value.IsNil() && value.Elem().Kind() == reflect.Struct {
+			s.environment(prefix+name+".", value.Elem())
+		} else if key := field.Tag.Get("env"); key != "" && os.Getenv(key) != "" {
+			s[prefix+name] = "env " + key
+		}
+	}
+}
+
+// leaves returns the dotted paths of the values in doc, a key without a value is left to the files further up
+func leaves(prefix string, doc yaml.MapSlice) []string {
+	var paths []string
+	for _, item := range doc {
+		path := prefix + fmt.Sprint(item.Key)
+		switch value := item.Value.(type) {
+		case yaml.MapSlice:
+			paths = append(paths, leaves(path+".", value)...)
+		default:
+			if value != nil {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// keyPaths returns the dotted path of the key on every line of a block style yaml document, empty for other lines
+func keyPaths(content []byte) []string {
+	type level struct {
+		indent int
+		key    string
+	}
+	var stack []level
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	paths := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		colon := strings.Index(trimmed, ":")
+		if colon <= 0 || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "-") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, level{indent: indent, key: strings.Trim(trimmed[:colon], `"'`)})
+		keys := make([]string, len(stack))
+		for j, l := range stack {
+			keys[j] = l.key
+		}
+		paths[i] = strings.Join(keys, ".")
+	}
+	return paths
+}
+
+// keyLines returns the line of every key in content by its dotted path
+func keyLines(content []byte) map[string]int {
+	lines := make(map[string]int)
+	for i, path := range keyPaths(content) {
+		if _, exists := lines[path]; path != "" && !exists {
+			lines[path] = i + 1
+		}
+	}
+	return lines
+}
+
+// lineOf returns the line of path, a value in a flow style mapping is on the line of the closest key in block style
+func lineOf(lines map[string]int, path string) int {
+	for key := path; key != ""; key = parent(key) {
+		if line, exists := lines[key]; exists {
+			return line
+		}
+	}
+	return 0
+}
+
+// hasValue tells if the key on line has a value of its own instead of starting a nested mapping
+func hasValue(line string) bool {
+	value := strings.TrimSpace(line[strings.Index(line, ":")+1:])
+	return value != "" && !strings.HasPrefix(value, "#")
+}
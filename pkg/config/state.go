@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/config/ci"
+	"github.com/buildtool/scaffold/pkg/file"
+	"github.com/buildtool/scaffold/pkg/templating"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/yaml.v2"
+	"os"
+	"reflect"
+)
+
+const stateFile = ".scaffold-state.yaml"
+
+// state is what a project was scaffolded with and the content that was generated, the base for later updates
+type state struct {
+	Stack string                  `yaml:"stack"`
+	CI    string                  `yaml:"ci,omitempty"`
+	Data  templating.TemplateData `yaml:"data"`
+	Files map[string]string       `yaml:"files"`
+}
+
+// pipelines are the kinds of CI by the name they are recorded with in the state, their files are rendered without any configuration
+var pipelines = map[string]func() ci.CI{
+	"buildkite": func() ci.CI { return &ci.Buildkite{} },
+	"gitlab":    func() ci.CI { return &ci.Gitlab{} },
+	"none":      func() ci.CI { return &ci.None{} },
+}
+
+// pipelineName returns the name provider is recorded with in the state, empty when it is not one of pipelines
+func pipelineName(provider ci.CI) string {
+	for name, kind := range pipelines {
+		if reflect.TypeOf(kind()) == reflect.TypeOf(provider) {
+			return name
+		}
+	}
+	return ""
+}
+
+func newState(fs billy.Filesystem, stack, ci string, data templating.TemplateData, files []string) (*state, error) {
+	s := &state{Stack: stack, CI: ci, Data: data, Files: make(map[string]string)}
+	for _, name := range files {
+		content, err := file.Read(fs, name)
+		if err != nil {
+			return nil, err
+		}
+		s.Files[name] = content
+	}
+	return s, nil
+}
+
+func loadState(fs billy.Filesystem) (*state, error) {
+	content, err := file.Read(fs, stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("'%s' does not exist, the project was not scaffolded or was scaffolded by an older version", stateFile)
+		}
+		return nil, err
+	}
+	s := &state{}
+	if err := yaml.UnmarshalStrict([]byte(content), s); err != nil {
+		return nil, err
+	}
+	if s.Files == nil {
+		s.Files = make(map[string]string)
+	}
+	return s, nil
+}
+
+func (s *state) save(fs billy.Filesystem) error {
+	content, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return file.Write(fs, stateFile, string(content))
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/config/ci"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/file"
+	"github.com/buildtool/scaffold/pkg/merge"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/buildtool/scaffold/pkg/stack"
+	"github.com/buildtool/scaffold/pkg/templating"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+	"os"
+	"path/filepath"
+)
+
+// Update re-renders the templates of the project in dir with the stack, CI and data it was scaffolded with and merges the changes since
+// then into the current files. The configured CI is used for projects that were scaffolded before the CI was recorded.
+// With diff the changes are printed instead of written.
+func (c *Config) Update(dir string, diff bool, r report.Reporter) int {
+	fs := osfs.New(dir)
+	state, err := loadState(fs)
+	if err != nil {
+		return updateFailed(r, failure.Config, err, "run 'scaffold update' in the root of a project created by 'scaffold new' or 'scaffold adopt'")
+	}
+	currentStack, exists := stack.Stacks[state.Stack]
+	if !exists {
+		return updateFailed(r, failure.Config, fmt.Errorf("stack '%s' in '%s' does not exist", state.Stack, stateFile), "")
+	}
+	currentCI := c.CurrentCI
+	if state.CI == "" {
+		if err := c.requireCI(); err != nil {
+			return updateFailed(r, failure.Config, err, "")
+		}
+		currentCI = c.CurrentCI
+	} else {
+		kind, exists := pipelines[state.CI]
+		if !exists {
+			return updateFailed(r, failure.Config, fmt.Errorf("CI '%s' in '%s' does not exist", state.CI, stateFile), "")
+		}
+		currentCI = kind()
+	}
+	// Nothing is created at the providers, only the files are rendered
+	currentCI.DryRun(report.Nop)
+	rendered := memfs.New()
+	if err := render(rendered, currentCI, currentStack, state.Data); err != nil {
+		return updateFailed(r, failure.Filesystem, err, "")
+	}
+	names, err := file.List(rendered, "")
+	if err != nil {
+		return updateFailed(r, failure.Filesystem, err, "")
+	}
+
+	r.Started("Updating '%s' using stack '%s'", state.Data.ProjectName, state.Stack)
+	var conflicts []string
+	for _, name := range names {
+		theirs, err := file.Read(rendered, name)
+		if err != nil {
+			return updateFailed(r, failure.Filesystem, err, "")
+		}
+		base, generated := state.Files[name]
+		ours, err := file.Read(fs, name)
+		missing := os.IsNotExist(err)
+		if err != nil && !missing {
+			return updateFailed(r, failure.Filesystem, err, "")
+		}
+
+		var result string
+		switch {
+		case generated && theirs == base:
+			continue
+		case generated && missing:
+			r.Warning("Skipping deleted '%s'", name)
+			continue
+		case !missing && ours == theirs:
+			state.Files[name] = theirs
+			continue
+		case !generated && !missing:
+			r.Warning("Skipping '%s', it was not created by scaffold", name)
+			continue
+		case missing:
+			r.Succeeded("Adding '%s'", name)
+			result = theirs
+		case ours == base:
+			r.Succeeded("Updating '%s'", name)
+			result = theirs
+		default:
+			var clean bool
+			if result, clean = merge.ThreeWay(base, ours, theirs); clean {
+				r.Succeeded("Merging '%s'", name)
+			} else {
+				r.Failed("Conflict in '%s'", name)
+				conflicts = append(conflicts, name)
+			}
+		}
+		state.Files[name] = theirs
+		if diff {
+			_, _ = fmt.Fprint(r.Output(), merge.Unified(ours, result, filepath.Join("current", name), filepath.Join("updated", name)))
+			continue
+		}
+		if err := util.WriteFile(fs, name, []byte(result), 0666); err != nil {
+			return updateFailed(r, failure.Filesystem, err, "")
+		}
+	}
+	if diff {
+		return 0
+	}
+	if err := state.save(fs); err != nil {
+		return updateFailed(r, failure.Filesystem, err, "")
+	}
+	if len(conflicts) > 0 {
+		return updateFailed(r, failure.Conflict, fmt.Errorf("%d file(s) could not be merged", len(conflicts)), "resolve the conflict markers in the files listed above and commit the result")
+	}
+	return 0
+}
+
+// requireCI checks that a CI is configured and resolves its secrets, only projects that did not record their CI need one
+func (c *Config) requireCI() error {
+	if !c.local && c.CI != nil {
+		if _, err := selectProvider("CI", "ci", c.CI, c.CI.Default); err != nil {
+			return err
+		}
+	}
+	if c.CurrentCI == nil {
+		return errors.New("no CI configured")
+	}
+	return c.ResolveSecrets(nil, []ci.CI{c.CurrentCI})
+}
+
+func updateFailed(r report.Reporter, kind failure.Kind, err error, hint string) int {
+	e := failure.Wrap(kind, "update", err)
+	if e.Hint == "" {
+		e.Hint = hint
+	}
+	r.Error(e)
+	return failure.ExitCode(e)
+}
+
+// render writes the files the steps of scaffold would write to fs
+func render(fs billy.Filesystem, ci ci.CI, stack stack.Stack, data templating.TemplateData) error {
+	if _, err := ci.Scaffold(context.Background(), fs, data); err != nil {
+		return err
+	}
+	if err := createDotfiles(fs); err != nil {
+		return err
+	}
+	if err := createReadme(fs, data); err != nil {
+		return err
+	}
+	if err := createDeployment(fs, data); err != nil {
+		return err
+	}
+	return stack.Scaffold(fs, data)
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"github.com/buildtool/scaffold/pkg/config/ci"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/buildtool/scaffold/pkg/stack"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestScaffold_Saves_State(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{httpUrl: "https://example.com/org/project.git"}
+	cfg.CurrentCI = &mockCi{}
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.Go{}, report.NewText(&bytes.Buffer{}, true))
+
+	assert.Equal(t, 0, exitCode)
+	state, err := loadState(osfs.New(filepath.Join(name, "project")))
+	assert.NoError(t, err)
+	assert.Equal(t, "go", state.Stack)
+	assert.Equal(t, "project", state.Data.ProjectName)
+	assert.Equal(t, []string{".dockerignore", ".editorconfig", ".gitignore", "README.md", "go.mod", filepath.Join("k8s", "deploy.yaml")}, keys(state.Files))
+	editorconfig, _ := ioutil.ReadFile(filepath.Join(name, "project", ".editorconfig"))
+	assert.Equal(t, string(editorconfig), state.Files[".editorconfig"])
+}
+
+func TestUpdate_No_State(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	_ = os.MkdirAll(name, 0777)
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{}
+	cfg.CurrentCI = &mockCi{}
+	out := &bytes.Buffer{}
+
+	exitCode := cfg.Update(name, false, report.NewText(out, true))
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[31m'.scaffold-state.yaml' does not exist, the project was not scaffolded or was scaffolded by an older version\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mHint: run 'scaffold update' in the root of a project created by 'scaffold new' or 'scaffold adopt'\x1b[39m\x1b[0m\n", out.String())
+}
+
+func TestUpdate_Up_To_Date(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	dir := scaffolded(t)
+	before, _ := ioutil.ReadFile(filepath.Join(dir, stateFile))
+	out := &bytes.Buffer{}
+
+	exitCode := updateConfig().Update(dir, false, report.NewText(out, true))
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[94mUpdating \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", out.String())
+	after, _ := ioutil.ReadFile(filepath.Join(dir, stateFile))
+	assert.Equal(t, string(before), string(after))
+}
+
+func TestUpdate_Unchanged_File_Is_Replaced(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	dir := scaffolded(t)
+	olderTemplate(t, dir, "replicas: 2", "replicas: 1")
+	out := &bytes.Buffer{}
+
+	exitCode := updateConfig().Update(dir, false, report.NewText(out, true))
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "\x1b[32mUpdating \x1b[39m\x1b[97m\x1b[1m'k8s/deploy.yaml'\x1b[0m\x1b[97m\x1b[39m\n")
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "k8s", "deploy.yaml"))
+	assert.Contains(t, string(content), "replicas: 2")
+	state, _ := loadState(osfs.New(dir))
+	assert.Equal(t, string(content), state.Files[filepath.Join("k8s", "deploy.yaml")])
+}
+
+func TestUpdate_Merges_Local_Changes(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	dir := scaffolded(t)
+	olderTemplate(t, dir, "replicas: 2", "replicas: 1")
+	changeFile(t, dir, "imagePullPolicy: Always", "imagePullPolicy: IfNotPresent")
+	out := &bytes.Buffer{}
+
+	exitCode := updateConfig().Update(dir, false, report.NewText(out, true))
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "\x1b[32mMerging \x1b[39m\x1b[97m\x1b[1m'k8s/deploy.yaml'\x1b[0m\x1b[97m\x1b[39m\n")
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "k8s", "deploy.yaml"))
+	assert.Contains(t, string(content), "replicas: 2")
+	assert.Contains(t, string(content), "imagePullPolicy: IfNotPresent")
+}
+
+func TestUpdate_Conflict(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	dir := scaffolded(t)
+	olderTemplate(t, dir, "replicas: 2", "replicas: 1")
+	changeFile(t, dir, "replicas: 1", "replicas: 5")
+	out := &bytes.Buffer{}
+
+	exitCode := updateConfig().Update(dir, false, report.NewText(out, true))
+
+	assert.Equal(t, 5, exitCode)
+	assert.Contains(t, out.String(), "\x1b[31mConflict in \x1b[39m\x1b[97m\x1b[1m'k8s/deploy.yaml'\x1b[0m\x1b[97m\x1b[39m\n")
+	assert.Contains(t, out.String(), "\x1b[31m1 file(s) could not be merged\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mHint: resolve the conflict markers in the files listed above and commit the result\x1b[39m")
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "k8s", "deploy.yaml"))
+	assert.Contains(t, string(content), "<<<<<<< current\n replicas: 5\n=======\n replicas: 2\n>>>>>>> template\n")
+}
+
+func TestUpdate_Diff(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	dir := scaffolded(t)
+	olderTemplate(t, dir, "replicas: 2", "replicas: 1")
+	before, _ := ioutil.ReadFile(filepath.Join(dir, stateFile))
+	out := &bytes.Buffer{}
+
+	exitCode := updateConfig().Update(dir, true, report.NewText(out, true))
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "--- current/k8s/deploy.yaml\n+++ updated/k8s/deploy.yaml\n")
+	assert.Contains(t, out.String(), "\n- replicas: 1\n+ replicas: 2\n")
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "k8s", "deploy.yaml"))
+	assert.Contains(t, string(content), "replicas: 1")
+	after, _ := ioutil.ReadFile(filepath.Join(dir, stateFile))
+	assert.Equal(t, string(before), string(after))
+}
+
+func TestUpdate_Skips_Deleted_And_Foreign_Files(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	dir := scaffolded(t)
+	olderTemplate(t, dir, "replicas: 2", "replicas: 1")
+	_ = os.Remove(filepath.Join(dir, "k8s", "deploy.yaml"))
+	state, _ := loadState(osfs.New(dir))
+	delete(state.Files, "README.md")
+	_ = state.save(osfs.New(dir))
+	_ = ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("own readme\n"), 0666)
+	out := &bytes.Buffer{}
+
+	exitCode := updateConfig().Update(dir, false, report.NewText(out, true))
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "\x1b[33mSkipping deleted \x1b[39m\x1b[97m\x1b[1m'k8s/deploy.yaml'\x1b[0m\x1b[97m\x1b[39m\n")
+	assert.Contains(t, out.String(), "\x1b[33mSkipping \x1b[39m\x1b[97m\x1b[1m'README.md'\x1b[0m\x1b[97m\x1b[39m\x1b[33m, it was not created by scaffold\x1b[39m\n")
+	_, err := os.Stat(filepath.Join(dir, "k8s", "deploy.yaml"))
+	assert.True(t, os.IsNotExist(err))
+	readme, _ := ioutil.ReadFile(filepath.Join(dir, "README.md"))
+	assert.Equal(t, "own readme\n", string(readme))
+}
+
+func TestUpdate_Adds_New_Files(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	dir := scaffolded(t)
+	_ = os.Remove(filepath.Join(dir, ".gitignore"))
+	state, _ := loadState(osfs.New(dir))
+	delete(state.Files, ".gitignore")
+	_ = state.save(osfs.New(dir))
+	out := &bytes.Buffer{}
+
+	exitCode := updateConfig().Update(dir, false, report.NewText(out, true))
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "\x1b[32mAdding \x1b[39m\x1b[97m\x1b[1m'.gitignore'\x1b[0m\x1b[97m\x1b[39m\n")
+	assert.FileExists(t, filepath.Join(dir, ".gitignore"))
+}
+
+func TestScaffold_Saves_CI_In_State(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	os.Clearenv()
+	cfg := updateConfig()
+	cfg.CurrentVCS = &mockVcs{httpUrl: "https://example.com/org/project.git"}
+	cfg.CurrentCI = &ci.Gitlab{}
+	cfg.CurrentCI.DryRun(report.Nop)
+
+	exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(&bytes.Buffer{}, true))
+
+	assert.Equal(t, 0, exitCode)
+	state, err := loadState(osfs.New(filepath.Join(name, "project")))
+	assert.NoError(t, err)
+	assert.Equal(t, "gitlab", state.CI)
+}
+
+func TestUpdate_Renders_With_Recorded_CI(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	dir := scaffolded(t)
+	fs := osfs.New(dir)
+	state, _ := loadState(fs)
+	state.CI = "gitlab"
+	assert.NoError(t, state.save(fs))
+	out := &bytes.Buffer{}
+
+	exitCode := updateConfig().Update(dir, false, report.NewText(out, true))
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "Adding \x1b[39m\x1b[97m\x1b[1m'.gitlab-ci.yml'")
+	_, err := os.Stat(filepath.Join(dir, ".gitlab-ci.yml"))
+	assert.NoError(t, err)
+}
+
+func TestUpdate_Unknown_CI(t *testing.T) {
+	defer func() { _ = os.RemoveAll(name) }()
+	dir := scaffolded(t)
+	fs := osfs.New(dir)
+	state, _ := loadState(fs)
+	state.CI = "travis"
+	assert.NoError(t, state.save(fs))
+	out := &bytes.Buffer{}
+
+	exitCode := updateConfig().Update(dir, false, report.NewText(out, true))
+
+	assert.Equal(t, 3, exitCode)
+	assert.Contains(t, out.String(), "CI 'travis' in '.scaffold-state.yaml' does not exist")
+}
+
+func updateConfig() *Config {
+	cfg := InitEmptyConfig()
+	cfg.CurrentVCS = &mockVcs{}
+	cfg.CurrentCI = &mockCi{}
+	return cfg
+}
+
+func scaffolded(t *testing.T) string {
+	os.Clearenv()
+	cfg := updateConfig()
+	cfg.CurrentVCS = &mockVcs{httpUrl: "https://example.com/org/project.git"}
+	if exitCode := cfg.Scaffold(context.Background(), name, "project", &stack.None{}, report.NewText(&bytes.Buffer{}, true)); exitCode != 0 {
+		t.Fatalf("scaffold failed with %d", exitCode)
+	}
+	return filepath.Join(name, "project")
+}
+
+// olderTemplate makes it look like the deployment was generated from a template with old instead of new
+func olderTemplate(t *testing.T, dir, new, old string) {
+	fs := osfs.New(dir)
+	state, err := loadState(fs)
+	assert.NoError(t, err)
+	name := filepath.Join("k8s", "deploy.yaml")
+	state.Files[name] = strings.Replace(state.Files[name], new, old, 1)
+	assert.NoError(t, state.save(fs))
+	changeFile(t, dir, new, old)
+}
+
+func changeFile(t *testing.T, dir, from, to string) {
+	path := filepath.Join(dir, "k8s", "deploy.yaml")
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(strings.Replace(string(content), from, to, 1)), 0666))
+}
+
+func keys(m map[string]string) []string {
+	var result []string
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,134 @@
+package vcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/dryrun"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/buildtool/scaffold/pkg/wrappers"
+	"github.com/google/go-github/v28/github"
+	"github.com/xanzy/go-gitlab"
+	"net/http"
+	"net/url"
+)
+
+const (
+	githubApi = "https://api.github.com/"
+	gitlabApi = "https://gitlab.com/api/v4/"
+)
+
+type dryRunRepositories struct {
+	reporter report.Reporter
+}
+
+func (r *dryRunRepositories) Create(ctx context.Context, org string, repo *github.Repository) (*github.Repository, *github.Response, error) {
+	owner, path := "current-user", "user/repos"
+	if org != "" {
+		owner, path = org, fmt.Sprintf("orgs/%s/repos", org)
+	}
+	dryrun.Request(r.reporter, http.MethodPost, githubApi+path, repo)
+	return &github.Repository{
+		Name:     repo.Name,
+		Owner:    &github.User{Login: wrappers.String(owner)},
+		SSHURL:   wrappers.String(fmt.Sprintf("git@github.com:%s/%s.git", owner, *repo.Name)),
+		CloneURL: wrappers.String(fmt.Sprintf("https://github.com/%s/%s.git", owner, *repo.Name)),
+	}, githubResponse(http.StatusCreated), nil
+}
+
+func (r *dryRunRepositories) UpdateBranchProtection(ctx context.Context, owner, repo, branch string, preq *github.ProtectionRequest) (*github.Protection, *github.Response, error) {
+	dryrun.Request(r.reporter, http.MethodPut, fmt.Sprintf("%srepos/%s/%s/branches/%s/protection", githubApi, owner, repo, branch), preq)
+	return &github.Protection{}, githubResponse(http.StatusOK), nil
+}
+
+func (r *dryRunRepositories) CreateHook(ctx context.Context, owner, repo string, hook *github.Hook) (*github.Hook, *github.Response, error) {
+	dryrun.Request(r.reporter, http.MethodPost, fmt.Sprintf("%srepos/%s/%s/hooks", githubApi, owner, repo), hook)
+	created := *hook
+	created.ID = github.Int64(1)
+	return &created, githubResponse(http.StatusCreated), nil
+}
+
+func (r *dryRunRepositories) Delete(ctx context.Context, owner, repo string) (*github.Response, error) {
+	dryrun.Request(r.reporter, http.MethodDelete, fmt.Sprintf("%srepos/%s/%s", githubApi, owner, repo), nil)
+	return githubResponse(http.StatusNoContent), nil
+}
+
+func (r *dryRunRepositories) DeleteHook(ctx context.Context, owner, repo string, id int64) (*github.Response, error) {
+	dryrun.Request(r.reporter, http.MethodDelete, fmt.Sprintf("%srepos/%s/%s/hooks/%d", githubApi, owner, repo, id), nil)
+	return githubResponse(http.StatusNoContent), nil
+}
+
+var _ RepositoriesService = &dryRunRepositories{}
+
+type dryRunPullRequests struct {
+	reporter report.Reporter
+}
+
+func (p *dryRunPullRequests) Create(ctx context.Context, owner string, repo string, pull *github.NewPullRequest) (*github.PullRequest, *github.Response, error) {
+	dryrun.Request(p.reporter, http.MethodPost, fmt.Sprintf("%srepos/%s/%s/pulls", githubApi, owner, repo), pull)
+	return &github.PullRequest{HTMLURL: wrappers.String(fmt.Sprintf("https://github.com/%s/%s/pull/1", owner, repo))}, githubResponse(http.StatusCreated), nil
+}
+
+var _ githubPullRequestsService = &dryRunPullRequests{}
+
+func githubResponse(status int) *github.Response {
+	return &github.Response{Response: &http.Response{StatusCode: status, Status: fmt.Sprintf("%d %s", status, http.StatusText(status))}}
+}
+
+type dryRunProjects struct {
+	reporter report.Reporter
+	group    string
+}
+
+func (p *dryRunProjects) GetProject(pid interface{}, opt *gitlab.GetProjectOptions, options ...gitlab.OptionFunc) (*gitlab.Project, *gitlab.Response, error) {
+	return nil, gitlabResponse(http.StatusNotFound), errors.New("404 Project Not Found")
+}
+
+func (p *dryRunProjects) CreateProject(opt *gitlab.CreateProjectOptions, options ...gitlab.OptionFunc) (*gitlab.Project, *gitlab.Response, error) {
+	dryrun.Request(p.reporter, http.MethodPost, gitlabApi+"projects", opt)
+	return &gitlab.Project{
+		Name:          *opt.Name,
+		SSHURLToRepo:  fmt.Sprintf("git@gitlab.com:%s/%s.git", p.group, *opt.Name),
+		HTTPURLToRepo: fmt.Sprintf("https://gitlab.com/%s/%s.git", p.group, *opt.Name),
+	}, gitlabResponse(http.StatusCreated), nil
+}
+
+func (p *dryRunProjects) AddProjectHook(pid interface{}, opt *gitlab.AddProjectHookOptions, options ...gitlab.OptionFunc) (*gitlab.ProjectHook, *gitlab.Response, error) {
+	dryrun.Request(p.reporter, http.MethodPost, fmt.Sprintf("%sprojects/%s/hooks", gitlabApi, url.PathEscape(fmt.Sprint(pid))), opt)
+	return &gitlab.ProjectHook{ID: 1, URL: *opt.URL}, gitlabResponse(http.StatusCreated), nil
+}
+
+func (p *dryRunProjects) DeleteProject(pid interface{}, options ...gitlab.OptionFunc) (*gitlab.Response, error) {
+	dryrun.Request(p.reporter, http.MethodDelete, fmt.Sprintf("%sprojects/%s", gitlabApi, url.PathEscape(fmt.Sprint(pid))), nil)
+	return gitlabResponse(http.StatusAccepted), nil
+}
+
+func (p *dryRunProjects) DeleteProjectHook(pid interface{}, hook int, options ...gitlab.OptionFunc) (*gitlab.Response, error) {
+	dryrun.Request(p.reporter, http.MethodDelete, fmt.Sprintf("%sprojects/%s/hooks/%d", gitlabApi, url.PathEscape(fmt.Sprint(pid)), hook), nil)
+	return gitlabResponse(http.StatusNoContent), nil
+}
+
+var _ projectsService = &dryRunProjects{}
+
+type dryRunMergeRequests struct {
+	reporter report.Reporter
+}
+
+func (m *dryRunMergeRequests) CreateMergeRequest(pid interface{}, opt *gitlab.CreateMergeRequestOptions, options ...gitlab.OptionFunc) (*gitlab.MergeRequest, *gitlab.Response, error) {
+	dryrun.Request(m.reporter, http.MethodPost, fmt.Sprintf("%sprojects/%s/merge_requests", gitlabApi, url.PathEscape(fmt.Sprint(pid))), opt)
+	return &gitlab.MergeRequest{WebURL: fmt.Sprintf("https://gitlab.com/%s/merge_requests/1", pid)}, gitlabResponse(http.StatusCreated), nil
+}
+
+var _ mergeRequestsService = &dryRunMergeRequests{}
+
+type dryRunGroups struct{}
+
+func (g *dryRunGroups) GetGroup(gid interface{}, options ...gitlab.OptionFunc) (*gitlab.Group, *gitlab.Response, error) {
+	return &gitlab.Group{FullPath: fmt.Sprint(gid)}, gitlabResponse(http.StatusOK), nil
+}
+
+var _ groupsService = &dryRunGroups{}
+
+func gitlabResponse(status int) *gitlab.Response {
+	return &gitlab.Response{Response: &http.Response{StatusCode: status}}
+}
@@ -0,0 +1,70 @@
+package vcs
+
+import (
+	"bytes"
+	"context"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGithub_DryRun(t *testing.T) {
+	out := &bytes.Buffer{}
+	vcs := &Github{Organisation: "org"}
+	vcs.DryRun(report.NewText(out, true))
+
+	assert.NoError(t, vcs.Validate(context.Background(), "project"))
+	repository, err := vcs.Scaffold(context.Background(), "project")
+	assert.NoError(t, err)
+	assert.Equal(t, &RepositoryInfo{SSHURL: "git@github.com:org/project.git", HTTPURL: "https://github.com/org/project.git"}, repository)
+	assert.NoError(t, vcs.Webhook(context.Background(), "project", "https://example.org/hook"))
+	assert.NoError(t, vcs.DeleteWebhook(context.Background(), "project"))
+	assert.NoError(t, vcs.DeleteRepository(context.Background(), "project"))
+	url, err := vcs.PullRequest(context.Background(), "project", "scaffold/initial", "Scaffold project", "body")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.com/org/project/pull/1", url)
+
+	assert.Contains(t, out.String(), "POST https://api.github.com/orgs/org/repos")
+	assert.Contains(t, out.String(), "DELETE https://api.github.com/repos/org/project/hooks/1")
+	assert.Contains(t, out.String(), "DELETE https://api.github.com/repos/org/project\x1b")
+	assert.Contains(t, out.String(), "PUT https://api.github.com/repos/org/project/branches/master/protection")
+	assert.Contains(t, out.String(), "POST https://api.github.com/repos/org/project/hooks")
+	assert.Contains(t, out.String(), "\"url\": \"https://example.org/hook\"")
+	assert.Contains(t, out.String(), "POST https://api.github.com/repos/org/project/pulls")
+}
+
+func TestGithub_DryRun_Without_Organisation(t *testing.T) {
+	out := &bytes.Buffer{}
+	vcs := &Github{}
+	vcs.DryRun(report.NewText(out, true))
+
+	repository, err := vcs.Scaffold(context.Background(), "project")
+	assert.NoError(t, err)
+	assert.Equal(t, &RepositoryInfo{SSHURL: "git@github.com:current-user/project.git", HTTPURL: "https://github.com/current-user/project.git"}, repository)
+	assert.Contains(t, out.String(), "POST https://api.github.com/user/repos")
+}
+
+func TestGitlab_DryRun(t *testing.T) {
+	out := &bytes.Buffer{}
+	vcs := &Gitlab{Group: "group", Visibility: "private"}
+	vcs.DryRun(report.NewText(out, true))
+
+	assert.NoError(t, vcs.Validate(context.Background(), "project"))
+	repository, err := vcs.Scaffold(context.Background(), "project")
+	assert.NoError(t, err)
+	assert.Equal(t, &RepositoryInfo{SSHURL: "git@gitlab.com:group/project.git", HTTPURL: "https://gitlab.com/group/project.git"}, repository)
+	assert.NoError(t, vcs.Webhook(context.Background(), "project", "https://example.org/hook"))
+	assert.NoError(t, vcs.DeleteWebhook(context.Background(), "project"))
+	assert.NoError(t, vcs.DeleteRepository(context.Background(), "project"))
+	url, err := vcs.PullRequest(context.Background(), "project", "scaffold/initial", "Scaffold project", "body")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://gitlab.com/group/project/merge_requests/1", url)
+
+	assert.Contains(t, out.String(), "POST https://gitlab.com/api/v4/projects")
+	assert.Contains(t, out.String(), "DELETE https://gitlab.com/api/v4/projects/group%2Fproject/hooks/1")
+	assert.Contains(t, out.String(), "DELETE https://gitlab.com/api/v4/projects/group%2Fproject\x1b")
+	assert.Contains(t, out.String(), "\"visibility\": \"private\"")
+	assert.Contains(t, out.String(), "POST https://gitlab.com/api/v4/projects/group%2Fproject/hooks")
+	assert.Contains(t, out.String(), "\"url\": \"https://example.org/hook\"")
+	assert.Contains(t, out.String(), "POST https://gitlab.com/api/v4/projects/group%2Fproject/merge_requests")
+}
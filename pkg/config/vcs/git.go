@@ -1,14 +1,236 @@
 package vcs
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/failure"
 	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	format "gopkg.in/src-d/go-git.v4/plumbing/format/config"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 	"io"
+	"net/url"
+	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 type Git struct{}
 
-func (g Git) Clone(dir, name, url string, out io.Writer) error {
-	_, err := git.PlainClone(filepath.Join(dir, name), false, &git.CloneOptions{URL: url, Progress: out})
+// Commit is the commit of the generated files, the author is taken from the git configuration when Name or Email is empty
+// The commit is made on a new Branch when set.
+type Commit struct {
+	Message string
+	Name    string
+	Email   string
+	Branch  string
+}
+
+func (g Git) Clone(ctx context.Context, dir, name, url string, out io.Writer) error {
+	return wait(ctx, func() error {
+		_, err := git.PlainCloneContext(ctx, filepath.Join(dir, name), false, &git.CloneOptions{URL: url, Progress: out})
+		return err
+	})
+}
+
+func (g Git) Commit(dir, name string, files []string, commit Commit) error {
+	repo, err := git.PlainOpen(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	tree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	author, err := commit.author(tree.Filesystem.Root())
+	if err != nil {
+		return err
+	}
+	if commit.Branch != "" {
+		if err := branch(repo, tree, plumbing.NewBranchReferenceName(commit.Branch)); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		if _, err := tree.Add(file); err != nil {
+			return err
+		}
+	}
+	_, err = tree.Commit(commit.Message, &git.CommitOptions{Author: author})
 	return err
 }
+
+func branch(repo *git.Repository, tree *git.Worktree, name plumbing.ReferenceName) error {
+	if _, err := repo.Head(); err == plumbing.ErrReferenceNotFound {
+		return repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, name))
+	}
+	return tree.Checkout(&git.CheckoutOptions{Branch: name, Create: true, Keep: true})
+}
+
+func (g Git) Push(ctx context.Context, dir, name string, out io.Writer) error {
+	repo, err := git.PlainOpen(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	refSpec := config.RefSpec(fmt.Sprintf("%s:%s", head.Name(), head.Name()))
+	return wait(ctx, func() error {
+		if err := repo.PushContext(ctx, &git.PushOptions{RefSpecs: []config.RefSpec{refSpec}, Progress: out}); err != nil && err != git.NoErrAlreadyUpToDate {
+			return err
+		}
+		return nil
+	})
+}
+
+// wait runs call and stops waiting for it when ctx is done, go-git does not pass ctx on to every request it makes
+func wait(ctx context.Context, call func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- call()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c Commit) author(root string) (*object.Signature, error) {
+	author := &object.Signature{Name: c.Name, Email: c.Email, When: time.Now()}
+	files := []string{filepath.Join(root, ".git", "config")}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		files = append(files, filepath.Join(xdg, "git", "config"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".config", "git", "config"), filepath.Join(home, ".gitconfig"))
+	}
+	for _, file := range files {
+		if author.Name != "" && author.Email != "" {
+			break
+		}
+		name, email := gitUser(file)
+		if author.Name == "" {
+			author.Name = name
+		}
+		if author.Email == "" {
+			author.Email = email
+		}
+	}
+	if author.Name == "" || author.Email == "" {
+		return nil, &failure.Error{
+			Kind: failure.Config,
+			Hint: "set user.name and user.email with 'git config --global' or use --author-name and --author-email",
+			Err:  errors.New("no author for the commit of the generated files"),
+		}
+	}
+	return author, nil
+}
+
+func gitUser(file string) (string, string) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", ""
+	}
+	defer func() { _ = f.Close() }()
+	cfg := format.New()
+	if err := format.NewDecoder(f).Decode(cfg); err != nil {
+		return "", ""
+	}
+	user := cfg.Section("user")
+	return user.Option("name"), user.Option("email")
+}
+
+// Origin finds the clone dir belongs to and returns its root together with the repository its origin remote points to
+func Origin(dir string) (string, *RepositoryInfo, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return "", nil, fmt.Errorf("'%s' is not inside a git repository", dir)
+	}
+	tree, err := repo.Worktree()
+	if err != nil {
+		return "", nil, err
+	}
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return "", nil, fmt.Errorf("the repository at '%s' has no '%s' remote", tree.Filesystem.Root(), git.DefaultRemoteName)
+	}
+	info, err := repositoryInfo(remote.Config().URLs[0])
+	if err != nil {
+		return "", nil, err
+	}
+	return tree.Filesystem.Root(), info, nil
+}
+
+func repositoryInfo(remote string) (*RepositoryInfo, error) {
+	var host, repoPath, sshHost, httpHost string
+	if strings.Contains(remote, "://") {
+		parsed, err := url.Parse(remote)
+		if err != nil {
+			return nil, err
+		}
+		host, repoPath = parsed.Hostname(), parsed.Path
+		// The port of an ssh remote is only used for ssh, the one of an http remote only for http
+		if port := parsed.Port(); port != "" && strings.HasSuffix(parsed.Scheme, "ssh") {
+			user := "git"
+			if parsed.User != nil {
+				user = parsed.User.Username()
+			}
+			sshHost = fmt.Sprintf("%s@%s:%s", user, host, port)
+		} else if port != "" {
+			httpHost = host + ":" + port
+		}
+	} else if i := strings.Index(remote, ":"); i > 0 {
+		host, repoPath = remote[:i], remote[i+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	}
+	repoPath = strings.Trim(repoPath, "/")
+	if host == "" || repoPath == "" {
+		return nil, fmt.Errorf("unable to parse remote url '%s'", remote)
+	}
+	if !strings.HasSuffix(repoPath, ".git") {
+		repoPath = repoPath + ".git"
+	}
+	info := &RepositoryInfo{
+		SSHURL:  fmt.Sprintf("git@%s:%s", host, repoPath),
+		HTTPURL: fmt.Sprintf("https://%s/%s", host, repoPath),
+	}
+	if sshHost != "" {
+		info.SSHURL = fmt.Sprintf("ssh://%s/%s", sshHost, repoPath)
+	}
+	if httpHost != "" {
+		info.HTTPURL = fmt.Sprintf("https://%s/%s", httpHost, repoPath)
+	}
+	return info, nil
+}
+
+// Path returns the path of the repository at its host, e.g. org/project
+func (r *RepositoryInfo) Path() (string, error) {
+	parsed, err := url.Parse(r.HTTPURL)
+	if err != nil {
+		return "", err
+	}
+	repoPath := strings.TrimSuffix(strings.Trim(parsed.Path, "/"), ".git")
+	if !strings.Contains(repoPath, "/") {
+		return "", fmt.Errorf("repository url '%s' has no owner", r.HTTPURL)
+	}
+	return repoPath, nil
+}
+
+// Name returns the name of the repository, the last element of its path
+func (r *RepositoryInfo) Name() (string, error) {
+	repoPath, err := r.Path()
+	if err != nil {
+		return "", err
+	}
+	return path.Base(repoPath), nil
+}
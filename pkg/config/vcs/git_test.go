@@ -2,13 +2,22 @@ package vcs
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"github.com/buildtool/scaffold/pkg/failure"
 	"github.com/stretchr/testify/assert"
 	git2 "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestGit_Clone(t *testing.T) {
@@ -27,7 +36,221 @@ func TestGit_Clone(t *testing.T) {
 	_, _ = tree.Commit("Test", &git2.CommitOptions{Author: &object.Signature{Email: "test@example.com"}})
 
 	buff := &bytes.Buffer{}
-	err := vcs.Clone(name, "project", fmt.Sprintf("file://%s", dir), buff)
+	err := vcs.Clone(context.Background(), name, "project", fmt.Sprintf("file://%s", dir), buff)
 	assert.NoError(t, err)
 	assert.Contains(t, buff.String(), "Total 2 (delta 0), reused 0 (delta 0)")
 }
+
+func TestGit_Clone_Timeout(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(dir) }()
+	hanging := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hanging
+	}))
+	defer server.Close()
+	defer close(hanging)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := Git{}.Clone(ctx, dir, "project", server.URL+"/org/project.git", &bytes.Buffer{})
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+}
+
+func TestGit_Commit(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(dir) }()
+	repo, _ := git2.PlainInit(filepath.Join(dir, "project"), false)
+	_ = ioutil.WriteFile(filepath.Join(dir, "project", "README.md"), []byte("# project"), 0666)
+	_ = ioutil.WriteFile(filepath.Join(dir, "project", "other"), []byte("other"), 0666)
+
+	err := Git{}.Commit(dir, "project", []string{"README.md"}, Commit{Message: "Initial", Name: "Test", Email: "test@example.com"})
+
+	assert.NoError(t, err)
+	head, _ := repo.Head()
+	commit, _ := repo.CommitObject(head.Hash())
+	assert.Equal(t, "Initial", commit.Message)
+	assert.Equal(t, "Test", commit.Author.Name)
+	assert.Equal(t, "test@example.com", commit.Author.Email)
+	files, _ := commit.Files()
+	var names []string
+	_ = files.ForEach(func(f *object.File) error {
+		names = append(names, f.Name)
+		return nil
+	})
+	assert.Equal(t, []string{"README.md"}, names)
+}
+
+func TestGit_Commit_Author_From_Git_Config(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(dir) }()
+	repo, _ := git2.PlainInit(filepath.Join(dir, "project"), false)
+	f, _ := os.OpenFile(filepath.Join(dir, "project", ".git", "config"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	_, _ = f.WriteString("[user]\n\tname = Config User\n\temail = config@example.com\n")
+	_ = f.Close()
+	_ = ioutil.WriteFile(filepath.Join(dir, "project", "README.md"), []byte("# project"), 0666)
+
+	err := Git{}.Commit(dir, "project", []string{"README.md"}, Commit{Message: "Initial"})
+
+	assert.NoError(t, err)
+	head, _ := repo.Head()
+	commit, _ := repo.CommitObject(head.Hash())
+	assert.Equal(t, "Config User", commit.Author.Name)
+	assert.Equal(t, "config@example.com", commit.Author.Email)
+}
+
+func TestGit_Commit_Without_Author(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(dir) }()
+	home := os.Getenv("HOME")
+	defer func() { _ = os.Setenv("HOME", home) }()
+	_ = os.Setenv("HOME", dir)
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	defer func() { _ = os.Setenv("XDG_CONFIG_HOME", xdg) }()
+	_ = os.Unsetenv("XDG_CONFIG_HOME")
+	_, _ = git2.PlainInit(filepath.Join(dir, "project"), false)
+
+	err := Git{}.Commit(dir, "project", nil, Commit{Message: "Initial"})
+
+	assert.EqualError(t, err, "no author for the commit of the generated files")
+	assert.Equal(t, failure.Config, err.(*failure.Error).Kind)
+}
+
+func TestGit_Push(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(dir) }()
+	remote, _ := git2.PlainInit(filepath.Join(dir, "remote.git"), true)
+	repo, _ := git2.PlainInit(filepath.Join(dir, "project"), false)
+	_, _ = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{filepath.Join(dir, "remote.git")}})
+	_ = ioutil.WriteFile(filepath.Join(dir, "project", "README.md"), []byte("# project"), 0666)
+	_ = Git{}.Commit(dir, "project", []string{"README.md"}, Commit{Message: "Initial", Name: "Test", Email: "test@example.com"})
+
+	err := Git{}.Push(context.Background(), dir, "project", &bytes.Buffer{})
+
+	assert.NoError(t, err)
+	head, _ := repo.Head()
+	pushed, err := remote.Reference(head.Name(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, head.Hash(), pushed.Hash())
+	assert.NoError(t, Git{}.Push(context.Background(), dir, "project", &bytes.Buffer{}))
+}
+
+func TestGit_Push_Branch(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(dir) }()
+	remote, _ := git2.PlainInit(filepath.Join(dir, "remote.git"), true)
+	repo, _ := git2.PlainInit(filepath.Join(dir, "project"), false)
+	_, _ = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{filepath.Join(dir, "remote.git")}})
+	_ = ioutil.WriteFile(filepath.Join(dir, "project", "README.md"), []byte("# project"), 0666)
+	_ = Git{}.Commit(dir, "project", []string{"README.md"}, Commit{Message: "Initial", Name: "Test", Email: "test@example.com"})
+	master, _ := repo.Head()
+	_ = ioutil.WriteFile(filepath.Join(dir, "project", ".buildtools.yaml"), []byte("registry: {}"), 0666)
+
+	err := Git{}.Commit(dir, "project", []string{".buildtools.yaml"}, Commit{Message: "Scaffold", Name: "Test", Email: "test@example.com", Branch: InitialBranch})
+	assert.NoError(t, err)
+	err = Git{}.Push(context.Background(), dir, "project", &bytes.Buffer{})
+	assert.NoError(t, err)
+
+	head, _ := repo.Head()
+	assert.Equal(t, "refs/heads/scaffold/initial", head.Name().String())
+	pushed, err := remote.Reference(head.Name(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, head.Hash(), pushed.Hash())
+	_, err = remote.Reference(master.Name(), true)
+	assert.Error(t, err)
+	commit, _ := repo.CommitObject(head.Hash())
+	assert.Equal(t, []plumbing.Hash{master.Hash()}, commit.ParentHashes)
+}
+
+func TestGit_Commit_Branch_Without_Commits(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(dir) }()
+	repo, _ := git2.PlainInit(filepath.Join(dir, "project"), false)
+	_ = ioutil.WriteFile(filepath.Join(dir, "project", "README.md"), []byte("# project"), 0666)
+
+	err := Git{}.Commit(dir, "project", []string{"README.md"}, Commit{Message: "Initial", Name: "Test", Email: "test@example.com", Branch: InitialBranch})
+
+	assert.NoError(t, err)
+	head, _ := repo.Head()
+	assert.Equal(t, "refs/heads/scaffold/initial", head.Name().String())
+}
+
+func TestOrigin(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "Git-repo")
+	defer func() { _ = os.RemoveAll(dir) }()
+	repo, _ := git2.PlainInit(dir, false)
+	_, _ = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"git@github.com:org/project.git"}})
+	sub := filepath.Join(dir, "sub")
+	_ = os.MkdirAll(sub, 0777)
+
+	root, info, err := Origin(sub)
+
+	assert.NoError(t, err)
+	assert.Equal(t, dir, root)
+	assert.Equal(t, &RepositoryInfo{SSHURL: "git@github.com:org/project.git", HTTPURL: "https://github.com/org/project.git"}, info)
+}
+
+func TestOrigin_Not_A_Repository(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "Git-repo")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	_, _, err := Origin(dir)
+
+	assert.EqualError(t, err, fmt.Sprintf("'%s' is not inside a git repository", dir))
+}
+
+func TestOrigin_Missing_Remote(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "Git-repo")
+	defer func() { _ = os.RemoveAll(dir) }()
+	_, _ = git2.PlainInit(dir, false)
+
+	_, _, err := Origin(dir)
+
+	assert.EqualError(t, err, fmt.Sprintf("the repository at '%s' has no 'origin' remote", dir))
+}
+
+func TestRepositoryInfo(t *testing.T) {
+	tests := []struct {
+		remote string
+		ssh    string
+		http   string
+	}{
+		{"git@github.com:org/project.git", "git@github.com:org/project.git", "https://github.com/org/project.git"},
+		{"git@gitlab.com:group/sub/project", "git@gitlab.com:group/sub/project.git", "https://gitlab.com/group/sub/project.git"},
+		{"ssh://git@gitlab.example.com:2222/group/project.git", "ssh://git@gitlab.example.com:2222/group/project.git", "https://gitlab.example.com/group/project.git"},
+		{"ssh://gitlab.example.com/group/project.git", "git@gitlab.example.com:group/project.git", "https://gitlab.example.com/group/project.git"},
+		{"https://gitlab.example.com:8443/group/project.git", "git@gitlab.example.com:group/project.git", "https://gitlab.example.com:8443/group/project.git"},
+		{"https://user@github.com/org/project.git", "git@github.com:org/project.git", "https://github.com/org/project.git"},
+		{"https://github.com/org/project", "git@github.com:org/project.git", "https://github.com/org/project.git"},
+	}
+	for _, test := range tests {
+		info, err := repositoryInfo(test.remote)
+		assert.NoError(t, err, test.remote)
+		assert.Equal(t, &RepositoryInfo{SSHURL: test.ssh, HTTPURL: test.http}, info, test.remote)
+	}
+}
+
+func TestRepositoryInfo_Invalid(t *testing.T) {
+	_, err := repositoryInfo("/some/local/path")
+
+	assert.EqualError(t, err, "unable to parse remote url '/some/local/path'")
+}
+
+func TestRepositoryInfo_Name(t *testing.T) {
+	info := &RepositoryInfo{HTTPURL: "https://gitlab.com/group/sub/project.git"}
+
+	name, err := info.Name()
+	assert.NoError(t, err)
+	assert.Equal(t, "project", name)
+
+	repoPath, err := info.Path()
+	assert.NoError(t, err)
+	assert.Equal(t, "group/sub/project", repoPath)
+}
+
+func TestRepositoryInfo_Path_Without_Owner(t *testing.T) {
+	_, err := (&RepositoryInfo{HTTPURL: "https://example.com/project.git"}).Path()
+
+	assert.EqualError(t, err, "repository url 'https://example.com/project.git' has no owner")
+}
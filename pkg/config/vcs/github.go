@@ -4,19 +4,27 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/buildtool/scaffold/pkg/retry"
 	"github.com/buildtool/scaffold/pkg/wrappers"
 	"github.com/google/go-github/v28/github"
 	"golang.org/x/oauth2"
 	"net/http"
+	"strings"
 )
 
 type Github struct {
-	Git
-	Token        string `yaml:"token" env:"GITHUB_TOKEN"`
-	Organisation string `yaml:"organisation" env:"GITHUB_ORG"`
-	Public       bool   `yaml:"public"`
-	repoOwner    string
-	repositories RepositoriesService
+	Git           `yaml:"-"`
+	Token         string `yaml:"token" env:"GITHUB_TOKEN"`
+	Organisation  string `yaml:"organisation" env:"GITHUB_ORG"`
+	Public        bool   `yaml:"public"`
+	repoOwner     string
+	hookId        int64
+	repositories  RepositoriesService
+	users         githubUsersService
+	organizations githubOrganizationsService
+	pullRequests  githubPullRequestsService
 }
 
 func (v *Github) Name() string {
@@ -30,15 +38,15 @@ func (v *Github) ValidateConfig() error {
 	return nil
 }
 
-func (v *Github) Scaffold(name string) (*RepositoryInfo, error) {
+func (v *Github) Scaffold(ctx context.Context, name string) (*RepositoryInfo, error) {
 	repo := &github.Repository{
 		Name:     wrappers.String(name),
-		Private:  wrappers.Bool(v.Public),
+		Private:  wrappers.Bool(!v.Public),
 		AutoInit: wrappers.Bool(true),
 	}
-	repo, resp, err := v.repositories.Create(context.Background(), v.Organisation, repo)
+	repo, resp, err := v.repositories.Create(ctx, v.Organisation, repo)
 	if err != nil {
-		return nil, err
+		return nil, v.apiError(resp, err)
 	}
 
 	v.repoOwner = v.Organisation
@@ -55,12 +63,21 @@ func (v *Github) Scaffold(name string) (*RepositoryInfo, error) {
 			EnforceAdmins: true,
 		}
 
-		_, response, err := v.repositories.UpdateBranchProtection(context.Background(), v.repoOwner, *repo.Name, "master", preq)
+		_, response, err := v.repositories.UpdateBranchProtection(ctx, v.repoOwner, *repo.Name, "master", preq)
 		if err != nil || (response != nil && response.StatusCode != http.StatusOK) {
-			return nil, fmt.Errorf("failed to set repository branch protection %s", response.Status)
+			e := v.apiError(response, fmt.Errorf("failed to set repository branch protection %s", status(response)))
+			if e.Status == http.StatusForbidden || e.Status == http.StatusNotFound {
+				e.Kind = failure.Auth
+				e.Hint = "branch protection needs admin access to the repository and a paid plan for private repositories"
+			}
+			return nil, e
 		}
 	default:
-		return nil, fmt.Errorf("failed to create repository %s, %s", name, resp.Status)
+		e := v.apiError(resp, fmt.Errorf("failed to create repository %s, %s", name, resp.Status))
+		if e.Kind == failure.Conflict {
+			e.Hint = fmt.Sprintf("choose another name or remove the existing repository '%s'", name)
+		}
+		return nil, e
 	}
 	return &RepositoryInfo{
 		SSHURL:  *repo.SSHURL,
@@ -68,7 +85,16 @@ func (v *Github) Scaffold(name string) (*RepositoryInfo, error) {
 	}, nil
 }
 
-func (v *Github) Webhook(name, url string) error {
+func (v *Github) Adopt(repository *RepositoryInfo) error {
+	repoPath, err := repository.Path()
+	if err != nil {
+		return err
+	}
+	v.repoOwner = strings.Split(repoPath, "/")[0]
+	return nil
+}
+
+func (v *Github) Webhook(ctx context.Context, name, url string) error {
 	hook := &github.Hook{
 		Events: []string{
 			"push",
@@ -82,30 +108,182 @@ func (v *Github) Webhook(name, url string) error {
 		Active: wrappers.Bool(true),
 	}
 
-	_, resp, err := v.repositories.CreateHook(context.Background(), v.repoOwner, name, hook)
+	created, resp, err := v.repositories.CreateHook(ctx, v.repoOwner, name, hook)
 	if err != nil || (resp != nil && resp.StatusCode != http.StatusCreated) {
-		return fmt.Errorf("failed to create webhook %s", resp.Status)
+		e := v.apiError(resp, fmt.Errorf("failed to create webhook %s", status(resp)))
+		if e.Status == http.StatusForbidden || e.Status == http.StatusNotFound {
+			e.Kind = failure.Auth
+			e.Hint = "token lacks admin:repo_hook scope"
+		}
+		return e
+	}
+	if created != nil && created.ID != nil {
+		v.hookId = *created.ID
 	}
 
 	return nil
 }
 
-func (v *Github) Validate(name string) error {
+func (v *Github) PullRequest(ctx context.Context, name, branch, title, body string) (string, error) {
+	pull, response, err := v.pullRequests.Create(ctx, v.repoOwner, name, &github.NewPullRequest{
+		Title: wrappers.String(title),
+		Head:  wrappers.String(branch),
+		Base:  wrappers.String("master"),
+		Body:  wrappers.String(body),
+	})
+	if err != nil {
+		return "", v.apiError(response, err)
+	}
+	return pull.GetHTMLURL(), nil
+}
+
+func (v *Github) DeleteRepository(ctx context.Context, name string) error {
+	_, err := v.repositories.Delete(ctx, v.repoOwner, name)
+	return err
+}
+
+func (v *Github) DeleteWebhook(ctx context.Context, name string) error {
+	if v.hookId == 0 {
+		return nil
+	}
+	_, err := v.repositories.DeleteHook(ctx, v.repoOwner, name, v.hookId)
+	return err
+}
+
+func (v *Github) Validate(ctx context.Context, name string) error {
 	// TODO: Check that repository doesn't already exists
 	return nil
 }
 
-func (v *Github) Configure() {
-	client := github.NewClient(oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: v.Token},
-	)))
+func (v *Github) Check(ctx context.Context, report func(check string, err error)) {
+	user, response, err := v.users.Get(ctx, "")
+	if err != nil {
+		report("token", v.apiError(response, err))
+		return
+	}
+	report("token", nil)
+	// Fine-grained tokens do not list their scopes
+	if scopes := response.Header.Get("X-OAuth-Scopes"); scopes != "" {
+		report("repo scope", requireScope(scopes, "repo", "create repositories"))
+		report("admin:repo_hook scope", requireScope(scopes, "admin:repo_hook", "add the webhook of the build pipeline"))
+	}
+	if v.Organisation == "" {
+		// The owner of the account is an admin of its repositories
+		report("branch protection", nil)
+		return
+	}
+	check := fmt.Sprintf("organisation '%s'", v.Organisation)
+	membership, response, err := v.organizations.GetOrgMembership(ctx, "", v.Organisation)
+	if err != nil {
+		report(check, v.apiError(response, err))
+		return
+	}
+	if membership.GetState() != "active" {
+		report(check, &failure.Error{
+			Kind:     failure.Auth,
+			Provider: v.Name(),
+			Hint:     fmt.Sprintf("accept the invitation to '%s' on Github", v.Organisation),
+			Err:      fmt.Errorf("membership of '%s' in '%s' is %s", user.GetLogin(), v.Organisation, membership.GetState()),
+		})
+		return
+	}
+	if membership.GetRole() == "admin" {
+		report(check, nil)
+		report("branch protection", nil)
+		return
+	}
+	org, response, err := v.organizations.Get(ctx, v.Organisation)
+	if err != nil {
+		report(check, v.apiError(response, err))
+		return
+	}
+	if org.MembersCanCreateRepos != nil && !*org.MembersCanCreateRepos {
+		report(check, &failure.Error{
+			Kind:     failure.Auth,
+			Provider: v.Name(),
+			Hint:     fmt.Sprintf("ask an owner of '%s' to allow members to create repositories or to make you an owner", v.Organisation),
+			Err:      fmt.Errorf("members of '%s' are not allowed to create repositories and '%s' is not an owner", v.Organisation, user.GetLogin()),
+		})
+		return
+	}
+	report(check, nil)
+	report("branch protection", &failure.Error{
+		Kind:     failure.Auth,
+		Provider: v.Name(),
+		Hint:     fmt.Sprintf("make sure members of '%s' are admins of the repositories they create or ask an owner to make you one", v.Organisation),
+		Err:      fmt.Errorf("protecting the default branch needs admin rights on the repository and '%s' is not an owner of '%s'", user.GetLogin(), v.Organisation),
+	})
+}
+
+func requireScope(scopes, scope, needed string) error {
+	for _, s := range strings.Split(scopes, ",") {
+		if strings.TrimSpace(s) == scope {
+			return nil
+		}
+	}
+	return &failure.Error{
+		Kind:     failure.Auth,
+		Provider: "Github",
+		Hint:     fmt.Sprintf("create a new token with the '%s' scope", scope),
+		Err:      fmt.Errorf("token lacks the '%s' scope needed to %s, it has '%s'", scope, needed, scopes),
+	}
+}
+
+func (v *Github) apiError(response *github.Response, err error) *failure.Error {
+	code := 0
+	if response != nil && response.Response != nil {
+		code = response.StatusCode
+	}
+	return failure.API(v.Name(), code, err)
+}
+
+func status(response *github.Response) string {
+	if response == nil || response.Response == nil {
+		return "no response"
+	}
+	return response.Status
+}
+
+func (v *Github) Configure(r report.Reporter) {
+	client := v.client(r)
 	v.repositories = client.Repositories
+	v.users = client.Users
+	v.organizations = client.Organizations
+	v.pullRequests = client.PullRequests
+}
+
+func (v *Github) client(r report.Reporter) *github.Client {
+	httpClient := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: v.Token},
+	))
+	httpClient.Transport = retry.New(httpClient.Transport, v.Name(), r)
+	return github.NewClient(httpClient)
+}
+
+func (v *Github) DryRun(r report.Reporter) {
+	v.repositories = &dryRunRepositories{reporter: r}
+	v.pullRequests = &dryRunPullRequests{reporter: r}
 }
 
 var _ VCS = &Github{}
 
+type githubUsersService interface {
+	Get(ctx context.Context, user string) (*github.User, *github.Response, error)
+}
+
+type githubOrganizationsService interface {
+	Get(ctx context.Context, org string) (*github.Organization, *github.Response, error)
+	GetOrgMembership(ctx context.Context, user, org string) (*github.Membership, *github.Response, error)
+}
+
+type githubPullRequestsService interface {
+	Create(ctx context.Context, owner string, repo string, pull *github.NewPullRequest) (*github.PullRequest, *github.Response, error)
+}
+
 type RepositoriesService interface {
 	Create(ctx context.Context, org string, repo *github.Repository) (*github.Repository, *github.Response, error)
 	UpdateBranchProtection(ctx context.Context, owner, repo, branch string, preq *github.ProtectionRequest) (*github.Protection, *github.Response, error)
 	CreateHook(ctx context.Context, owner, repo string, hook *github.Hook) (*github.Hook, *github.Response, error)
+	Delete(ctx context.Context, owner, repo string) (*github.Response, error)
+	DeleteHook(ctx context.Context, owner, repo string, id int64) (*github.Response, error)
 }
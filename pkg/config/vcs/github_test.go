@@ -1,21 +1,27 @@
 package vcs
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/buildtool/scaffold/pkg/config/vcs/mocks"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/report"
 	"github.com/buildtool/scaffold/pkg/wrappers"
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-github/v28/github"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 )
 
 func TestGithub_Validate(t *testing.T) {
 	vcs := &Github{}
 
-	err := vcs.Validate("project")
+	err := vcs.Validate(context.Background(), "project")
 
 	assert.NoError(t, err)
 }
@@ -23,9 +29,116 @@ func TestGithub_Validate(t *testing.T) {
 func TestGithub_Configure(t *testing.T) {
 	vcs := &Github{}
 
-	vcs.Configure()
+	vcs.Configure(report.Nop)
 
 	assert.NotNil(t, vcs.repositories)
+	assert.NotNil(t, vcs.users)
+	assert.NotNil(t, vcs.organizations)
+}
+
+func TestGithub_Configure_Retries_Rate_Limited(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"login":"user"}`)
+	}))
+	defer server.Close()
+	out := &bytes.Buffer{}
+	client := (&Github{Token: "abc"}).client(report.NewText(out, true))
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	user, _, err := client.Users.Get(context.Background(), "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user", user.GetLogin())
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "\x1b[0m\x1b[33mRate limit of \x1b[39m\x1b[97m\x1b[1mGithub\x1b[0m\x1b[97m\x1b[39m \x1b[33mreached, retrying in \x1b[39m\x1b[97m\x1b[1m0s\x1b[0m\x1b[97m\x1b[39m \x1b[33m(attempt \x1b[39m\x1b[97m\x1b[1m2\x1b[0m\x1b[97m\x1b[39m \x1b[33mof \x1b[39m\x1b[97m\x1b[1m5\x1b[0m\x1b[97m\x1b[39m\x1b[33m)\x1b[39m\n\x1b[0m", out.String())
+}
+
+func TestGithub_Check_Invalid_Token(t *testing.T) {
+	vcs := &Github{users: &mockGithubUsers{err: errors.New("401 Bad credentials")}}
+
+	assert.Equal(t, []string{"token: 401 Bad credentials"}, checks(vcs))
+}
+
+func TestGithub_Check_Missing_Repo_Scope(t *testing.T) {
+	vcs := &Github{users: &mockGithubUsers{scopes: "read:org, gist"}}
+
+	assert.Equal(t, []string{"token: ok", "repo scope: token lacks the 'repo' scope needed to create repositories, it has 'read:org, gist'", "admin:repo_hook scope: token lacks the 'admin:repo_hook' scope needed to add the webhook of the build pipeline, it has 'read:org, gist'", "branch protection: ok"}, checks(vcs))
+}
+
+func TestGithub_Check_Missing_Hook_Scope(t *testing.T) {
+	vcs := &Github{users: &mockGithubUsers{scopes: "repo"}}
+
+	assert.Equal(t, []string{"token: ok", "repo scope: ok", "admin:repo_hook scope: token lacks the 'admin:repo_hook' scope needed to add the webhook of the build pipeline, it has 'repo'", "branch protection: ok"}, checks(vcs))
+}
+
+func TestGithub_Check_Without_Organisation(t *testing.T) {
+	vcs := &Github{users: &mockGithubUsers{scopes: "admin:repo_hook, repo"}}
+
+	assert.Equal(t, []string{"token: ok", "repo scope: ok", "admin:repo_hook scope: ok", "branch protection: ok"}, checks(vcs))
+}
+
+func TestGithub_Check_Organisation_Membership_Error(t *testing.T) {
+	vcs := &Github{
+		Organisation:  "org",
+		users:         &mockGithubUsers{},
+		organizations: &mockGithubOrganizations{membershipErr: errors.New("404 Not Found")},
+	}
+
+	assert.Equal(t, []string{"token: ok", "organisation 'org': 404 Not Found"}, checks(vcs))
+}
+
+func TestGithub_Check_Organisation_Pending(t *testing.T) {
+	vcs := &Github{
+		Organisation:  "org",
+		users:         &mockGithubUsers{},
+		organizations: &mockGithubOrganizations{membership: &github.Membership{State: github.String("pending"), Role: github.String("admin")}},
+	}
+
+	assert.Equal(t, []string{"token: ok", "organisation 'org': membership of 'user' in 'org' is pending"}, checks(vcs))
+}
+
+func TestGithub_Check_Organisation_Admin(t *testing.T) {
+	vcs := &Github{
+		Organisation:  "org",
+		users:         &mockGithubUsers{},
+		organizations: &mockGithubOrganizations{membership: &github.Membership{State: github.String("active"), Role: github.String("admin")}, orgErr: errors.New("not called")},
+	}
+
+	assert.Equal(t, []string{"token: ok", "organisation 'org': ok", "branch protection: ok"}, checks(vcs))
+}
+
+func TestGithub_Check_Organisation_Members_Cannot_Create(t *testing.T) {
+	vcs := &Github{
+		Organisation: "org",
+		users:        &mockGithubUsers{},
+		organizations: &mockGithubOrganizations{
+			membership: &github.Membership{State: github.String("active"), Role: github.String("member")},
+			org:        &github.Organization{MembersCanCreateRepos: github.Bool(false)},
+		},
+	}
+
+	assert.Equal(t, []string{"token: ok", "organisation 'org': members of 'org' are not allowed to create repositories and 'user' is not an owner"}, checks(vcs))
+}
+
+func TestGithub_Check_Organisation_Member(t *testing.T) {
+	vcs := &Github{
+		Organisation: "org",
+		users:        &mockGithubUsers{},
+		organizations: &mockGithubOrganizations{
+			membership: &github.Membership{State: github.String("active"), Role: github.String("member")},
+			org:        &github.Organization{MembersCanCreateRepos: github.Bool(true)},
+		},
+	}
+
+	assert.Equal(t, []string{"token: ok", "organisation 'org': ok", "branch protection: protecting the default branch needs admin rights on the repository and 'user' is not an owner of 'org'"}, checks(vcs))
 }
 
 func TestGithubVCS_Scaffold(t *testing.T) {
@@ -45,7 +158,7 @@ func TestGithubVCS_Scaffold(t *testing.T) {
 	repository := github.Repository{
 		Name:     wrappers.String(repoName),
 		AutoInit: wrappers.Bool(true),
-		Private:  wrappers.Bool(false),
+		Private:  wrappers.Bool(true),
 	}
 
 	repositoryResponse := repository
@@ -67,7 +180,7 @@ func TestGithubVCS_Scaffold(t *testing.T) {
 		}).Return(nil, githubOkResponse, nil).
 		Times(1)
 
-	res, err := git.Scaffold(repoName)
+	res, err := git.Scaffold(context.Background(), repoName)
 	assert.NoError(t, err)
 	assert.Equal(t, &RepositoryInfo{repoSSHUrl, repoCloneUrl}, res)
 }
@@ -85,7 +198,7 @@ func TestGithubVCS_ScaffoldWithoutOrganisation(t *testing.T) {
 	repository := github.Repository{
 		Name:     wrappers.String(repoName),
 		AutoInit: wrappers.Bool(true),
-		Private:  wrappers.Bool(false),
+		Private:  wrappers.Bool(true),
 	}
 
 	repositoryResponse := repository
@@ -110,7 +223,7 @@ func TestGithubVCS_ScaffoldWithoutOrganisation(t *testing.T) {
 		}).Return(nil, githubOkResponse, nil).
 		Times(1)
 
-	res, err := git.Scaffold(repoName)
+	res, err := git.Scaffold(context.Background(), repoName)
 	assert.NoError(t, err)
 	assert.Equal(t, &RepositoryInfo{repoSSHUrl, repoCloneUrl}, res)
 
@@ -127,7 +240,7 @@ func TestGithubVCS_Scaffold_RepositoryAlreadyExist(t *testing.T) {
 	repository := github.Repository{
 		Name:     wrappers.String(repoName),
 		AutoInit: wrappers.Bool(true),
-		Private:  wrappers.Bool(false),
+		Private:  wrappers.Bool(true),
 	}
 
 	repositoryResponse := repository
@@ -145,10 +258,36 @@ func TestGithubVCS_Scaffold_RepositoryAlreadyExist(t *testing.T) {
 		}, nil).
 		Times(1)
 
-	_, err := git.Scaffold(repoName)
+	_, err := git.Scaffold(context.Background(), repoName)
 	assert.EqualError(t, err, "failed to create repository ALREADY_EXISTS, already exists")
 }
 
+func TestGithubVCS_Scaffold_RepositoryAlreadyExist_Is_Conflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockRepositoriesService(ctrl)
+	git := Github{Organisation: "org", repositories: m}
+
+	m.EXPECT().
+		Create(context.Background(), "org", gomock.Any()).Return(&github.Repository{},
+		&github.Response{
+			Response: &http.Response{
+				StatusCode: http.StatusUnprocessableEntity,
+				Status:     "422 Unprocessable Entity",
+			},
+		}, nil).
+		Times(1)
+
+	_, err := git.Scaffold(context.Background(), "repo")
+	assert.Equal(t, &failure.Error{
+		Kind:     failure.Conflict,
+		Provider: "Github",
+		Status:   http.StatusUnprocessableEntity,
+		Hint:     "choose another name or remove the existing repository 'repo'",
+		Err:      errors.New("failed to create repository repo, 422 Unprocessable Entity"),
+	}, err)
+}
+
 func TestGithubVCS_Scaffold_CreateError(t *testing.T) {
 	repoName := "ALREADY_EXISTS"
 
@@ -163,7 +302,7 @@ func TestGithubVCS_Scaffold_CreateError(t *testing.T) {
 	repository := &github.Repository{
 		Name:     wrappers.String(repoName),
 		AutoInit: wrappers.Bool(true),
-		Private:  wrappers.Bool(false),
+		Private:  wrappers.Bool(true),
 	}
 
 	m.EXPECT().
@@ -171,7 +310,7 @@ func TestGithubVCS_Scaffold_CreateError(t *testing.T) {
 		repository, nil, fmt.Errorf("failed to create repo")).
 		Times(1)
 
-	_, err := git.Scaffold(repoName)
+	_, err := git.Scaffold(context.Background(), repoName)
 	assert.EqualError(t, err, "failed to create repo")
 }
 
@@ -187,7 +326,7 @@ func TestGithubVCS_ScaffoldProtectBranchError(t *testing.T) {
 	repository := github.Repository{
 		Name:     wrappers.String(repoName),
 		AutoInit: wrappers.Bool(true),
-		Private:  wrappers.Bool(false),
+		Private:  wrappers.Bool(true),
 	}
 
 	repositoryResponse := repository
@@ -212,7 +351,7 @@ func TestGithubVCS_ScaffoldProtectBranchError(t *testing.T) {
 		nil, githubBadRequestResponse, nil).
 		Times(1)
 
-	_, err := git.Scaffold(repoName)
+	_, err := git.Scaffold(context.Background(), repoName)
 	assert.EqualError(t, err, "failed to set repository branch protection something went wrong")
 }
 
@@ -251,7 +390,7 @@ func TestGithubVCS_Webhook(t *testing.T) {
 	}).Return(nil, githubCreatedResponse, nil).
 		Times(1)
 
-	err := githubVCS.Webhook("repo", "https://ab.cd")
+	err := githubVCS.Webhook(context.Background(), "repo", "https://ab.cd")
 	assert.NoError(t, err)
 }
 
@@ -278,10 +417,143 @@ func TestGithubVCS_WebhookError(t *testing.T) {
 	}).Return(nil, githubBadRequestResponse, nil).
 		Times(1)
 
-	err := githubVCS.Webhook("repo", "https://ab.cd")
+	err := githubVCS.Webhook(context.Background(), "repo", "https://ab.cd")
 	assert.EqualError(t, err, "failed to create webhook something went wrong")
 }
 
+func TestGithubVCS_Webhook_Missing_Scope(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepositoriesService(ctrl)
+	githubVCS := Github{
+		repoOwner:    "test",
+		repositories: m,
+	}
+	response := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found"}}
+	m.EXPECT().CreateHook(context.Background(), "test", "repo", gomock.Any()).Return(nil, response, errors.New("404 Not Found")).
+		Times(1)
+
+	err := githubVCS.Webhook(context.Background(), "repo", "https://ab.cd")
+	assert.Equal(t, &failure.Error{
+		Kind:     failure.Auth,
+		Provider: "Github",
+		Status:   http.StatusNotFound,
+		Hint:     "token lacks admin:repo_hook scope",
+		Err:      errors.New("failed to create webhook 404 Not Found"),
+	}, err)
+}
+
+func TestGithubVCS_Adopt(t *testing.T) {
+	githubVCS := Github{Organisation: "other"}
+
+	err := githubVCS.Adopt(&RepositoryInfo{HTTPURL: "https://github.com/org/repo.git"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "org", githubVCS.repoOwner)
+}
+
+func TestGithubVCS_Adopt_Error(t *testing.T) {
+	githubVCS := Github{}
+
+	err := githubVCS.Adopt(&RepositoryInfo{HTTPURL: "https://github.com/repo.git"})
+
+	assert.EqualError(t, err, "repository url 'https://github.com/repo.git' has no owner")
+}
+
+func TestGithubVCS_DeleteRepository(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepositoriesService(ctrl)
+	githubVCS := Github{
+		repoOwner:    "test",
+		repositories: m,
+	}
+	m.EXPECT().Delete(context.Background(), "test", "repo").Return(nil, errors.New("delete error")).
+		Times(1)
+
+	err := githubVCS.DeleteRepository(context.Background(), "repo")
+	assert.EqualError(t, err, "delete error")
+}
+
+func TestGithubVCS_DeleteWebhook_Without_Hook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepositoriesService(ctrl)
+	githubVCS := Github{
+		repoOwner:    "test",
+		repositories: m,
+	}
+
+	err := githubVCS.DeleteWebhook(context.Background(), "repo")
+	assert.NoError(t, err)
+}
+
+func TestGithubVCS_DeleteWebhook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepositoriesService(ctrl)
+	githubVCS := Github{
+		repoOwner:    "test",
+		repositories: m,
+	}
+	m.EXPECT().CreateHook(context.Background(), "test", "repo", gomock.Any()).Return(&github.Hook{ID: github.Int64(42)}, githubCreatedResponse, nil).
+		Times(1)
+	m.EXPECT().DeleteHook(context.Background(), "test", "repo", int64(42)).Return(nil, nil).
+		Times(1)
+
+	assert.NoError(t, githubVCS.Webhook(context.Background(), "repo", "https://ab.cd"))
+	err := githubVCS.DeleteWebhook(context.Background(), "repo")
+	assert.NoError(t, err)
+}
+
+func checks(v VCS) []string {
+	var result []string
+	v.Check(context.Background(), func(check string, err error) {
+		if err != nil {
+			result = append(result, fmt.Sprintf("%s: %s", check, err.Error()))
+		} else {
+			result = append(result, fmt.Sprintf("%s: ok", check))
+		}
+	})
+	return result
+}
+
+type mockGithubUsers struct {
+	err    error
+	scopes string
+}
+
+func (m mockGithubUsers) Get(ctx context.Context, user string) (*github.User, *github.Response, error) {
+	header := http.Header{}
+	if m.scopes != "" {
+		header.Set("X-OAuth-Scopes", m.scopes)
+	}
+	return &github.User{Login: github.String("user")}, &github.Response{Response: &http.Response{Header: header}}, m.err
+}
+
+var _ githubUsersService = &mockGithubUsers{}
+
+type mockGithubOrganizations struct {
+	orgErr        error
+	membershipErr error
+	org           *github.Organization
+	membership    *github.Membership
+}
+
+func (m mockGithubOrganizations) Get(ctx context.Context, org string) (*github.Organization, *github.Response, error) {
+	return m.org, nil, m.orgErr
+}
+
+func (m mockGithubOrganizations) GetOrgMembership(ctx context.Context, user, org string) (*github.Membership, *github.Response, error) {
+	return m.membership, nil, m.membershipErr
+}
+
+var _ githubOrganizationsService = &mockGithubOrganizations{}
+
 var githubOkResponse = &github.Response{
 	Response: &http.Response{
 		StatusCode: http.StatusOK,
@@ -300,3 +572,47 @@ var githubBadRequestResponse = &github.Response{
 		Status:     "something went wrong",
 	},
 }
+
+func TestGithub_PullRequest(t *testing.T) {
+	pulls := &mockGithubPullRequests{pull: &github.PullRequest{HTMLURL: github.String("https://github.com/org/project/pull/1")}}
+	vcs := &Github{repoOwner: "org", pullRequests: pulls}
+
+	url, err := vcs.PullRequest(context.Background(), "project", "scaffold/initial", "Scaffold project", "body")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.com/org/project/pull/1", url)
+	assert.Equal(t, "org", pulls.owner)
+	assert.Equal(t, "project", pulls.repo)
+	assert.Equal(t, &github.NewPullRequest{
+		Title: github.String("Scaffold project"),
+		Head:  github.String("scaffold/initial"),
+		Base:  github.String("master"),
+		Body:  github.String("body"),
+	}, pulls.request)
+}
+
+func TestGithub_PullRequest_Error(t *testing.T) {
+	vcs := &Github{repoOwner: "org", pullRequests: &mockGithubPullRequests{err: errors.New("validation failed")}}
+
+	url, err := vcs.PullRequest(context.Background(), "project", "scaffold/initial", "Scaffold project", "body")
+
+	assert.EqualError(t, err, "validation failed")
+	assert.Equal(t, "", url)
+}
+
+type mockGithubPullRequests struct {
+	err     error
+	owner   string
+	repo    string
+	request *github.NewPullRequest
+	pull    *github.PullRequest
+}
+
+func (m *mockGithubPullRequests) Create(ctx context.Context, owner string, repo string, pull *github.NewPullRequest) (*github.PullRequest, *github.Response, error) {
+	m.owner = owner
+	m.repo = repo
+	m.request = pull
+	return m.pull, nil, m.err
+}
+
+var _ githubPullRequestsService = &mockGithubPullRequests{}
@@ -1,9 +1,15 @@
 package vcs
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/buildtool/scaffold/pkg/retry"
 	"github.com/xanzy/go-gitlab"
+	"net/http"
+	"path"
 	"path/filepath"
 )
 
@@ -11,19 +17,37 @@ type projectsService interface {
 	GetProject(pid interface{}, opt *gitlab.GetProjectOptions, options ...gitlab.OptionFunc) (*gitlab.Project, *gitlab.Response, error)
 	CreateProject(opt *gitlab.CreateProjectOptions, options ...gitlab.OptionFunc) (*gitlab.Project, *gitlab.Response, error)
 	AddProjectHook(pid interface{}, opt *gitlab.AddProjectHookOptions, options ...gitlab.OptionFunc) (*gitlab.ProjectHook, *gitlab.Response, error)
+	DeleteProject(pid interface{}, options ...gitlab.OptionFunc) (*gitlab.Response, error)
+	DeleteProjectHook(pid interface{}, hook int, options ...gitlab.OptionFunc) (*gitlab.Response, error)
+}
+
+type mergeRequestsService interface {
+	CreateMergeRequest(pid interface{}, opt *gitlab.CreateMergeRequestOptions, options ...gitlab.OptionFunc) (*gitlab.MergeRequest, *gitlab.Response, error)
 }
 
 type groupsService interface {
 	GetGroup(gid interface{}, options ...gitlab.OptionFunc) (*gitlab.Group, *gitlab.Response, error)
 }
 
+type usersService interface {
+	CurrentUser(options ...gitlab.OptionFunc) (*gitlab.User, *gitlab.Response, error)
+}
+
+type membersService interface {
+	ListAllGroupMembers(gid interface{}, opt *gitlab.ListGroupMembersOptions, options ...gitlab.OptionFunc) ([]*gitlab.GroupMember, *gitlab.Response, error)
+}
+
 type Gitlab struct {
-	Git
+	Git             `yaml:"-"`
 	Group           string `yaml:"group" env:"GITLAB_GROUP"`
 	Token           string `yaml:"token" env:"GITLAB_TOKEN"`
 	Visibility      string `yaml:"visibility"`
+	hookId          int
 	projectsService projectsService
 	groupsService   groupsService
+	usersService    usersService
+	membersService  membersService
+	mergeRequests   mergeRequestsService
 }
 
 func (v *Gitlab) Name() string {
@@ -37,14 +61,14 @@ func (v *Gitlab) ValidateConfig() error {
 	return nil
 }
 
-func (v *Gitlab) Scaffold(name string) (*RepositoryInfo, error) {
-	group, _, err := v.groupsService.GetGroup(v.Group)
+func (v *Gitlab) Scaffold(ctx context.Context, name string) (*RepositoryInfo, error) {
+	group, response, err := v.groupsService.GetGroup(v.Group, gitlab.WithContext(ctx))
 	if err != nil {
-		return nil, err
+		return nil, v.apiError(response, err)
 	}
 
 	visibility := gitlab.VisibilityValue(v.Visibility)
-	project, _, err := v.projectsService.CreateProject(&gitlab.CreateProjectOptions{
+	project, response, err := v.projectsService.CreateProject(&gitlab.CreateProjectOptions{
 		Name:                             gitlab.String(name),
 		NamespaceID:                      gitlab.Int(group.ID),
 		IssuesEnabled:                    gitlab.Bool(true),
@@ -61,9 +85,13 @@ func (v *Gitlab) Scaffold(name string) (*RepositoryInfo, error) {
 		OnlyAllowMergeIfAllDiscussionsAreResolved: gitlab.Bool(true),
 		PrintingMergeRequestLinkEnabled:           gitlab.Bool(true),
 		InitializeWithReadme:                      gitlab.Bool(true),
-	})
+	}, gitlab.WithContext(ctx))
 	if err != nil {
-		return nil, err
+		e := v.apiError(response, err)
+		if e.Kind == failure.Conflict {
+			e.Hint = fmt.Sprintf("choose another name or remove the existing project '%s/%s'", v.Group, name)
+		}
+		return nil, e
 	}
 	return &RepositoryInfo{
 		SSHURL:  project.SSHURLToRepo,
@@ -71,39 +99,157 @@ func (v *Gitlab) Scaffold(name string) (*RepositoryInfo, error) {
 	}, nil
 }
 
-func (v *Gitlab) Webhook(name, url string) error {
+func (v *Gitlab) Adopt(repository *RepositoryInfo) error {
+	repoPath, err := repository.Path()
+	if err != nil {
+		return err
+	}
+	v.Group = path.Dir(repoPath)
+	return nil
+}
+
+func (v *Gitlab) Webhook(ctx context.Context, name, url string) error {
 	path := filepath.Join(v.Group, name)
-	_, _, err := v.projectsService.AddProjectHook(path, &gitlab.AddProjectHookOptions{
+	hook, response, err := v.projectsService.AddProjectHook(path, &gitlab.AddProjectHookOptions{
 		URL:                 gitlab.String(url),
 		PushEvents:          gitlab.Bool(true),
 		MergeRequestsEvents: gitlab.Bool(true),
 		TagPushEvents:       gitlab.Bool(true),
-	})
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		e := v.apiError(response, err)
+		if e.Kind == failure.Auth {
+			e.Hint = fmt.Sprintf("webhooks need at least the maintainer role in '%s'", v.Group)
+		}
+		return e
+	}
+	if hook != nil {
+		v.hookId = hook.ID
+	}
+	return nil
+}
+
+func (v *Gitlab) PullRequest(ctx context.Context, name, branch, title, body string) (string, error) {
+	request, response, err := v.mergeRequests.CreateMergeRequest(filepath.Join(v.Group, name), &gitlab.CreateMergeRequestOptions{
+		Title:              gitlab.String(title),
+		Description:        gitlab.String(body),
+		SourceBranch:       gitlab.String(branch),
+		TargetBranch:       gitlab.String("master"),
+		RemoveSourceBranch: gitlab.Bool(true),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", v.apiError(response, err)
+	}
+	return request.WebURL, nil
+}
+
+func (v *Gitlab) DeleteRepository(ctx context.Context, name string) error {
+	_, err := v.projectsService.DeleteProject(filepath.Join(v.Group, name), gitlab.WithContext(ctx))
 	return err
 }
 
-func (v *Gitlab) Validate(name string) error {
-	_, _, err := v.groupsService.GetGroup(v.Group)
+func (v *Gitlab) DeleteWebhook(ctx context.Context, name string) error {
+	if v.hookId == 0 {
+		return nil
+	}
+	_, err := v.projectsService.DeleteProjectHook(filepath.Join(v.Group, name), v.hookId, gitlab.WithContext(ctx))
+	return err
+}
+
+func (v *Gitlab) Validate(ctx context.Context, name string) error {
+	_, response, err := v.groupsService.GetGroup(v.Group, gitlab.WithContext(ctx))
 	if err != nil {
-		return err
+		return v.groupError(response, err)
 	}
 	path := filepath.Join(v.Group, name)
-	project, response, err := v.projectsService.GetProject(path, nil)
+	project, response, err := v.projectsService.GetProject(path, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		if response == nil || response.StatusCode != 404 {
-			return err
+			return v.apiError(response, err)
 		}
 	}
 	if project != nil {
-		return fmt.Errorf("project named '%s/%s' already exists at Gitlab", v.Group, name)
+		return &failure.Error{
+			Kind:     failure.Conflict,
+			Provider: v.Name(),
+			Hint:     "choose another name or remove the existing project",
+			Err:      fmt.Errorf("project named '%s/%s' already exists at Gitlab", v.Group, name),
+		}
 	}
 	return nil
 }
 
-func (v *Gitlab) Configure() {
-	client := gitlab.NewClient(nil, v.Token)
+func (v *Gitlab) Check(ctx context.Context, report func(check string, err error)) {
+	user, response, err := v.usersService.CurrentUser(gitlab.WithContext(ctx))
+	if err != nil {
+		report("token", v.apiError(response, err))
+		return
+	}
+	report("token", nil)
+	check := fmt.Sprintf("group '%s'", v.Group)
+	group, response, err := v.groupsService.GetGroup(v.Group, gitlab.WithContext(ctx))
+	if err != nil {
+		report(check, v.groupError(response, err))
+		return
+	}
+	report(check, nil)
+	members, response, err := v.membersService.ListAllGroupMembers(group.ID, &gitlab.ListGroupMembersOptions{Query: gitlab.String(user.Username)}, gitlab.WithContext(ctx))
+	if err != nil {
+		report("maintainer role", v.apiError(response, err))
+		return
+	}
+	var level gitlab.AccessLevelValue
+	for _, member := range members {
+		if member.ID == user.ID && member.AccessLevel > level {
+			level = member.AccessLevel
+		}
+	}
+	if level < gitlab.MaintainerPermissions {
+		report("maintainer role", &failure.Error{
+			Kind:     failure.Auth,
+			Provider: v.Name(),
+			Hint:     fmt.Sprintf("ask an owner of '%s' to give '%s' the maintainer role", v.Group, user.Username),
+			Err:      fmt.Errorf("'%s' needs at least the maintainer role in '%s' to create projects and webhooks", user.Username, v.Group),
+		})
+		return
+	}
+	report("maintainer role", nil)
+}
+
+func (v *Gitlab) groupError(response *gitlab.Response, err error) *failure.Error {
+	e := v.apiError(response, err)
+	if e.Status == http.StatusNotFound {
+		e.Kind = failure.Config
+		e.Hint = fmt.Sprintf("check that the group '%s' exists and that the token can access it", v.Group)
+	}
+	return e
+}
+
+func (v *Gitlab) apiError(response *gitlab.Response, err error) *failure.Error {
+	code := 0
+	if response != nil && response.Response != nil {
+		code = response.StatusCode
+	}
+	return failure.API(v.Name(), code, err)
+}
+
+func (v *Gitlab) Configure(r report.Reporter) {
+	client := v.client(r)
 	v.projectsService = client.Projects
 	v.groupsService = client.Groups
+	v.usersService = client.Users
+	v.membersService = client.Groups
+	v.mergeRequests = client.MergeRequests
+}
+
+func (v *Gitlab) client(r report.Reporter) *gitlab.Client {
+	return gitlab.NewClient(&http.Client{Transport: retry.New(nil, v.Name(), r)}, v.Token)
+}
+
+func (v *Gitlab) DryRun(r report.Reporter) {
+	v.projectsService = &dryRunProjects{reporter: r, group: v.Group}
+	v.groupsService = &dryRunGroups{}
+	v.mergeRequests = &dryRunMergeRequests{reporter: r}
 }
 
 var _ VCS = &Gitlab{}
@@ -1,10 +1,15 @@
 package vcs
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/report"
 	"github.com/stretchr/testify/assert"
 	"github.com/xanzy/go-gitlab"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -16,9 +21,34 @@ func TestGitlab_Name(t *testing.T) {
 func TestGitlab_Configure(t *testing.T) {
 	vcs := &Gitlab{}
 
-	vcs.Configure()
+	vcs.Configure(report.Nop)
 	assert.NotNil(t, vcs.projectsService)
 	assert.NotNil(t, vcs.groupsService)
+	assert.NotNil(t, vcs.usersService)
+	assert.NotNil(t, vcs.membersService)
+}
+
+func TestGitlab_Configure_Retries_Server_Error(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"username":"user"}`)
+	}))
+	defer server.Close()
+	out := &bytes.Buffer{}
+	client := (&Gitlab{Token: "abc"}).client(report.NewText(out, true))
+	_ = client.SetBaseURL(server.URL)
+
+	user, _, err := client.Users.CurrentUser()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user", user.Username)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "\x1b[0m\x1b[97m\x1b[1mGitlab\x1b[0m\x1b[97m\x1b[39m \x1b[33manswered \x1b[39m\x1b[97m\x1b[1m503 Service Unavailable\x1b[0m\x1b[97m\x1b[39m\x1b[33m, retrying in \x1b[39m\x1b[97m\x1b[1m1s\x1b[0m\x1b[97m\x1b[39m \x1b[33m(attempt \x1b[39m\x1b[97m\x1b[1m2\x1b[0m\x1b[97m\x1b[39m \x1b[33mof \x1b[39m\x1b[97m\x1b[1m5\x1b[0m\x1b[97m\x1b[39m\x1b[33m)\x1b[39m\n\x1b[0m", out.String())
 }
 
 func TestGitlab_ValidateConfig_Ok(t *testing.T) {
@@ -40,7 +70,7 @@ func TestGitlab_Validate_Return_Error_If_Group_Not_Found(t *testing.T) {
 	groups := &mockGroups{err: errors.New("group not found")}
 	vcs := &Gitlab{Group: "group/sub", groupsService: groups}
 
-	err := vcs.Validate("project")
+	err := vcs.Validate(context.Background(), "project")
 
 	assert.Equal(t, "group/sub", groups.gid)
 	assert.EqualError(t, err, "group not found")
@@ -55,7 +85,7 @@ func TestGitlab_Validate_Unexpected_Error_From_Project(t *testing.T) {
 		},
 	}
 
-	err := vcs.Validate("project")
+	err := vcs.Validate(context.Background(), "project")
 
 	assert.EqualError(t, err, "unexpected")
 }
@@ -70,7 +100,7 @@ func TestGitlab_Validate_Project_Exists(t *testing.T) {
 		projectsService: projects,
 	}
 
-	err := vcs.Validate("project")
+	err := vcs.Validate(context.Background(), "project")
 
 	assert.Equal(t, "group/sub/project", projects.pid)
 	assert.EqualError(t, err, "project named 'group/sub/project' already exists at Gitlab")
@@ -88,7 +118,7 @@ func TestGitlab_Validate_Ok(t *testing.T) {
 		},
 	}
 
-	err := vcs.Validate("project")
+	err := vcs.Validate(context.Background(), "project")
 
 	assert.NoError(t, err)
 }
@@ -102,7 +132,7 @@ func TestGitlab_Scaffold_Return_Error_If_Group_Not_Found(t *testing.T) {
 		groupsService: groups,
 	}
 
-	_, err := vcs.Scaffold("project")
+	_, err := vcs.Scaffold(context.Background(), "project")
 
 	assert.Equal(t, "group/sub", groups.gid)
 	assert.EqualError(t, err, "group not found")
@@ -119,7 +149,7 @@ func TestGitlab_Scaffold_Return_Error_If_Create_Error(t *testing.T) {
 		projectsService: projects,
 	}
 
-	_, err := vcs.Scaffold("project")
+	_, err := vcs.Scaffold(context.Background(), "project")
 
 	visibility := gitlab.VisibilityValue("private")
 	expectedOpts := &gitlab.CreateProjectOptions{
@@ -156,7 +186,7 @@ func TestGitlab_Scaffold_Return_URLs_If_Create_Ok(t *testing.T) {
 		},
 	}
 
-	info, err := vcs.Scaffold("project")
+	info, err := vcs.Scaffold(context.Background(), "project")
 
 	assert.NoError(t, err)
 	assert.Equal(t, "git@gitlab.com:group/sub/project.git", info.SSHURL)
@@ -173,7 +203,7 @@ func TestGitlab_Webhook_Add_Error(t *testing.T) {
 		projectsService: projects,
 	}
 
-	err := vcs.Webhook("project", "https://example.org/hook")
+	err := vcs.Webhook(context.Background(), "project", "https://example.org/hook")
 
 	expectedOpts := &gitlab.AddProjectHookOptions{
 		URL:                 gitlab.String("https://example.org/hook"),
@@ -185,14 +215,139 @@ func TestGitlab_Webhook_Add_Error(t *testing.T) {
 	assert.EqualError(t, err, "hook error")
 }
 
+func TestGitlab_DeleteRepository(t *testing.T) {
+	projects := &mockProjects{deleteErr: errors.New("delete error")}
+	vcs := &Gitlab{Group: "org", projectsService: projects}
+
+	err := vcs.DeleteRepository(context.Background(), "reponame")
+
+	assert.EqualError(t, err, "delete error")
+	assert.Equal(t, "org/reponame", projects.pid)
+}
+
+func TestGitlab_DeleteWebhook_Without_Hook(t *testing.T) {
+	projects := &mockProjects{}
+	vcs := &Gitlab{Group: "org", projectsService: projects}
+
+	err := vcs.DeleteWebhook(context.Background(), "reponame")
+
+	assert.NoError(t, err)
+	assert.Nil(t, projects.pid)
+}
+
+func TestGitlab_DeleteWebhook(t *testing.T) {
+	projects := &mockProjects{hook: &gitlab.ProjectHook{ID: 17}}
+	vcs := &Gitlab{Group: "org", projectsService: projects}
+
+	assert.NoError(t, vcs.Webhook(context.Background(), "reponame", "https://example.org"))
+	err := vcs.DeleteWebhook(context.Background(), "reponame")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "org/reponame", projects.pid)
+	assert.Equal(t, 17, projects.deletedId)
+}
+
+func TestGitlab_Adopt(t *testing.T) {
+	vcs := &Gitlab{Group: "group"}
+
+	err := vcs.Adopt(&RepositoryInfo{HTTPURL: "https://gitlab.com/other/sub/project.git"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "other/sub", vcs.Group)
+}
+
+func TestGitlab_Check_Invalid_Token(t *testing.T) {
+	vcs := &Gitlab{usersService: &mockUsers{err: errors.New("unauthorized")}}
+
+	assert.Equal(t, []string{"token: unauthorized"}, checks(vcs))
+}
+
+func TestGitlab_Check_Missing_Group(t *testing.T) {
+	vcs := &Gitlab{
+		Group:         "group",
+		usersService:  &mockUsers{user: &gitlab.User{ID: 1, Username: "user"}},
+		groupsService: &mockGroups{err: errors.New("404 Group Not Found")},
+	}
+
+	assert.Equal(t, []string{"token: ok", "group 'group': 404 Group Not Found"}, checks(vcs))
+}
+
+func TestGitlab_Check_Developer(t *testing.T) {
+	members := &mockMembers{members: []*gitlab.GroupMember{
+		{ID: 2, Username: "username", AccessLevel: gitlab.OwnerPermissions},
+		{ID: 1, Username: "user", AccessLevel: gitlab.DeveloperPermissions},
+	}}
+	vcs := &Gitlab{
+		Group:          "group",
+		usersService:   &mockUsers{user: &gitlab.User{ID: 1, Username: "user"}},
+		groupsService:  &mockGroups{group: &gitlab.Group{ID: 123}},
+		membersService: members,
+	}
+
+	assert.Equal(t, []string{"token: ok", "group 'group': ok", "maintainer role: 'user' needs at least the maintainer role in 'group' to create projects and webhooks"}, checks(vcs))
+	assert.Equal(t, 123, members.gid)
+	assert.Equal(t, "user", *members.query)
+}
+
+func TestGitlab_Check_Maintainer(t *testing.T) {
+	vcs := &Gitlab{
+		Group:          "group",
+		usersService:   &mockUsers{user: &gitlab.User{ID: 1, Username: "user"}},
+		groupsService:  &mockGroups{group: &gitlab.Group{ID: 123}},
+		membersService: &mockMembers{members: []*gitlab.GroupMember{{ID: 1, Username: "user", AccessLevel: gitlab.MaintainerPermissions}}},
+	}
+
+	assert.Equal(t, []string{"token: ok", "group 'group': ok", "maintainer role: ok"}, checks(vcs))
+}
+
+func TestGitlab_Check_Members_Error(t *testing.T) {
+	vcs := &Gitlab{
+		Group:          "group",
+		usersService:   &mockUsers{user: &gitlab.User{ID: 1, Username: "user"}},
+		groupsService:  &mockGroups{group: &gitlab.Group{ID: 123}},
+		membersService: &mockMembers{err: errors.New("forbidden")},
+	}
+
+	assert.Equal(t, []string{"token: ok", "group 'group': ok", "maintainer role: forbidden"}, checks(vcs))
+}
+
+type mockUsers struct {
+	err  error
+	user *gitlab.User
+}
+
+func (m mockUsers) CurrentUser(options ...gitlab.OptionFunc) (*gitlab.User, *gitlab.Response, error) {
+	return m.user, nil, m.err
+}
+
+var _ usersService = &mockUsers{}
+
+type mockMembers struct {
+	err     error
+	gid     interface{}
+	query   *string
+	members []*gitlab.GroupMember
+}
+
+func (m *mockMembers) ListAllGroupMembers(gid interface{}, opt *gitlab.ListGroupMembersOptions, options ...gitlab.OptionFunc) ([]*gitlab.GroupMember, *gitlab.Response, error) {
+	m.gid = gid
+	m.query = opt.Query
+	return m.members, nil, m.err
+}
+
+var _ membersService = &mockMembers{}
+
 type mockProjects struct {
 	response   *gitlab.Response
 	getErr     error
 	createErr  error
 	hookErr    error
+	deleteErr  error
 	pid        interface{}
 	createOpts *gitlab.CreateProjectOptions
 	hookOpts   *gitlab.AddProjectHookOptions
+	hook       *gitlab.ProjectHook
+	deletedId  int
 	project    *gitlab.Project
 }
 
@@ -209,7 +364,18 @@ func (m *mockProjects) CreateProject(opt *gitlab.CreateProjectOptions, options .
 func (m *mockProjects) AddProjectHook(pid interface{}, opt *gitlab.AddProjectHookOptions, options ...gitlab.OptionFunc) (*gitlab.ProjectHook, *gitlab.Response, error) {
 	m.pid = pid
 	m.hookOpts = opt
-	return nil, m.response, m.hookErr
+	return m.hook, m.response, m.hookErr
+}
+
+func (m *mockProjects) DeleteProject(pid interface{}, options ...gitlab.OptionFunc) (*gitlab.Response, error) {
+	m.pid = pid
+	return m.response, m.deleteErr
+}
+
+func (m *mockProjects) DeleteProjectHook(pid interface{}, hook int, options ...gitlab.OptionFunc) (*gitlab.Response, error) {
+	m.pid = pid
+	m.deletedId = hook
+	return m.response, m.deleteErr
 }
 
 var _ projectsService = &mockProjects{}
@@ -226,3 +392,45 @@ func (m *mockGroups) GetGroup(gid interface{}, options ...gitlab.OptionFunc) (*g
 }
 
 var _ groupsService = &mockGroups{}
+
+func TestGitlab_PullRequest(t *testing.T) {
+	requests := &mockMergeRequests{request: &gitlab.MergeRequest{WebURL: "https://gitlab.com/group/project/merge_requests/1"}}
+	vcs := &Gitlab{Group: "group", mergeRequests: requests}
+
+	url, err := vcs.PullRequest(context.Background(), "project", "scaffold/initial", "Scaffold project", "body")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://gitlab.com/group/project/merge_requests/1", url)
+	assert.Equal(t, "group/project", requests.pid)
+	assert.Equal(t, &gitlab.CreateMergeRequestOptions{
+		Title:              gitlab.String("Scaffold project"),
+		Description:        gitlab.String("body"),
+		SourceBranch:       gitlab.String("scaffold/initial"),
+		TargetBranch:       gitlab.String("master"),
+		RemoveSourceBranch: gitlab.Bool(true),
+	}, requests.opts)
+}
+
+func TestGitlab_PullRequest_Error(t *testing.T) {
+	vcs := &Gitlab{Group: "group", mergeRequests: &mockMergeRequests{err: errors.New("branch missing")}}
+
+	url, err := vcs.PullRequest(context.Background(), "project", "scaffold/initial", "Scaffold project", "body")
+
+	assert.EqualError(t, err, "branch missing")
+	assert.Equal(t, "", url)
+}
+
+type mockMergeRequests struct {
+	err     error
+	pid     interface{}
+	opts    *gitlab.CreateMergeRequestOptions
+	request *gitlab.MergeRequest
+}
+
+func (m *mockMergeRequests) CreateMergeRequest(pid interface{}, opt *gitlab.CreateMergeRequestOptions, options ...gitlab.OptionFunc) (*gitlab.MergeRequest, *gitlab.Response, error) {
+	m.pid = pid
+	m.opts = opt
+	return m.request, nil, m.err
+}
+
+var _ mergeRequestsService = &mockMergeRequests{}
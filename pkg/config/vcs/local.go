@@ -0,0 +1,84 @@
+package vcs
+
+import (
+	"context"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/report"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"io"
+	"path"
+	"path/filepath"
+)
+
+// Local creates no repository at any provider, the project is initialised as a new git repository with Module as its origin
+type Local struct {
+	Git
+	Module string
+}
+
+func (v *Local) Name() string {
+	return "Local"
+}
+
+func (v *Local) ValidateConfig() error {
+	return nil
+}
+
+func (v *Local) Configure(r report.Reporter) {}
+
+func (v *Local) DryRun(r report.Reporter) {}
+
+func (v *Local) Validate(ctx context.Context, name string) error {
+	return nil
+}
+
+func (v *Local) Check(ctx context.Context, report func(check string, err error)) {}
+
+func (v *Local) Scaffold(ctx context.Context, name string) (*RepositoryInfo, error) {
+	module := v.Module
+	if module == "" {
+		module = path.Join("local", name)
+	}
+	repository, err := repositoryInfo("https://" + module)
+	if err != nil {
+		return nil, fmt.Errorf("invalid module path '%s'", module)
+	}
+	return repository, nil
+}
+
+func (v *Local) Adopt(repository *RepositoryInfo) error {
+	return nil
+}
+
+func (v *Local) Webhook(ctx context.Context, name, url string) error {
+	return nil
+}
+
+func (v *Local) DeleteRepository(ctx context.Context, name string) error {
+	return nil
+}
+
+func (v *Local) DeleteWebhook(ctx context.Context, name string) error {
+	return nil
+}
+
+func (v *Local) Clone(ctx context.Context, dir, name, url string, out io.Writer) error {
+	repo, err := git.PlainInit(filepath.Join(dir, name), false)
+	if err != nil {
+		return err
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{url}})
+	return err
+}
+
+// Push does nothing, the repository the origin points to does not exist yet
+func (v *Local) Push(ctx context.Context, dir, name string, out io.Writer) error {
+	return nil
+}
+
+func (v *Local) PullRequest(ctx context.Context, name, branch, title, body string) (string, error) {
+	return "", nil
+}
+
+var _ VCS = &Local{}
@@ -0,0 +1,56 @@
+package vcs
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	git2 "gopkg.in/src-d/go-git.v4"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocal_Scaffold_Default_Module(t *testing.T) {
+	repository, err := (&Local{}).Scaffold(context.Background(), "project")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &RepositoryInfo{SSHURL: "git@local:project.git", HTTPURL: "https://local/project.git"}, repository)
+}
+
+func TestLocal_Scaffold_Module(t *testing.T) {
+	repository, err := (&Local{Module: "github.com/org/project"}).Scaffold(context.Background(), "project")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &RepositoryInfo{SSHURL: "git@github.com:org/project.git", HTTPURL: "https://github.com/org/project.git"}, repository)
+}
+
+func TestLocal_Scaffold_Invalid_Module(t *testing.T) {
+	_, err := (&Local{Module: "%31"}).Scaffold(context.Background(), "project")
+
+	assert.EqualError(t, err, "invalid module path '%31'")
+}
+
+func TestLocal_Clone(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	err := (&Local{}).Clone(context.Background(), dir, "project", "git@github.com:org/project.git", &bytes.Buffer{})
+
+	assert.NoError(t, err)
+	repo, err := git2.PlainOpen(filepath.Join(dir, "project"))
+	assert.NoError(t, err)
+	remote, err := repo.Remote("origin")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"git@github.com:org/project.git"}, remote.Config().URLs)
+}
+
+func TestLocal_Clone_Existing_Repository(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "scaffold")
+	defer func() { _ = os.RemoveAll(dir) }()
+	_, _ = git2.PlainInit(filepath.Join(dir, "project"), false)
+
+	err := (&Local{}).Clone(context.Background(), dir, "project", "git@github.com:org/project.git", &bytes.Buffer{})
+
+	assert.EqualError(t, err, "repository already exists")
+}
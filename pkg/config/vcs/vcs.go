@@ -1,18 +1,33 @@
 package vcs
 
-import "io"
+import (
+	"context"
+	"github.com/buildtool/scaffold/pkg/report"
+	"io"
+)
 
 type VCS interface {
 	Name() string
 	ValidateConfig() error
-	Configure()
-	Validate(name string) error
-	Scaffold(name string) (*RepositoryInfo, error)
-	Webhook(name, url string) error
-	Clone(dir, name, url string, out io.Writer) error
+	Configure(r report.Reporter)
+	DryRun(r report.Reporter)
+	Validate(ctx context.Context, name string) error
+	Check(ctx context.Context, report func(check string, err error))
+	Scaffold(ctx context.Context, name string) (*RepositoryInfo, error)
+	Adopt(repository *RepositoryInfo) error
+	Webhook(ctx context.Context, name, url string) error
+	DeleteRepository(ctx context.Context, name string) error
+	DeleteWebhook(ctx context.Context, name string) error
+	Clone(ctx context.Context, dir, name, url string, out io.Writer) error
+	Commit(dir, name string, files []string, commit Commit) error
+	Push(ctx context.Context, dir, name string, out io.Writer) error
+	PullRequest(ctx context.Context, name, branch, title, body string) (string, error)
 }
 
+// InitialBranch is where the generated files are pushed to, they reach the protected default branch through a pull request
+const InitialBranch = "scaffold/initial"
+
 type RepositoryInfo struct {
-	SSHURL  string
-	HTTPURL string
+	SSHURL  string `yaml:"sshUrl"`
+	HTTPURL string `yaml:"httpUrl"`
 }
@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"github.com/buildtool/scaffold/pkg/config"
+	"github.com/buildtool/scaffold/pkg/config/ci"
+	"github.com/buildtool/scaffold/pkg/config/vcs"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/report"
+	"io"
+	"time"
+)
+
+type checker interface {
+	Name() string
+	Check(ctx context.Context, report func(check string, err error))
+}
+
+func Doctor(dir string, out io.Writer, args ...string) int {
+	var timeout time.Duration
+	var progress string
+	var selection config.Selection
+	set := newFlagSet("scaffold doctor", "", "Verifies tokens, organisation and group access and permissions of the configured VCS and CI without creating anything", out)
+	set.DurationVar(&timeout, "timeout", defaultTimeout, timeoutUsage)
+	set.StringVar(&progress, "progress", report.Auto, report.Usage)
+	selectionFlags(set, &selection)
+	if exitCode, ok := parseFlags(set, args); !ok {
+		return exitCode
+	}
+	r, err := report.New(out, progress)
+	if err != nil {
+		set.Usage()
+		return failure.Usage.ExitCode()
+	}
+	defer r.Done()
+	cfg, err := config.Load(dir, selection, r)
+	if err != nil {
+		err := failure.Wrap(failure.Config, "load-config", err)
+		r.Error(err)
+		return failure.ExitCode(err)
+	}
+	// Every configured provider is checked, unless one is chosen on the command line
+	vcss, cis := cfg.Providers()
+	if selection.VCS != "" {
+		vcss = []vcs.VCS{cfg.CurrentVCS}
+	}
+	if selection.CI != "" {
+		cis = []ci.CI{cfg.CurrentCI}
+	}
+	if err := configured(vcss, cis); err != nil {
+		err := failure.Wrap(failure.Config, "validate-config", err)
+		r.Error(err)
+		return failure.ExitCode(err)
+	}
+	if err := cfg.ResolveSecrets(vcss, cis); err != nil {
+		err := failure.Wrap(failure.Config, "validate-config", err)
+		r.Error(err)
+		return failure.ExitCode(err)
+	}
+	ctx, cancel := interruptible(timeout)
+	defer cancel()
+	return doctor(ctx, vcss, cis, r)
+}
+
+func configured(vcss []vcs.VCS, cis []ci.CI) error {
+	if len(vcss) == 0 {
+		return errors.New("no VCS configured")
+	}
+	if len(cis) == 0 {
+		return errors.New("no CI configured")
+	}
+	return nil
+}
+
+func doctor(ctx context.Context, vcss []vcs.VCS, cis []ci.CI, r report.Reporter) int {
+	var problems []error
+	reportCheck := func(check string, err error) {
+		if err != nil {
+			err := failure.Wrap(failure.Remote, check, err)
+			problems = append(problems, err)
+			r.Failed("  failed %s: %s", check, err.Error())
+			if err.Hint != "" {
+				r.Warning("    Hint: %s", err.Hint)
+			}
+			return
+		}
+		r.Succeeded("  ok %s", check)
+	}
+
+	for _, provider := range vcss {
+		provider.Configure(r)
+		check(ctx, provider, nil, r, reportCheck)
+	}
+	for _, provider := range cis {
+		check(ctx, provider, provider.Configure(r), r, reportCheck)
+	}
+
+	if len(problems) > 0 {
+		r.Failed("Found %d problem(s)", len(problems))
+		return failure.ExitCode(problems[0])
+	}
+	r.Succeeded("All checks passed")
+	return 0
+}
+
+func check(ctx context.Context, provider checker, configureErr error, r report.Reporter, report func(check string, err error)) {
+	r.Started("Checking '%s'", provider.Name())
+	if configureErr != nil {
+		report("configuration", failure.Wrap(failure.Config, "", configureErr))
+		return
+	}
+	provider.Check(ctx, report)
+}
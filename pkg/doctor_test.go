@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/buildtool/scaffold/pkg/config/ci"
+	"github.com/buildtool/scaffold/pkg/config/vcs"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDoctor_Ok(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	exitCode := doctor(context.Background(), []vcs.VCS{&mockVcs{}}, []ci.CI{&mockCi{}}, report.NewText(out, true))
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[94mChecking \x1b[39m\x1b[97m\x1b[1m'mock'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m  \x1b[32mok \x1b[39m\x1b[97m\x1b[1mtoken\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mChecking \x1b[39m\x1b[97m\x1b[1m'mockCi'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m  \x1b[32mok \x1b[39m\x1b[97m\x1b[1mtoken\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mAll checks passed\x1b[39m\n\x1b[0m", out.String())
+}
+
+func TestDoctor_Reports_All_Problems(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	exitCode := doctor(context.Background(), []vcs.VCS{&mockVcs{checkErr: errors.New("401 Bad credentials")}}, []ci.CI{&mockCi{configErr: errors.New("invalid token")}}, report.NewText(out, true))
+
+	assert.Equal(t, 6, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[94mChecking \x1b[39m\x1b[97m\x1b[1m'mock'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m  \x1b[31mfailed \x1b[39m\x1b[97m\x1b[1mtoken\x1b[0m\x1b[97m\x1b[39m\x1b[31m: \x1b[39m\x1b[97m\x1b[1m401 Bad credentials\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mChecking \x1b[39m\x1b[97m\x1b[1m'mockCi'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m  \x1b[31mfailed \x1b[39m\x1b[97m\x1b[1mconfiguration\x1b[0m\x1b[97m\x1b[39m\x1b[31m: \x1b[39m\x1b[97m\x1b[1minvalid token\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[31mFound \x1b[39m\x1b[97m\x1b[1m2\x1b[0m\x1b[97m\x1b[39m \x1b[31mproblem(s)\x1b[39m\n\x1b[0m", out.String())
+}
+
+func TestDoctor_Checks_Every_Provider(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	exitCode := doctor(context.Background(), []vcs.VCS{&mockVcs{}, &mockVcs{checkErr: errors.New("401 Bad credentials")}}, []ci.CI{&mockCi{}}, report.NewText(out, true))
+
+	assert.Equal(t, 6, exitCode)
+	assert.Equal(t, 3, strings.Count(out.String(), "Checking"))
+	assert.Contains(t, out.String(), "401 Bad credentials")
+}
+
+func TestDoctor_No_Color(t *testing.T) {
+	_ = os.Setenv("NO_COLOR", "1")
+	defer func() { _ = os.Unsetenv("NO_COLOR") }()
+	out := &bytes.Buffer{}
+
+	exitCode := doctor(context.Background(), []vcs.VCS{&mockVcs{checkErr: errors.New("401 Bad credentials")}}, []ci.CI{&mockCi{}}, report.NewText(out, report.Colored()))
+
+	assert.Equal(t, 6, exitCode)
+	assert.Equal(t, "Checking 'mock'\n  failed token: 401 Bad credentials\nChecking 'mockCi'\n  ok token\nFound 1 problem(s)\n", out.String())
+}
+
+func TestDoctor_Progress_Plain(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	exitCode := Doctor(name, out, "--progress", "plain")
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "no VCS configured\n", out.String())
+}
@@ -0,0 +1,38 @@
+package dryrun
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/file"
+	"github.com/buildtool/scaffold/pkg/report"
+	"gopkg.in/src-d/go-billy.v4"
+	"path/filepath"
+)
+
+func Request(r report.Reporter, method, url string, payload interface{}) {
+	r.Warning("Would call %s", method+" "+url)
+	if payload == nil {
+		return
+	}
+	if content, err := json.MarshalIndent(payload, "", "  "); err != nil {
+		_, _ = fmt.Fprintln(r.Output(), err.Error())
+	} else {
+		_, _ = fmt.Fprintln(r.Output(), string(content))
+	}
+}
+
+func Files(r report.Reporter, fs billy.Filesystem, dir string) error {
+	files, err := file.List(fs, "")
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		content, err := file.Read(fs, name)
+		if err != nil {
+			return err
+		}
+		r.Warning("Would write file '%s'", filepath.Join(dir, name))
+		_, _ = fmt.Fprint(r.Output(), content)
+	}
+	return nil
+}
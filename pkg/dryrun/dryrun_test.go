@@ -0,0 +1,49 @@
+package dryrun
+
+import (
+	"bytes"
+	"github.com/buildtool/scaffold/pkg/file"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"testing"
+)
+
+func TestRequest_Without_Payload(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	Request(report.NewText(out, true), "GET", "https://example.org/api", nil)
+
+	assert.Equal(t, "\x1b[0m\x1b[33mWould call \x1b[39m\x1b[97m\x1b[1mGET https://example.org/api\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", out.String())
+}
+
+func TestRequest_With_Payload(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	Request(report.NewText(out, true), "POST", "https://example.org/api", struct {
+		Name string `json:"name"`
+	}{Name: "project"})
+
+	assert.Equal(t, "\x1b[0m\x1b[33mWould call \x1b[39m\x1b[97m\x1b[1mPOST https://example.org/api\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m{\n  \"name\": \"project\"\n}\n", out.String())
+}
+
+func TestRequest_Unmarshallable_Payload(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	Request(report.NewText(out, true), "POST", "https://example.org/api", make(chan int))
+
+	assert.Equal(t, "\x1b[0m\x1b[33mWould call \x1b[39m\x1b[97m\x1b[1mPOST https://example.org/api\x1b[0m\x1b[97m\x1b[39m\n\x1b[0mjson: unsupported type: chan int\n", out.String())
+}
+
+func TestFiles(t *testing.T) {
+	fs := memfs.New()
+	_ = file.Write(fs, "b/c.txt", "c")
+	_ = file.Write(fs, "a.txt", "a")
+	_ = fs.MkdirAll("empty", 0777)
+	out := &bytes.Buffer{}
+
+	err := Files(report.NewText(out, true), fs, "/tmp/project")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "\x1b[0m\x1b[33mWould write file \x1b[39m\x1b[97m\x1b[1m'/tmp/project/a.txt'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0ma\n\x1b[0m\x1b[33mWould write file \x1b[39m\x1b[97m\x1b[1m'/tmp/project/b/c.txt'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0mc\n", out.String())
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"encoding/json"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/templating"
+	"io"
+	"time"
+)
+
+type Status string
+
+const (
+	Succeeded Status = "succeeded"
+	Failed    Status = "failed"
+	Skipped   Status = "skipped"
+)
+
+type Step struct {
+	Type       string             `json:"type"`
+	Name       string             `json:"name"`
+	Status     Status             `json:"status"`
+	DurationMs int64              `json:"durationMs"`
+	Resources  []string           `json:"resources,omitempty"`
+	Badges     []templating.Badge `json:"badges,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	ErrorKind  string             `json:"errorKind,omitempty"`
+	Provider   string             `json:"provider,omitempty"`
+	HTTPStatus int                `json:"httpStatus,omitempty"`
+	Hint       string             `json:"hint,omitempty"`
+	ExitCode   int                `json:"exitCode,omitempty"`
+}
+
+// Fail marks the step as failed with err and the exit code it maps to
+func (s *Step) Fail(err error) {
+	s.Status = Failed
+	s.Error = err.Error()
+	s.ExitCode = failure.ExitCode(err)
+	if e, ok := err.(*failure.Error); ok {
+		s.ErrorKind = e.Kind.String()
+		s.Provider = e.Provider
+		s.HTTPStatus = e.Status
+		s.Hint = e.Hint
+	} else {
+		s.ErrorKind = failure.Internal.String()
+	}
+}
+
+type Summary struct {
+	Type       string             `json:"type"`
+	Name       string             `json:"name"`
+	Stack      string             `json:"stack"`
+	Status     Status             `json:"status"`
+	DurationMs int64              `json:"durationMs"`
+	Resources  []string           `json:"resources,omitempty"`
+	Badges     []templating.Badge `json:"badges,omitempty"`
+	FailedStep string             `json:"failedStep,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	ErrorKind  string             `json:"errorKind,omitempty"`
+	Provider   string             `json:"provider,omitempty"`
+	HTTPStatus int                `json:"httpStatus,omitempty"`
+	Hint       string             `json:"hint,omitempty"`
+	ExitCode   int                `json:"exitCode"`
+}
+
+type Listener interface {
+	Step(step Step)
+	Finish(name, stack string, exitCode int)
+}
+
+var Nop Listener = nop{}
+
+type nop struct{}
+
+func (nop) Step(step Step) {}
+
+func (nop) Finish(name, stack string, exitCode int) {}
+
+type JSON struct {
+	encoder *json.Encoder
+	started time.Time
+	summary Summary
+}
+
+func NewJSON(out io.Writer) *JSON {
+	return &JSON{encoder: json.NewEncoder(out), started: now()}
+}
+
+func (j *JSON) Step(step Step) {
+	step.Type = "step"
+	j.summary.Resources = append(j.summary.Resources, step.Resources...)
+	j.summary.Badges = append(j.summary.Badges, step.Badges...)
+	if step.Status == Failed {
+		j.summary.FailedStep = step.Name
+		j.summary.Error = step.Error
+		j.summary.ErrorKind = step.ErrorKind
+		j.summary.Provider = step.Provider
+		j.summary.HTTPStatus = step.HTTPStatus
+		j.summary.Hint = step.Hint
+	}
+	_ = j.encoder.Encode(step)
+}
+
+func (j *JSON) Finish(name, stack string, exitCode int) {
+	summary := j.summary
+	summary.Type = "summary"
+	summary.Name = name
+	summary.Stack = stack
+	summary.Status = Succeeded
+	if exitCode != 0 {
+		summary.Status = Failed
+	}
+	summary.DurationMs = Since(j.started)
+	summary.ExitCode = exitCode
+	_ = j.encoder.Encode(summary)
+}
+
+var now = time.Now
+
+func Since(start time.Time) int64 {
+	return int64(now().Sub(start) / time.Millisecond)
+}
+
+var _ Listener = &JSON{}
@@ -0,0 +1,62 @@
+package events
+
+import (
+	"bytes"
+	"errors"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/templating"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestJSON_Step(t *testing.T) {
+	out := &bytes.Buffer{}
+	listener := NewJSON(out)
+
+	listener.Step(Step{Name: "repository", Status: Succeeded, DurationMs: 12, Resources: []string{"git@example.com:org/project.git"}})
+
+	assert.Equal(t, `{"type":"step","name":"repository","status":"succeeded","durationMs":12,"resources":["git@example.com:org/project.git"]}`+"\n", out.String())
+}
+
+func TestJSON_Finish_Succeeded(t *testing.T) {
+	start := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+	out := &bytes.Buffer{}
+	listener := NewJSON(out)
+	listener.Step(Step{Name: "repository", Status: Succeeded, Resources: []string{"git@example.com:org/project.git"}})
+	listener.Step(Step{Name: "badges", Status: Succeeded, Badges: []templating.Badge{{Title: "Build status", ImageUrl: "https://img", LinkUrl: "https://link"}}})
+	out.Reset()
+
+	now = func() time.Time { return start.Add(1500 * time.Millisecond) }
+	listener.Finish("project", "go", 0)
+
+	assert.Equal(t, `{"type":"summary","name":"project","stack":"go","status":"succeeded","durationMs":1500,"resources":["git@example.com:org/project.git"],"badges":[{"title":"Build status","imageUrl":"https://img","linkUrl":"https://link"}],"exitCode":0}`+"\n", out.String())
+}
+
+func TestJSON_Finish_Failed(t *testing.T) {
+	out := &bytes.Buffer{}
+	listener := NewJSON(out)
+	step := Step{Name: "pipeline"}
+	step.Fail(failure.API("Buildkite", 401, errors.New("token expired")))
+	listener.Step(step)
+	out.Reset()
+
+	listener.Finish("project", "none", 4)
+
+	assert.Equal(t, `{"type":"summary","name":"project","stack":"none","status":"failed","durationMs":0,"failedStep":"pipeline","error":"token expired","errorKind":"auth","provider":"Buildkite","httpStatus":401,"hint":"the token for Buildkite is invalid or has expired","exitCode":4}`+"\n", out.String())
+}
+
+func TestStep_Fail_Unclassified(t *testing.T) {
+	step := Step{Name: "readme"}
+
+	step.Fail(errors.New("error"))
+
+	assert.Equal(t, Step{Name: "readme", Status: Failed, Error: "error", ErrorKind: "internal", ExitCode: 1}, step)
+}
+
+func TestNop(t *testing.T) {
+	Nop.Step(Step{Name: "repository"})
+	Nop.Finish("project", "none", 0)
+}
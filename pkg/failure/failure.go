@@ -0,0 +1,138 @@
+package failure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/color"
+	"io"
+	"net/http"
+)
+
+// Kind classifies an error and decides the exit code scaffold terminates with
+type Kind int
+
+const (
+	Internal Kind = iota + 1
+	Usage
+	Config
+	Auth
+	Conflict
+	Remote
+	Filesystem
+	Incomplete
+	Interrupted
+)
+
+var kinds = map[Kind]string{
+	Internal:    "internal",
+	Usage:       "usage",
+	Config:      "config",
+	Auth:        "auth",
+	Conflict:    "conflict",
+	Remote:      "remote",
+	Filesystem:  "filesystem",
+	Incomplete:  "incomplete",
+	Interrupted: "interrupted",
+}
+
+func (k Kind) String() string {
+	if name, exists := kinds[k]; exists {
+		return name
+	}
+	return kinds[Internal]
+}
+
+func (k Kind) ExitCode() int {
+	if _, exists := kinds[k]; exists {
+		return int(k)
+	}
+	return int(Internal)
+}
+
+type Error struct {
+	Kind     Kind
+	Step     string
+	Provider string
+	Status   int
+	Hint     string
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap classifies err as kind unless it already is an *Error, in which case only a missing step is filled in
+func Wrap(kind Kind, step string, err error) *Error {
+	if e, ok := err.(*Error); ok {
+		wrapped := *e
+		if wrapped.Step == "" {
+			wrapped.Step = step
+		}
+		return &wrapped
+	}
+	if e := cancelled(err); e != nil {
+		e.Step = step
+		return e
+	}
+	return &Error{Kind: kind, Step: step, Err: err}
+}
+
+// API classifies an error returned by the API of provider from the HTTP status of its response, 0 if there was none
+func API(provider string, status int, err error) *Error {
+	if e := cancelled(err); e != nil {
+		e.Provider = provider
+		return e
+	}
+	e := &Error{Kind: Remote, Provider: provider, Status: status, Err: err}
+	switch {
+	case status == 0:
+		e.Hint = fmt.Sprintf("check the network connection to %s", provider)
+	case status == http.StatusUnauthorized:
+		e.Kind = Auth
+		e.Hint = fmt.Sprintf("the token for %s is invalid or has expired", provider)
+	case status == http.StatusForbidden:
+		e.Kind = Auth
+		e.Hint = fmt.Sprintf("the token for %s lacks the permissions needed", provider)
+	case status == http.StatusConflict, status == http.StatusUnprocessableEntity:
+		e.Kind = Conflict
+	case status >= http.StatusInternalServerError:
+		e.Hint = fmt.Sprintf("%s is having problems, try again later", provider)
+	}
+	return e
+}
+
+// cancelled classifies err if it was caused by an interrupt or the timeout, nil otherwise
+func cancelled(err error) *Error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return &Error{Kind: Interrupted, Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Kind: Remote, Hint: "it did not finish within --timeout, try again or increase it", Err: err}
+	}
+	return nil
+}
+
+// ExitCode returns the exit code err maps to, 0 if err is nil
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if e, ok := err.(*Error); ok {
+		return e.Kind.ExitCode()
+	}
+	return Internal.ExitCode()
+}
+
+// Print writes err and, if there is one, its remediation hint to out
+func Print(out io.Writer, err error) {
+	_, _ = fmt.Fprintln(out, color.Sprintf("<red>%s</red>", err.Error()))
+	if e, ok := err.(*Error); ok && e.Hint != "" {
+		_, _ = fmt.Fprintln(out, color.Sprintf("<yellow>Hint: %s</yellow>", e.Hint))
+	}
+}
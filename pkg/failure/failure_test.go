@@ -0,0 +1,116 @@
+package failure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestKind(t *testing.T) {
+	assert.Equal(t, "auth", Auth.String())
+	assert.Equal(t, 4, Auth.ExitCode())
+	assert.Equal(t, "internal", Kind(0).String())
+	assert.Equal(t, 1, Kind(0).ExitCode())
+}
+
+func TestExitCodes_Are_Stable(t *testing.T) {
+	assert.Equal(t, 1, Internal.ExitCode())
+	assert.Equal(t, 2, Usage.ExitCode())
+	assert.Equal(t, 3, Config.ExitCode())
+	assert.Equal(t, 4, Auth.ExitCode())
+	assert.Equal(t, 5, Conflict.ExitCode())
+	assert.Equal(t, 6, Remote.ExitCode())
+	assert.Equal(t, 7, Filesystem.ExitCode())
+	assert.Equal(t, 8, Incomplete.ExitCode())
+	assert.Equal(t, 9, Interrupted.ExitCode())
+}
+
+func TestWrap(t *testing.T) {
+	err := Wrap(Filesystem, "readme", errors.New("disk full"))
+
+	assert.EqualError(t, err, "disk full")
+	assert.Equal(t, &Error{Kind: Filesystem, Step: "readme", Err: errors.New("disk full")}, err)
+}
+
+func TestWrap_Keeps_Classification(t *testing.T) {
+	original := &Error{Kind: Auth, Provider: "Github", Err: errors.New("401")}
+
+	err := Wrap(Remote, "repository", original)
+
+	assert.Equal(t, &Error{Kind: Auth, Step: "repository", Provider: "Github", Err: errors.New("401")}, err)
+	assert.Equal(t, "", original.Step)
+}
+
+func TestWrap_Interrupted(t *testing.T) {
+	err := Wrap(Filesystem, "clone", context.Canceled)
+
+	assert.Equal(t, &Error{Kind: Interrupted, Step: "clone", Err: context.Canceled}, err)
+}
+
+func TestAPI_Timeout(t *testing.T) {
+	cause := &url.Error{Op: "Get", URL: "https://gitlab.com/api/v4/user", Err: context.DeadlineExceeded}
+
+	err := API("Gitlab", 0, cause)
+
+	assert.Equal(t, &Error{Kind: Remote, Provider: "Gitlab", Hint: "it did not finish within --timeout, try again or increase it", Err: cause}, err)
+}
+
+func TestAPI(t *testing.T) {
+	tests := []struct {
+		status int
+		kind   Kind
+		hint   string
+	}{
+		{0, Remote, "check the network connection to Github"},
+		{http.StatusUnauthorized, Auth, "the token for Github is invalid or has expired"},
+		{http.StatusForbidden, Auth, "the token for Github lacks the permissions needed"},
+		{http.StatusUnprocessableEntity, Conflict, ""},
+		{http.StatusConflict, Conflict, ""},
+		{http.StatusNotFound, Remote, ""},
+		{http.StatusBadGateway, Remote, "Github is having problems, try again later"},
+	}
+	for _, test := range tests {
+		err := API("Github", test.status, errors.New("error"))
+		assert.Equal(t, test.kind, err.Kind, "status %d", test.status)
+		assert.Equal(t, test.hint, err.Hint, "status %d", test.status)
+		assert.Equal(t, test.status, err.Status)
+		assert.Equal(t, "Github", err.Provider)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, 0, ExitCode(nil))
+	assert.Equal(t, 1, ExitCode(errors.New("error")))
+	assert.Equal(t, 5, ExitCode(&Error{Kind: Conflict, Err: errors.New("error")}))
+}
+
+func TestPrint(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	Print(out, &Error{Kind: Auth, Hint: "token lacks admin:repo_hook scope", Err: errors.New("404 Not Found")})
+
+	assert.Equal(t, "\x1b[0m\x1b[31m404 Not Found\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mHint: token lacks admin:repo_hook scope\x1b[39m\x1b[0m\n", out.String())
+}
+
+func TestPrint_Without_Hint(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	Print(out, errors.New("error"))
+
+	assert.Equal(t, "\x1b[0m\x1b[31merror\x1b[39m\x1b[0m\n", out.String())
+}
+
+func TestPrint_No_Color(t *testing.T) {
+	_ = os.Setenv("NO_COLOR", "1")
+	defer func() { _ = os.Unsetenv("NO_COLOR") }()
+	out := &bytes.Buffer{}
+
+	Print(out, &Error{Kind: Auth, Hint: "token lacks admin:repo_hook scope", Err: errors.New("404 Not Found")})
+
+	assert.Equal(t, "404 Not Found\nHint: token lacks admin:repo_hook scope\n", out.String())
+}
@@ -3,41 +3,74 @@ package file
 import (
 	"fmt"
 	"github.com/buildtool/scaffold/pkg/templating"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/util"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
-func Append(name, content string) error {
-	if f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+func Append(fs billy.Filesystem, name, content string) error {
+	if f, err := fs.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
 		return err
 	} else {
 		defer func() { _ = f.Close() }()
-		_, err := f.WriteString(fmt.Sprintf("\n%s\n", content))
+		_, err := f.Write([]byte(fmt.Sprintf("\n%s\n", content)))
 		return err
 	}
 }
 
-func AppendTemplated(name, template string, data templating.TemplateData) error {
+func AppendTemplated(fs billy.Filesystem, name, template string, data templating.TemplateData) error {
 	if content, err := templating.Execute(template, data); err != nil {
 		return err
 	} else {
-		return Append(name, content)
+		return Append(fs, name, content)
 	}
 }
 
-func Write(dir, file, content string) error {
-	if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0777); err != nil {
-		return err
-	}
-	return ioutil.WriteFile(filepath.Join(dir, file), []byte(fmt.Sprintln(strings.TrimSpace(content))), 0666)
+func Write(fs billy.Filesystem, file, content string) error {
+	return util.WriteFile(fs, file, []byte(fmt.Sprintln(strings.TrimSpace(content))), 0666)
 }
 
-func WriteTemplated(dir, file, template string, data templating.TemplateData) error {
+func WriteTemplated(fs billy.Filesystem, file, template string, data templating.TemplateData) error {
 	if content, err := templating.Execute(template, data); err != nil {
 		return err
 	} else {
-		return Write(dir, file, content)
+		return Write(fs, file, content)
+	}
+}
+
+func Read(fs billy.Filesystem, name string) (string, error) {
+	if f, err := fs.Open(name); err != nil {
+		return "", err
+	} else {
+		defer func() { _ = f.Close() }()
+		content, err := ioutil.ReadAll(f)
+		return string(content), err
+	}
+}
+
+// List returns the names of all files below dir in fs, sorted
+func List(fs billy.Filesystem, dir string) ([]string, error) {
+	infos, err := fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	var files []string
+	for _, info := range infos {
+		name := filepath.Join(dir, info.Name())
+		if info.IsDir() {
+			children, err := List(fs, name)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, children...)
+		} else {
+			files = append(files, name)
+		}
 	}
+	return files, nil
 }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/buildtool/scaffold/pkg/templating"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/osfs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -29,9 +31,22 @@ func teardown(tempDir string) {
 	_ = os.RemoveAll(tempDir)
 }
 
-func TestAppend_Return_Error_For_Missing_File(t *testing.T) {
-	err := Append(filepath.Join(name, "missing_dir/missing_file_XYZ"), "content")
-	assert.EqualError(t, err, fmt.Sprintf("open %s/missing_dir/missing_file_XYZ: no such file or directory", name))
+func TestAppend_Return_Error_For_Directory(t *testing.T) {
+	dir := filepath.Join(name, "existing_dir")
+	_ = os.MkdirAll(dir, 0777)
+	defer func() { _ = os.RemoveAll(dir) }()
+	err := Append(osfs.New(name), "existing_dir", "content")
+	assert.EqualError(t, err, fmt.Sprintf("open %s: is a directory", dir))
+}
+
+func TestAppend_Creates_Missing_File(t *testing.T) {
+	fileName := filepath.Join(name, "missing_dir", "missing_file_XYZ")
+	defer func() { _ = os.RemoveAll(filepath.Join(name, "missing_dir")) }()
+	err := Append(osfs.New(name), filepath.Join("missing_dir", "missing_file_XYZ"), "content")
+	assert.NoError(t, err)
+	bytes, err := ioutil.ReadFile(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, "\ncontent\n", string(bytes))
 }
 
 func TestAppend_Appends_To_Existing_File(t *testing.T) {
@@ -39,7 +54,7 @@ func TestAppend_Appends_To_Existing_File(t *testing.T) {
 	defer func() { _ = os.RemoveAll(fileName) }()
 	err := ioutil.WriteFile(fileName, []byte("abc"), 0777)
 	assert.NoError(t, err)
-	err = Append(fileName, "content")
+	err = Append(osfs.New(name), "file", "content")
 	assert.NoError(t, err)
 	bytes, err := ioutil.ReadFile(fileName)
 	assert.NoError(t, err)
@@ -51,7 +66,7 @@ func TestAppendTemplated_Return_Error_For_Broken_Template(t *testing.T) {
 	defer func() { _ = os.RemoveAll(fileName) }()
 	err := ioutil.WriteFile(fileName, []byte("abc"), 0777)
 	assert.NoError(t, err)
-	err = AppendTemplated(fileName, "--->{{.ProjectName }<---", templating.TemplateData{ProjectName: "ABC"})
+	err = AppendTemplated(osfs.New(name), "file", "--->{{.ProjectName }<---", templating.TemplateData{ProjectName: "ABC"})
 	assert.EqualError(t, err, `template: content:1: unexpected "}" in operand`)
 }
 
@@ -60,7 +75,7 @@ func TestAppendTemplated_Appends_To_Existing_File(t *testing.T) {
 	defer func() { _ = os.RemoveAll(fileName) }()
 	err := ioutil.WriteFile(fileName, []byte("abc"), 0777)
 	assert.NoError(t, err)
-	err = AppendTemplated(fileName, "--->{{.ProjectName }}<---", templating.TemplateData{ProjectName: "ABC"})
+	err = AppendTemplated(osfs.New(name), "file", "--->{{.ProjectName }}<---", templating.TemplateData{ProjectName: "ABC"})
 	assert.NoError(t, err)
 	bytes, err := ioutil.ReadFile(fileName)
 	assert.NoError(t, err)
@@ -70,24 +85,40 @@ func TestAppendTemplated_Appends_To_Existing_File(t *testing.T) {
 func TestWrite_Creates_All_Parent_Directories(t *testing.T) {
 	fileName := filepath.Join(name, "missing", "path", "file")
 	defer func() { _ = os.RemoveAll(fileName) }()
-	err := Write(name, filepath.Join("missing", "path", "file"), "abc")
+	err := Write(osfs.New(name), filepath.Join("missing", "path", "file"), "abc")
 	assert.NoError(t, err)
 	bytes, err := ioutil.ReadFile(fileName)
 	assert.NoError(t, err)
 	assert.Equal(t, "abc\n", string(bytes))
 }
 
+func TestWrite_In_Memory(t *testing.T) {
+	fs := memfs.New()
+	err := Write(fs, filepath.Join("missing", "path", "file"), "abc")
+	assert.NoError(t, err)
+	bytes, err := Read(fs, filepath.Join("missing", "path", "file"))
+	assert.NoError(t, err)
+	assert.Equal(t, "abc\n", bytes)
+	_, err = os.Stat(filepath.Join("missing", "path", "file"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRead_Missing_File(t *testing.T) {
+	_, err := Read(osfs.New(name), "unknown")
+	assert.EqualError(t, err, fmt.Sprintf("open %s/unknown: no such file or directory", name))
+}
+
 func TestWriteTemplated_Return_Error_For_Broken_Template(t *testing.T) {
 	fileName := filepath.Join(name, "missing", "path", "file")
 	defer func() { _ = os.RemoveAll(fileName) }()
-	err := WriteTemplated(name, filepath.Join("missing", "path", "file"), "{{ .ProjectName }", templating.TemplateData{ProjectName: "ABC"})
+	err := WriteTemplated(osfs.New(name), filepath.Join("missing", "path", "file"), "{{ .ProjectName }", templating.TemplateData{ProjectName: "ABC"})
 	assert.EqualError(t, err, `template: content:1: unexpected "}" in operand`)
 }
 
 func TestWriteTemplated(t *testing.T) {
 	fileName := filepath.Join(name, "missing", "path", "file")
 	defer func() { _ = os.RemoveAll(fileName) }()
-	err := WriteTemplated(name, filepath.Join("missing", "path", "file"), "--->{{ .ProjectName }}<---", templating.TemplateData{ProjectName: "ABC"})
+	err := WriteTemplated(osfs.New(name), filepath.Join("missing", "path", "file"), "--->{{ .ProjectName }}<---", templating.TemplateData{ProjectName: "ABC"})
 	assert.NoError(t, err)
 	bytes, err := ioutil.ReadFile(fileName)
 	assert.NoError(t, err)
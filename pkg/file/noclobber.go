@@ -0,0 +1,40 @@
+package file
+
+import (
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"os"
+	"path/filepath"
+)
+
+type noClobber struct {
+	billy.Filesystem
+	kept    func(name string)
+	discard billy.Filesystem
+	created map[string]bool
+}
+
+// NoClobber wraps fs so that files which already exist are kept instead of being overwritten, kept is called for each of them.
+// Appending to existing files is still allowed, and files created through the wrapper can be overwritten.
+func NoClobber(fs billy.Filesystem, kept func(name string)) billy.Filesystem {
+	return &noClobber{Filesystem: fs, kept: kept, discard: memfs.New(), created: make(map[string]bool)}
+}
+
+func (n *noClobber) Create(filename string) (billy.File, error) {
+	return n.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (n *noClobber) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	name := filepath.Clean(filename)
+	_, err := n.Filesystem.Stat(filename)
+	exists := err == nil
+	if exists && !n.created[name] && flag&os.O_APPEND == 0 && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		n.kept(filename)
+		return n.discard.OpenFile(filename, flag|os.O_CREATE, perm)
+	}
+	f, err := n.Filesystem.OpenFile(filename, flag, perm)
+	if err == nil && !exists && flag&os.O_CREATE != 0 {
+		n.created[name] = true
+	}
+	return f, err
+}
@@ -0,0 +1,55 @@
+package file
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"testing"
+)
+
+func TestNoClobber_Keeps_Existing_Files(t *testing.T) {
+	fs := memfs.New()
+	_ = Write(fs, "README.md", "existing")
+	var kept []string
+
+	noClobber := NoClobber(fs, func(name string) { kept = append(kept, name) })
+	assert.NoError(t, Write(noClobber, "README.md", "new"))
+	assert.NoError(t, Write(noClobber, ".gitignore", "new"))
+
+	content, _ := Read(fs, "README.md")
+	assert.Equal(t, "existing\n", content)
+	content, _ = Read(fs, ".gitignore")
+	assert.Equal(t, "new\n", content)
+	assert.Equal(t, []string{"README.md"}, kept)
+}
+
+func TestNoClobber_Appends_To_Existing_Files(t *testing.T) {
+	fs := memfs.New()
+	_ = Write(fs, ".dockerignore", ".git")
+
+	noClobber := NoClobber(fs, func(name string) { t.Errorf("unexpected kept %s", name) })
+	assert.NoError(t, Append(noClobber, ".dockerignore", ".buildkite"))
+
+	content, _ := Read(fs, ".dockerignore")
+	assert.Equal(t, ".git\n\n.buildkite\n", content)
+}
+
+func TestNoClobber_Overwrites_Created_Files(t *testing.T) {
+	fs := memfs.New()
+
+	noClobber := NoClobber(fs, func(name string) { t.Errorf("unexpected kept %s", name) })
+	assert.NoError(t, Append(noClobber, ".dockerignore", ".buildkite"))
+	assert.NoError(t, Write(noClobber, ".dockerignore", ".git"))
+
+	content, _ := Read(fs, ".dockerignore")
+	assert.Equal(t, ".git\n", content)
+}
+
+func TestNoClobber_Reads_Existing_Files(t *testing.T) {
+	fs := memfs.New()
+	_ = Write(fs, "go.mod", "module example")
+
+	content, err := Read(NoClobber(fs, func(name string) {}), "go.mod")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "module example\n", content)
+}
@@ -0,0 +1,29 @@
+package file
+
+import (
+	"gopkg.in/src-d/go-billy.v4"
+	"os"
+)
+
+type record struct {
+	billy.Filesystem
+	written func(name string)
+}
+
+// Record wraps fs so that written is called with the name of each file that is created or overwritten.
+// Appending to a file is not recorded.
+func Record(fs billy.Filesystem, written func(name string)) billy.Filesystem {
+	return &record{Filesystem: fs, written: written}
+}
+
+func (r *record) Create(filename string) (billy.File, error) {
+	return r.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (r *record) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	f, err := r.Filesystem.OpenFile(filename, flag, perm)
+	if err == nil && flag&os.O_APPEND == 0 && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		r.written(filename)
+	}
+	return f, err
+}
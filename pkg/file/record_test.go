@@ -0,0 +1,35 @@
+package file
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecord_Written_Files(t *testing.T) {
+	fs := memfs.New()
+	var written []string
+
+	record := Record(fs, func(name string) { written = append(written, name) })
+	assert.NoError(t, Write(record, "README.md", "content"))
+	assert.NoError(t, Write(record, filepath.Join("k8s", "deploy.yaml"), "content"))
+	assert.NoError(t, Append(record, ".dockerignore", ".buildkite"))
+	_, _ = Read(record, "README.md")
+
+	assert.Equal(t, []string{"README.md", filepath.Join("k8s", "deploy.yaml")}, written)
+	content, _ := Read(fs, "README.md")
+	assert.Equal(t, "content\n", content)
+}
+
+func TestList(t *testing.T) {
+	fs := memfs.New()
+	_ = Write(fs, "README.md", "")
+	_ = Write(fs, filepath.Join("k8s", "deploy.yaml"), "")
+	_ = Write(fs, ".gitignore", "")
+
+	files, err := List(fs, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".gitignore", "README.md", filepath.Join("k8s", "deploy.yaml")}, files)
+}
@@ -0,0 +1,38 @@
+package pkg
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// defaultTimeout bounds a run so that a provider that stops responding does not hang scaffold forever
+const defaultTimeout = 10 * time.Minute
+
+const timeoutUsage = "abort the calls to the providers when the command has not finished after this long, 0 waits forever"
+
+// interruptible returns the context of a command, it is cancelled on the first SIGINT or once timeout has passed unless it is 0.
+// A second SIGINT terminates scaffold right away.
+func interruptible(timeout time.Duration) (context.Context, context.CancelFunc) {
+	parent, interrupt := context.WithCancel(context.Background())
+	ctx, cancel := parent, interrupt
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, timeout)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			interrupt()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+		interrupt()
+	}
+}
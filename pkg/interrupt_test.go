@@ -0,0 +1,43 @@
+package pkg
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestInterruptible_Timeout(t *testing.T) {
+	ctx, cancel := interruptible(time.Millisecond)
+	defer cancel()
+
+	<-ctx.Done()
+
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+}
+
+func TestInterruptible_Without_Timeout(t *testing.T) {
+	ctx, cancel := interruptible(0)
+
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline)
+	assert.NoError(t, ctx.Err())
+	cancel()
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestInterruptible_Interrupt(t *testing.T) {
+	ctx, cancel := interruptible(time.Minute)
+	defer cancel()
+	process, _ := os.FindProcess(os.Getpid())
+
+	assert.NoError(t, process.Signal(os.Interrupt))
+
+	select {
+	case <-ctx.Done():
+		assert.Equal(t, context.Canceled, ctx.Err())
+	case <-time.After(5 * time.Second):
+		t.Fatal("context was not cancelled by the interrupt")
+	}
+}
@@ -0,0 +1,192 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	markerCurrent  = "<<<<<<< current"
+	markerSplit    = "======="
+	markerTemplate = ">>>>>>> template"
+)
+
+// ThreeWay merges the changes from base to theirs into ours, line by line.
+// Changes to the same lines on both sides are kept with conflict markers, the second result is false if there were any.
+func ThreeWay(base, ours, theirs string) (string, bool) {
+	b, o, t := lines(base), lines(ours), lines(theirs)
+	toOurs, toTheirs := matches(b, o), matches(b, t)
+
+	var result []string
+	clean := true
+	i, j, k := 0, 0, 0
+	for i < len(b) || j < len(o) || k < len(t) {
+		for i < len(b) && toOurs[i] == j && toTheirs[i] == k {
+			result = append(result, b[i])
+			i, j, k = i+1, j+1, k+1
+		}
+		if i == len(b) && j == len(o) && k == len(t) {
+			break
+		}
+		ni, nj, nk := len(b), len(o), len(t)
+		for n := i; n < len(b); n++ {
+			if toOurs[n] >= j && toTheirs[n] >= k {
+				ni, nj, nk = n, toOurs[n], toTheirs[n]
+				break
+			}
+		}
+		baseChunk, oursChunk, theirsChunk := b[i:ni], o[j:nj], t[k:nk]
+		switch {
+		case equal(oursChunk, baseChunk):
+			result = append(result, theirsChunk...)
+		case equal(theirsChunk, baseChunk), equal(oursChunk, theirsChunk):
+			result = append(result, oursChunk...)
+		default:
+			clean = false
+			result = append(result, markerCurrent)
+			result = append(result, oursChunk...)
+			result = append(result, markerSplit)
+			result = append(result, theirsChunk...)
+			result = append(result, markerTemplate)
+		}
+		i, j, k = ni, nj, nk
+	}
+	return join(result), clean
+}
+
+// Unified returns the differences between a and b in unified format with three lines of context, or an empty string if there are none
+func Unified(a, b, nameA, nameB string) string {
+	x, y := lines(a), lines(b)
+	toY := matches(x, y)
+
+	type edit struct {
+		op   byte
+		line string
+	}
+	var edits []edit
+	j := 0
+	for i := 0; i < len(x); i++ {
+		if toY[i] < 0 {
+			edits = append(edits, edit{'-', x[i]})
+			continue
+		}
+		for ; j < toY[i]; j++ {
+			edits = append(edits, edit{'+', y[j]})
+		}
+		edits = append(edits, edit{' ', x[i]})
+		j++
+	}
+	for ; j < len(y); j++ {
+		edits = append(edits, edit{'+', y[j]})
+	}
+
+	const context = 3
+	sb := &strings.Builder{}
+	for start := 0; start < len(edits); {
+		if edits[start].op == ' ' {
+			start++
+			continue
+		}
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+		to := start
+		for n := start; n < len(edits) && n <= to+2*context; n++ {
+			if edits[n].op != ' ' {
+				to = n
+			}
+		}
+		to += context + 1
+		if to > len(edits) {
+			to = len(edits)
+		}
+		lineA, lineB := 1, 1
+		for _, e := range edits[:from] {
+			if e.op != '+' {
+				lineA++
+			}
+			if e.op != '-' {
+				lineB++
+			}
+		}
+		countA, countB := 0, 0
+		for _, e := range edits[from:to] {
+			if e.op != '+' {
+				countA++
+			}
+			if e.op != '-' {
+				countB++
+			}
+		}
+		if sb.Len() == 0 {
+			_, _ = fmt.Fprintf(sb, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		_, _ = fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", lineA, countA, lineB, countB)
+		for _, e := range edits[from:to] {
+			_, _ = fmt.Fprintf(sb, "%c%s\n", e.op, e.line)
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+// matches returns, for each line of a, the index of the line of b it is paired with in a longest common subsequence, or -1
+func matches(a, b []string) []int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	result := make([]int, len(a))
+	i, j := 0, 0
+	for i < len(a) {
+		switch {
+		case j < len(b) && a[i] == b[j]:
+			result[i] = j
+			i, j = i+1, j+1
+		case j < len(b) && lengths[i+1][j] < lengths[i][j+1]:
+			j++
+		default:
+			result[i] = -1
+			i++
+		}
+	}
+	return result
+}
+
+func lines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+func join(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package merge
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const base = `apiVersion: apps/v1
+kind: Deployment
+spec:
+  replicas: 2
+  template:
+    image: registry/app
+`
+
+func TestThreeWay_Only_Template_Changed(t *testing.T) {
+	theirs := "apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 3\n  template:\n    image: registry/app\n"
+
+	merged, clean := ThreeWay(base, base, theirs)
+
+	assert.True(t, clean)
+	assert.Equal(t, theirs, merged)
+}
+
+func TestThreeWay_Only_Current_Changed(t *testing.T) {
+	ours := "apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 5\n  template:\n    image: registry/app\n"
+
+	merged, clean := ThreeWay(base, ours, base)
+
+	assert.True(t, clean)
+	assert.Equal(t, ours, merged)
+}
+
+func TestThreeWay_Changes_In_Different_Places(t *testing.T) {
+	ours := "apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 5\n  template:\n    image: registry/app\n"
+	theirs := "apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 2\n  template:\n    image: registry/app\n    imagePullPolicy: Always\n"
+
+	merged, clean := ThreeWay(base, ours, theirs)
+
+	assert.True(t, clean)
+	assert.Equal(t, "apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 5\n  template:\n    image: registry/app\n    imagePullPolicy: Always\n", merged)
+}
+
+func TestThreeWay_Same_Change_On_Both_Sides(t *testing.T) {
+	changed := "apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 3\n  template:\n    image: registry/app\n"
+
+	merged, clean := ThreeWay(base, changed, changed)
+
+	assert.True(t, clean)
+	assert.Equal(t, changed, merged)
+}
+
+func TestThreeWay_Conflict(t *testing.T) {
+	ours := "apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 5\n  template:\n    image: registry/app\n"
+	theirs := "apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 3\n  template:\n    image: registry/app\n"
+
+	merged, clean := ThreeWay(base, ours, theirs)
+
+	assert.False(t, clean)
+	assert.Equal(t, "apiVersion: apps/v1\nkind: Deployment\nspec:\n<<<<<<< current\n  replicas: 5\n=======\n  replicas: 3\n>>>>>>> template\n  template:\n    image: registry/app\n", merged)
+}
+
+func TestThreeWay_Empty_Base(t *testing.T) {
+	merged, clean := ThreeWay("", "a\n", "b\n")
+
+	assert.False(t, clean)
+	assert.Equal(t, "<<<<<<< current\na\n=======\nb\n>>>>>>> template\n", merged)
+}
+
+func TestUnified_No_Differences(t *testing.T) {
+	assert.Equal(t, "", Unified(base, base, "a", "b"))
+}
+
+func TestUnified(t *testing.T) {
+	changed := "apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 3\n  template:\n    image: registry/app\n"
+
+	diff := Unified(base, changed, "current/k8s/deploy.yaml", "template/k8s/deploy.yaml")
+
+	assert.Equal(t, `--- current/k8s/deploy.yaml
++++ template/k8s/deploy.yaml
+@@ -1,6 +1,6 @@
+ apiVersion: apps/v1
+ kind: Deployment
+ spec:
+-  replicas: 2
++  replicas: 3
+   template:
+     image: registry/app
+`, diff)
+}
+
+func TestUnified_Separate_Hunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n"
+
+	diff := Unified(a, b, "a", "b")
+
+	assert.Equal(t, `--- a
++++ b
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -9,4 +9,4 @@
+ 9
+ 10
+ 11
+-12
++twelve
+`, diff)
+}
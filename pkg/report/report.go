@@ -0,0 +1,144 @@
+package report
+
+import (
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/color"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Reporter prints the progress of a command. The arguments of the messages are highlighted when printed in colour.
+type Reporter interface {
+	Started(format string, args ...interface{})
+	Succeeded(format string, args ...interface{})
+	Created(resource, name string)
+	Failed(format string, args ...interface{})
+	Warning(format string, args ...interface{})
+	Info(format string, args ...interface{})
+	Error(err error)
+	// Output is where everything but messages is written, like the progress of git and the content of files
+	Output() io.Writer
+	// Colored tells if the messages are printed in colour, for output that is written as it is
+	Colored() bool
+	// Done must be called once nothing more is reported
+	Done()
+}
+
+const (
+	Auto  = "auto"
+	TTY   = "tty"
+	Plain = "plain"
+	Quiet = "quiet"
+)
+
+const Usage = "how progress is printed, auto, tty, plain or quiet (auto shows a spinner on a terminal, NO_COLOR turns colours off)"
+
+var isTerminal = terminal
+
+func terminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// Colored tells if colours are wanted, they are unless NO_COLOR is set
+func Colored() bool {
+	return color.Enabled()
+}
+
+// New returns the reporter for mode, auto prints lines in colour unless out is a terminal
+func New(out io.Writer, mode string) (Reporter, error) {
+	switch mode {
+	case Auto:
+		if isTerminal(out) {
+			return NewTerminal(out, Colored()), nil
+		}
+		return NewText(out, Colored()), nil
+	case TTY:
+		return NewTerminal(out, Colored()), nil
+	case Plain:
+		return NewText(out, false), nil
+	case Quiet:
+		return NewQuiet(out), nil
+	}
+	return nil, fmt.Errorf("unknown progress '%s', use one of auto, tty, plain or quiet", mode)
+}
+
+// quiet only prints failures
+type quiet struct {
+	text *Text
+}
+
+func NewQuiet(out io.Writer) Reporter {
+	return &quiet{text: NewText(out, Colored())}
+}
+
+// Buffered returns a reporter that prints to out like r, a line per message as out is not a terminal
+func Buffered(r Reporter, out io.Writer) Reporter {
+	if _, ok := r.(*quiet); ok {
+		return &quiet{text: NewText(out, r.Colored())}
+	}
+	return NewText(out, r.Colored())
+}
+
+func (q *quiet) Started(format string, args ...interface{}) {}
+
+func (q *quiet) Succeeded(format string, args ...interface{}) {}
+
+func (q *quiet) Created(resource, name string) {}
+
+func (q *quiet) Failed(format string, args ...interface{}) {
+	q.text.Failed(format, args...)
+}
+
+func (q *quiet) Warning(format string, args ...interface{}) {}
+
+func (q *quiet) Info(format string, args ...interface{}) {}
+
+func (q *quiet) Error(err error) {
+	q.text.Error(err)
+}
+
+func (q *quiet) Output() io.Writer {
+	return ioutil.Discard
+}
+
+func (q *quiet) Colored() bool {
+	return q.text.Colored()
+}
+
+func (q *quiet) Done() {}
+
+var Nop Reporter = nop{}
+
+type nop struct{}
+
+func (nop) Started(format string, args ...interface{}) {}
+
+func (nop) Succeeded(format string, args ...interface{}) {}
+
+func (nop) Created(resource, name string) {}
+
+func (nop) Failed(format string, args ...interface{}) {}
+
+func (nop) Warning(format string, args ...interface{}) {}
+
+func (nop) Info(format string, args ...interface{}) {}
+
+func (nop) Error(err error) {}
+
+func (nop) Output() io.Writer {
+	return ioutil.Discard
+}
+
+func (nop) Colored() bool {
+	return false
+}
+
+func (nop) Done() {}
+
+var _ Reporter = &quiet{}
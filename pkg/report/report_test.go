@@ -0,0 +1,149 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	r, err := New(out, Auto)
+	assert.NoError(t, err)
+	assert.Equal(t, NewText(out, true), r)
+
+	r, err = New(out, TTY)
+	assert.NoError(t, err)
+	assert.Equal(t, NewTerminal(out, true), r)
+
+	r, err = New(out, Plain)
+	assert.NoError(t, err)
+	assert.Equal(t, NewText(out, false), r)
+
+	r, err = New(out, Quiet)
+	assert.NoError(t, err)
+	assert.Equal(t, NewQuiet(out), r)
+}
+
+func TestNew_Unknown(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "fancy")
+
+	assert.EqualError(t, err, "unknown progress 'fancy', use one of auto, tty, plain or quiet")
+}
+
+func TestNew_Auto_Terminal(t *testing.T) {
+	isTerminal = func(io.Writer) bool { return true }
+	defer func() { isTerminal = terminal }()
+	out := &bytes.Buffer{}
+
+	r, err := New(out, Auto)
+
+	assert.NoError(t, err)
+	assert.Equal(t, NewTerminal(out, true), r)
+}
+
+func TestNew_No_Color(t *testing.T) {
+	_ = os.Setenv("NO_COLOR", "1")
+	defer func() { _ = os.Unsetenv("NO_COLOR") }()
+	out := &bytes.Buffer{}
+
+	r, err := New(out, Auto)
+	assert.NoError(t, err)
+	assert.Equal(t, NewText(out, false), r)
+
+	r, err = New(out, TTY)
+	assert.NoError(t, err)
+	assert.Equal(t, NewTerminal(out, false), r)
+}
+
+func TestText_Color(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := NewText(out, true)
+
+	r.Started("Creating new service '%s' using stack '%s'", "project", "go")
+	r.Succeeded("Opened pull request '%s'", "https://example.org/1")
+	r.Created("repository", "git@example.org:org/project.git")
+	r.Failed("Provided stack does not exist yet. Available stacks are: %s", "(go, none)")
+	r.Warning("Would commit %d files with 100%% certainty", 3)
+	r.Info("Parsing config from file: '%s'", "/tmp/.scaffold.yaml")
+
+	assert.Equal(t, "\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'go'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m"+
+		"\x1b[0m\x1b[32mOpened pull request \x1b[39m\x1b[97m\x1b[1m'https://example.org/1'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m"+
+		"\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'git@example.org:org/project.git'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m"+
+		"\x1b[0m\x1b[31mProvided stack does not exist yet. Available stacks are: \x1b[39m\x1b[97m\x1b[1m(go, none)\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m"+
+		"\x1b[0m\x1b[33mWould commit \x1b[39m\x1b[97m\x1b[1m3\x1b[0m\x1b[97m\x1b[39m \x1b[33mfiles with 100% certainty\x1b[39m\n\x1b[0m"+
+		"\x1b[0mParsing config from file: \x1b[32m'/tmp/.scaffold.yaml'\x1b[39m\x1b[0m\n", out.String())
+}
+
+func TestText_Plain(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := NewText(out, false)
+
+	r.Started("Creating new service '%s' using stack '%s'", "project", "go")
+	r.Created("repository", "git@example.org:org/project.git")
+	r.Warning("Would commit %d files", 3)
+	r.Info("Parsing config from file: '%s'", "/tmp/.scaffold.yaml")
+	r.Error(&failure.Error{Kind: failure.Auth, Hint: "check the token", Err: errors.New("401 Unauthorized")})
+	r.Done()
+
+	assert.Equal(t, "Creating new service 'project' using stack 'go'\nCreated repository 'git@example.org:org/project.git'\nWould commit 3 files\nParsing config from file: '/tmp/.scaffold.yaml'\n401 Unauthorized\nHint: check the token\n", out.String())
+	assert.Equal(t, out, r.Output())
+}
+
+func TestText_Error(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	NewText(out, true).Error(&failure.Error{Kind: failure.Auth, Hint: "check the token", Err: errors.New("401 Unauthorized")})
+
+	assert.Equal(t, "\x1b[0m\x1b[31m401 Unauthorized\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mHint: check the token\x1b[39m\x1b[0m\n", out.String())
+}
+
+func TestQuiet(t *testing.T) {
+	_ = os.Setenv("NO_COLOR", "1")
+	defer func() { _ = os.Unsetenv("NO_COLOR") }()
+	out := &bytes.Buffer{}
+	r := NewQuiet(out)
+
+	r.Started("Creating repository at '%s'", "Github")
+	r.Succeeded("Opened pull request '%s'", "https://example.org/1")
+	r.Created("repository", "git@example.org:org/project.git")
+	r.Warning("Removing %s", "webhook")
+	r.Info("Parsing config from file: '%s'", "/tmp/.scaffold.yaml")
+	r.Failed("Provided stack does not exist yet. Available stacks are: %s", "(go, none)")
+	r.Error(errors.New("network down"))
+	r.Done()
+
+	assert.Equal(t, "Provided stack does not exist yet. Available stacks are: (go, none)\nnetwork down\n", out.String())
+	assert.Equal(t, ioutil.Discard, r.Output())
+}
+
+func TestNop(t *testing.T) {
+	Nop.Started("Creating repository at '%s'", "Github")
+	Nop.Succeeded("Opened pull request '%s'", "https://example.org/1")
+	Nop.Created("repository", "git@example.org:org/project.git")
+	Nop.Failed("failed")
+	Nop.Warning("Removing %s", "webhook")
+	Nop.Info("Parsing config from file: '%s'", "/tmp/.scaffold.yaml")
+	Nop.Error(errors.New("network down"))
+	Nop.Done()
+
+	assert.Equal(t, ioutil.Discard, Nop.Output())
+}
+
+func TestSegments(t *testing.T) {
+	assert.Equal(t, []segment{
+		{text: "Would clone "},
+		{text: "'a'", arg: true},
+		{text: " into "},
+		{text: "b", arg: true},
+		{text: " at 50% of "},
+		{text: "3", arg: true},
+		{text: "'"},
+	}, segments("Would clone '%s' into %s at 50%% of %d'", []interface{}{"a", "b", 3}))
+}
@@ -0,0 +1,154 @@
+package report
+
+import (
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/liamg/tml"
+	"io"
+	"strings"
+)
+
+// Text prints every message on a line of its own, in colour or as plain text
+type Text struct {
+	out   io.Writer
+	color bool
+}
+
+func NewText(out io.Writer, color bool) *Text {
+	return &Text{out: out, color: color}
+}
+
+func (t *Text) Started(format string, args ...interface{}) {
+	t.print("lightblue", format, args)
+}
+
+func (t *Text) Succeeded(format string, args ...interface{}) {
+	t.print("green", format, args)
+}
+
+func (t *Text) Created(resource, name string) {
+	t.Succeeded("Created "+resource+" '%s'", name)
+}
+
+func (t *Text) Failed(format string, args ...interface{}) {
+	t.print("red", format, args)
+}
+
+func (t *Text) Warning(format string, args ...interface{}) {
+	t.print("yellow", format, args)
+}
+
+// Info prints the text as is and the arguments in green
+func (t *Text) Info(format string, args ...interface{}) {
+	if !t.color {
+		_, _ = fmt.Fprintln(t.out, fmt.Sprintf(format, args...))
+		return
+	}
+	template := ""
+	var values []interface{}
+	for _, s := range segments(format, args) {
+		if s.arg {
+			template += "<green>%s</green>"
+			values = append(values, s.text)
+		} else {
+			template += escape(s.text)
+		}
+	}
+	_, _ = fmt.Fprintln(t.out, tml.Sprintf(template, values...))
+}
+
+func (t *Text) Error(err error) {
+	if !t.color {
+		_, _ = fmt.Fprintln(t.out, err.Error())
+		if e, ok := err.(*failure.Error); ok && e.Hint != "" {
+			_, _ = fmt.Fprintln(t.out, "Hint: "+e.Hint)
+		}
+		return
+	}
+	failure.Print(t.out, err)
+}
+
+func (t *Text) Output() io.Writer {
+	return t.out
+}
+
+func (t *Text) Colored() bool {
+	return t.color
+}
+
+func (t *Text) Done() {}
+
+// print writes the text in color and the arguments in bold
+func (t *Text) print(color, format string, args []interface{}) {
+	if !t.color {
+		_, _ = fmt.Fprintln(t.out, fmt.Sprintf(format, args...))
+		return
+	}
+	template := ""
+	var values []interface{}
+	for _, s := range segments(format, args) {
+		if s.arg {
+			template += "<white><bold>%s</bold></white>"
+			values = append(values, s.text)
+			continue
+		}
+		text := strings.TrimLeft(s.text, " ")
+		template += s.text[:len(s.text)-len(text)]
+		if text != "" {
+			template += fmt.Sprintf("<%s>%s</%s>", color, escape(text), color)
+		}
+	}
+	_, _ = fmt.Fprint(t.out, tml.Sprintf(template+"\n", values...))
+}
+
+func escape(text string) string {
+	return strings.Replace(text, "%", "%%", -1)
+}
+
+type segment struct {
+	text string
+	arg  bool
+}
+
+// segments splits format into the text around the verbs and the formatted arguments, quotes around a verb belong to the argument
+func segments(format string, args []interface{}) []segment {
+	var result []segment
+	text := ""
+	next := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			text += string(format[i])
+			continue
+		}
+		if format[i+1] == '%' {
+			text += "%"
+			i++
+			continue
+		}
+		verb := format[i : i+2]
+		quoted := strings.HasSuffix(text, "'") && i+2 < len(format) && format[i+2] == '\''
+		i++
+		var value interface{}
+		if next < len(args) {
+			value = args[next]
+			next++
+		}
+		arg := fmt.Sprintf(verb, value)
+		if quoted {
+			text = strings.TrimSuffix(text, "'")
+			arg = "'" + arg + "'"
+			i++
+		}
+		if text != "" {
+			result = append(result, segment{text: text})
+			text = ""
+		}
+		result = append(result, segment{text: arg, arg: true})
+	}
+	if text != "" {
+		result = append(result, segment{text: text})
+	}
+	return result
+}
+
+var _ Reporter = &Text{}
@@ -0,0 +1,113 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var frames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const interval = 100 * time.Millisecond
+
+// Terminal shows a spinner next to the step in progress, the step is printed like Text once something else is reported
+type Terminal struct {
+	*Text
+	mutex   sync.Mutex
+	started func()
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func NewTerminal(out io.Writer, color bool) *Terminal {
+	return &Terminal{Text: NewText(out, color)}
+}
+
+func (t *Terminal) Started(format string, args ...interface{}) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.settle()
+	t.started = func() { t.Text.Started(format, args...) }
+	t.stop = make(chan struct{})
+	t.stopped = make(chan struct{})
+	go t.spin(fmt.Sprintf(format, args...), t.stop, t.stopped)
+}
+
+func (t *Terminal) Succeeded(format string, args ...interface{}) {
+	t.report(func() { t.Text.Succeeded(format, args...) })
+}
+
+func (t *Terminal) Created(resource, name string) {
+	t.report(func() { t.Text.Created(resource, name) })
+}
+
+func (t *Terminal) Failed(format string, args ...interface{}) {
+	t.report(func() { t.Text.Failed(format, args...) })
+}
+
+func (t *Terminal) Warning(format string, args ...interface{}) {
+	t.report(func() { t.Text.Warning(format, args...) })
+}
+
+func (t *Terminal) Info(format string, args ...interface{}) {
+	t.report(func() { t.Text.Info(format, args...) })
+}
+
+func (t *Terminal) Error(err error) {
+	t.report(func() { t.Text.Error(err) })
+}
+
+func (t *Terminal) Output() io.Writer {
+	return output{t}
+}
+
+func (t *Terminal) Done() {
+	t.report(func() {})
+}
+
+func (t *Terminal) report(print func()) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.settle()
+	print()
+}
+
+// settle stops the spinner and prints the step it was shown for
+func (t *Terminal) settle() {
+	if t.started == nil {
+		return
+	}
+	close(t.stop)
+	<-t.stopped
+	_, _ = fmt.Fprint(t.out, "\r\x1b[2K")
+	t.started()
+	t.started = nil
+}
+
+func (t *Terminal) spin(text string, stop, stopped chan struct{}) {
+	defer close(stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 0; ; i++ {
+		_, _ = fmt.Fprintf(t.out, "\r%s %s", frames[i%len(frames)], text)
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type output struct {
+	t *Terminal
+}
+
+func (p output) Write(b []byte) (int, error) {
+	p.t.mutex.Lock()
+	defer p.t.mutex.Unlock()
+	p.t.settle()
+	return p.t.out.Write(b)
+}
+
+var _ Reporter = &Terminal{}
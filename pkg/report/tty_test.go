@@ -0,0 +1,57 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestTerminal_Spinner(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := NewTerminal(out, false)
+
+	r.Started("Cloning '%s'", "project")
+	r.Created("repository", "git@example.org:org/project.git")
+	r.Done()
+
+	assert.True(t, strings.HasPrefix(out.String(), "\r⠋ Cloning 'project'"), out.String())
+	assert.True(t, strings.HasSuffix(out.String(), "\r\x1b[2KCloning 'project'\nCreated repository 'git@example.org:org/project.git'\n"), out.String())
+}
+
+func TestTerminal_Started_Twice(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := NewTerminal(out, false)
+
+	r.Started("Committing")
+	r.Started("Pushing")
+	r.Error(errors.New("rejected"))
+
+	assert.Equal(t, 2, strings.Count(out.String(), "\r\x1b[2K"))
+	assert.True(t, strings.Contains(out.String(), "\r\x1b[2KCommitting\n\r⠋ Pushing"), out.String())
+	assert.True(t, strings.HasSuffix(out.String(), "\r\x1b[2KPushing\nrejected\n"), out.String())
+}
+
+func TestTerminal_Output_Stops_Spinner(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := NewTerminal(out, false)
+
+	r.Started("Cloning")
+	_, _ = fmt.Fprint(r.Output(), "Counting objects: 3, done.\n")
+	r.Info("Merging with config from file: '%s'", "/tmp/.scaffold.yaml")
+	r.Done()
+
+	assert.True(t, strings.HasSuffix(out.String(), "\r\x1b[2KCloning\nCounting objects: 3, done.\nMerging with config from file: '/tmp/.scaffold.yaml'\n"), out.String())
+}
+
+func TestTerminal_Without_Step(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := NewTerminal(out, true)
+
+	r.Warning("Keeping existing '%s'", "README.md")
+	r.Done()
+
+	assert.Equal(t, "\x1b[0m\x1b[33mKeeping existing \x1b[39m\x1b[97m\x1b[1m'README.md'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", out.String())
+}
@@ -0,0 +1,182 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"github.com/buildtool/scaffold/pkg/report"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Attempts is how many times a request is sent at most
+	Attempts = 5
+	// Backoff is the wait before the first retry when the provider does not say how long to wait, it doubles with every retry
+	Backoff = time.Second
+	// MaxWait is the longest scaffold waits for a rate limit to reset, the request fails right away when it would take longer
+	MaxWait = 5 * time.Minute
+	// SecondaryWait is how long Github asks to wait after exceeding a secondary rate limit without saying how long
+	SecondaryWait = time.Minute
+)
+
+// Transport retries requests that were rejected by a rate limit, and requests that can safely be sent again when they
+// failed with a server error. The waits announced by Retry-After and X-RateLimit-Reset are honoured.
+type Transport struct {
+	Base     http.RoundTripper
+	Provider string
+	Reporter report.Reporter
+	now      func() time.Time
+	sleep    func(ctx context.Context, d time.Duration) error
+}
+
+// New wraps base, or http.DefaultTransport when nil, so that the requests to provider are retried, waits are reported to r
+func New(base http.RoundTripper, provider string, r report.Reporter) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base, Provider: provider, Reporter: r, now: time.Now, sleep: sleep}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := Backoff
+	for attempt := 1; ; attempt++ {
+		// The request of the caller must not be modified, so a copy is sent with a fresh body
+		send := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			send = req.WithContext(req.Context())
+			send.Body = body
+		}
+		response, err := t.Base.RoundTrip(send)
+		if err != nil || attempt == Attempts || !t.retryable(req, response) {
+			return response, err
+		}
+		wait, limited := t.wait(response)
+		if !limited {
+			wait = backoff
+			backoff *= 2
+		}
+		if wait > MaxWait {
+			return response, nil
+		}
+		_, _ = io.Copy(ioutil.Discard, response.Body)
+		_ = response.Body.Close()
+		if limited {
+			t.Reporter.Warning("Rate limit of %s reached, retrying in %s (attempt %d of %d)", t.Provider, wait, attempt+1, Attempts)
+		} else {
+			t.Reporter.Warning("%s answered %s, retrying in %s (attempt %d of %d)", t.Provider, response.Status, wait, attempt+1, Attempts)
+		}
+		if err := t.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable tells if req can be sent again, a rate limited request was never carried out so that is always safe
+func (t *Transport) retryable(req *http.Request, response *http.Response) bool {
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	if rateLimited(response) {
+		return true
+	}
+	switch response.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent(req.Method)
+	}
+	return false
+}
+
+// wait returns how long the provider asked to wait before retrying, and if it did
+func (t *Transport) wait(response *http.Response) (time.Duration, bool) {
+	if header := response.Header.Get("Retry-After"); header != "" {
+		if seconds, err := strconv.Atoi(header); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if at, err := http.ParseTime(header); err == nil {
+			return positive(at.Sub(t.now())), true
+		}
+	}
+	if header := response.Header.Get("X-RateLimit-Reset"); header != "" && rateLimited(response) {
+		if epoch, err := strconv.ParseInt(header, 10, 64); err == nil {
+			return positive(time.Unix(epoch, 0).Sub(t.now())), true
+		}
+	}
+	if response.StatusCode == http.StatusForbidden && secondaryLimit(response) {
+		return SecondaryWait, true
+	}
+	return 0, false
+}
+
+// rateLimited recognises 429 and the 403 Github answers with when the primary or secondary rate limit is exceeded
+func rateLimited(response *http.Response) bool {
+	switch response.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return response.Header.Get("Retry-After") != "" || response.Header.Get("X-RateLimit-Remaining") == "0" || secondaryLimit(response)
+	}
+	return false
+}
+
+// secondaryLimit tells if the message of the response is the one of a Github secondary rate limit, which does not always
+// come with a header saying so. The body is left for the caller to read.
+func secondaryLimit(response *http.Response) bool {
+	message := strings.ToLower(string(peek(response)))
+	return strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse detection")
+}
+
+// peekSize is how much of a body is read to recognise its message
+const peekSize = 4096
+
+type peeked struct {
+	io.Reader
+	io.Closer
+	start []byte
+}
+
+// peek returns the start of the body of response, which is put back so that it can still be read as a whole
+func peek(response *http.Response) []byte {
+	if p, ok := response.Body.(*peeked); ok {
+		return p.start
+	}
+	if response.Body == nil {
+		return nil
+	}
+	start, _ := ioutil.ReadAll(io.LimitReader(response.Body, peekSize))
+	response.Body = &peeked{Reader: io.MultiReader(bytes.NewReader(start), response.Body), Closer: response.Body, start: start}
+	return start
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func positive(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,269 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/report"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type server struct {
+	*httptest.Server
+	requests []string
+	bodies   []string
+}
+
+// newServer answers with the responses in order, the last one is repeated
+func newServer(responses ...func(w http.ResponseWriter)) *server {
+	s := &server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.requests = append(s.requests, r.Method)
+		s.bodies = append(s.bodies, string(body))
+		i := len(s.requests) - 1
+		if i >= len(responses) {
+			i = len(responses) - 1
+		}
+		responses[i](w)
+	}))
+	return s
+}
+
+func status(code int, headers ...string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for i := 0; i < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.WriteHeader(code)
+		_, _ = fmt.Fprint(w, http.StatusText(code))
+	}
+}
+
+func message(code int, text string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.WriteHeader(code)
+		_, _ = fmt.Fprint(w, text)
+	}
+}
+
+func transport(out *bytes.Buffer, waits *[]time.Duration) *Transport {
+	t := New(nil, "Github", report.NewText(out, true))
+	t.now = func() time.Time { return time.Unix(1000, 0) }
+	t.sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return ctx.Err()
+	}
+	return t
+}
+
+func TestNew(t *testing.T) {
+	r := report.NewText(&bytes.Buffer{}, true)
+	tr := New(nil, "Github", r)
+
+	assert.Equal(t, http.DefaultTransport, tr.Base)
+	assert.Equal(t, "Github", tr.Provider)
+	assert.Equal(t, r, tr.Reporter)
+}
+
+func TestTransport_Rate_Limited_Retry_After(t *testing.T) {
+	s := newServer(status(http.StatusTooManyRequests, "Retry-After", "3"), status(http.StatusOK))
+	defer s.Close()
+	out := &bytes.Buffer{}
+	var waits []time.Duration
+	client := &http.Client{Transport: transport(out, &waits)}
+
+	response, err := client.Get(s.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(t, "OK", string(body))
+	assert.Equal(t, []string{"GET", "GET"}, s.requests)
+	assert.Equal(t, []time.Duration{3 * time.Second}, waits)
+	assert.Equal(t, "\x1b[0m\x1b[33mRate limit of \x1b[39m\x1b[97m\x1b[1mGithub\x1b[0m\x1b[97m\x1b[39m \x1b[33mreached, retrying in \x1b[39m\x1b[97m\x1b[1m3s\x1b[0m\x1b[97m\x1b[39m \x1b[33m(attempt \x1b[39m\x1b[97m\x1b[1m2\x1b[0m\x1b[97m\x1b[39m \x1b[33mof \x1b[39m\x1b[97m\x1b[1m5\x1b[0m\x1b[97m\x1b[39m\x1b[33m)\x1b[39m\n\x1b[0m", out.String())
+}
+
+func TestTransport_Rate_Limited_Retry_After_Date(t *testing.T) {
+	s := newServer(status(http.StatusTooManyRequests, "Retry-After", time.Unix(1010, 0).UTC().Format(http.TimeFormat)), status(http.StatusOK))
+	defer s.Close()
+	var waits []time.Duration
+	client := &http.Client{Transport: transport(&bytes.Buffer{}, &waits)}
+
+	response, err := client.Get(s.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []time.Duration{10 * time.Second}, waits)
+}
+
+func TestTransport_Rate_Limit_Reset(t *testing.T) {
+	s := newServer(status(http.StatusForbidden, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "1042"), status(http.StatusOK))
+	defer s.Close()
+	var waits []time.Duration
+	client := &http.Client{Transport: transport(&bytes.Buffer{}, &waits)}
+
+	response, err := client.Get(s.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []time.Duration{42 * time.Second}, waits)
+}
+
+func TestTransport_Forbidden_Is_Not_Retried(t *testing.T) {
+	s := newServer(status(http.StatusForbidden, "X-RateLimit-Remaining", "4999"))
+	defer s.Close()
+	out := &bytes.Buffer{}
+	var waits []time.Duration
+	client := &http.Client{Transport: transport(out, &waits)}
+
+	response, err := client.Get(s.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+	assert.Equal(t, []string{"GET"}, s.requests)
+	assert.Equal(t, "", out.String())
+}
+
+func TestTransport_Secondary_Rate_Limit(t *testing.T) {
+	s := newServer(message(http.StatusForbidden, `{"message":"You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`), status(http.StatusOK))
+	defer s.Close()
+	var waits []time.Duration
+	client := &http.Client{Transport: transport(&bytes.Buffer{}, &waits)}
+
+	response, err := client.Get(s.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []string{"GET", "GET"}, s.requests)
+	assert.Equal(t, []time.Duration{SecondaryWait}, waits)
+}
+
+func TestTransport_Forbidden_Body_Is_Kept(t *testing.T) {
+	s := newServer(message(http.StatusForbidden, `{"message":"Must have admin rights to Repository."}`))
+	defer s.Close()
+	var waits []time.Duration
+	client := &http.Client{Transport: transport(&bytes.Buffer{}, &waits)}
+
+	response, err := client.Get(s.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(t, `{"message":"Must have admin rights to Repository."}`, string(body))
+	assert.Equal(t, []string{"GET"}, s.requests)
+	assert.Empty(t, waits)
+}
+
+func TestTransport_Request_Is_Not_Modified(t *testing.T) {
+	s := newServer(status(http.StatusTooManyRequests, "Retry-After", "1"), status(http.StatusCreated))
+	defer s.Close()
+	var waits []time.Duration
+	req, _ := http.NewRequest(http.MethodPost, s.URL, strings.NewReader(`{"name":"project"}`))
+	body := req.Body
+
+	response, err := transport(&bytes.Buffer{}, &waits).RoundTrip(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Equal(t, body, req.Body)
+	assert.Equal(t, []string{`{"name":"project"}`, `{"name":"project"}`}, s.bodies)
+}
+
+func TestTransport_Rate_Limited_Post_With_Body(t *testing.T) {
+	s := newServer(status(http.StatusTooManyRequests, "Retry-After", "1"), status(http.StatusCreated))
+	defer s.Close()
+	var waits []time.Duration
+	client := &http.Client{Transport: transport(&bytes.Buffer{}, &waits)}
+
+	response, err := client.Post(s.URL, "application/json", strings.NewReader(`{"name":"project"}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Equal(t, []string{`{"name":"project"}`, `{"name":"project"}`}, s.bodies)
+}
+
+func TestTransport_Rate_Limit_Too_Long(t *testing.T) {
+	s := newServer(status(http.StatusTooManyRequests, "Retry-After", "3600"))
+	defer s.Close()
+	out := &bytes.Buffer{}
+	var waits []time.Duration
+	client := &http.Client{Transport: transport(out, &waits)}
+
+	response, err := client.Get(s.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(t, "Too Many Requests", string(body))
+	assert.Equal(t, []string{"GET"}, s.requests)
+	assert.Nil(t, waits)
+}
+
+func TestTransport_Server_Error_Backoff(t *testing.T) {
+	s := newServer(status(http.StatusServiceUnavailable), status(http.StatusBadGateway), status(http.StatusOK))
+	defer s.Close()
+	out := &bytes.Buffer{}
+	var waits []time.Duration
+	client := &http.Client{Transport: transport(out, &waits)}
+
+	response, err := client.Get(s.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, waits)
+	assert.Equal(t, "\x1b[0m\x1b[97m\x1b[1mGithub\x1b[0m\x1b[97m\x1b[39m \x1b[33manswered \x1b[39m\x1b[97m\x1b[1m503 Service Unavailable\x1b[0m\x1b[97m\x1b[39m\x1b[33m, retrying in \x1b[39m\x1b[97m\x1b[1m1s\x1b[0m\x1b[97m\x1b[39m \x1b[33m(attempt \x1b[39m\x1b[97m\x1b[1m2\x1b[0m\x1b[97m\x1b[39m \x1b[33mof \x1b[39m\x1b[97m\x1b[1m5\x1b[0m\x1b[97m\x1b[39m\x1b[33m)\x1b[39m\n\x1b[0m\x1b[0m\x1b[97m\x1b[1mGithub\x1b[0m\x1b[97m\x1b[39m \x1b[33manswered \x1b[39m\x1b[97m\x1b[1m502 Bad Gateway\x1b[0m\x1b[97m\x1b[39m\x1b[33m, retrying in \x1b[39m\x1b[97m\x1b[1m2s\x1b[0m\x1b[97m\x1b[39m \x1b[33m(attempt \x1b[39m\x1b[97m\x1b[1m3\x1b[0m\x1b[97m\x1b[39m \x1b[33mof \x1b[39m\x1b[97m\x1b[1m5\x1b[0m\x1b[97m\x1b[39m\x1b[33m)\x1b[39m\n\x1b[0m", out.String())
+}
+
+func TestTransport_Server_Error_Gives_Up(t *testing.T) {
+	s := newServer(status(http.StatusInternalServerError))
+	defer s.Close()
+	var waits []time.Duration
+	client := &http.Client{Transport: transport(&bytes.Buffer{}, &waits)}
+
+	response, err := client.Get(s.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+	assert.Equal(t, Attempts, len(s.requests))
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}, waits)
+}
+
+func TestTransport_Server_Error_Post_Is_Not_Retried(t *testing.T) {
+	s := newServer(status(http.StatusServiceUnavailable), status(http.StatusCreated))
+	defer s.Close()
+	out := &bytes.Buffer{}
+	var waits []time.Duration
+	client := &http.Client{Transport: transport(out, &waits)}
+
+	response, err := client.Post(s.URL, "application/json", strings.NewReader("{}"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.Equal(t, []string{"POST"}, s.requests)
+	assert.Equal(t, "", out.String())
+}
+
+func TestTransport_Cancelled_While_Waiting(t *testing.T) {
+	s := newServer(status(http.StatusServiceUnavailable))
+	defer s.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	tr := New(nil, "Gitlab", report.NewText(&bytes.Buffer{}, true))
+	tr.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return sleep(ctx, d)
+	}
+	request, _ := http.NewRequest(http.MethodGet, s.URL, nil)
+
+	_, err := (&http.Client{Transport: tr}).Do(request.WithContext(ctx))
+
+	assert.Error(t, err)
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.Equal(t, []string{"GET"}, s.requests)
+}
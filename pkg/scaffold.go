@@ -1,69 +1,199 @@
 package pkg
 
 import (
-	"flag"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/buildtool/scaffold/pkg/config"
+	"github.com/buildtool/scaffold/pkg/config/vcs"
+	"github.com/buildtool/scaffold/pkg/events"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/report"
 	"github.com/buildtool/scaffold/pkg/stack"
-	"github.com/liamg/tml"
 	"io"
+	"io/ioutil"
 	"sort"
 	"strings"
+	"time"
 )
 
+type options struct {
+	name          string
+	stack         string
+	output        string
+	progress      string
+	selection     config.Selection
+	dryRun        bool
+	keepOnFailure bool
+	resume        bool
+	local         bool
+	module        string
+	organisation  string
+	registry      string
+	commit        vcs.Commit
+	timeout       time.Duration
+	wizard        *wizard
+}
+
 func Setup(dir string, out io.Writer, args ...string) int {
-	var selectedStack string
+	opts := &options{}
 	const (
-		stackUsage = "stack to scaffold"
+		stackUsage         = "stack to scaffold"
+		dryRunUsage        = "print the actions that would be taken without calling any API or writing any files"
+		keepOnFailureUsage = "keep already created resources if scaffolding fails instead of removing them"
+		resumeUsage        = "continue an interrupted run, skipping the steps it already completed"
+		outputUsage        = "output format, text or json"
+		localUsage         = "only initialise a local git repository and generate the files, without any VCS or CI provider"
+		moduleUsage        = "module path of the project with --local, e.g. github.com/org/name (default local/<name>)"
+		organisationUsage  = "organisation used in the templates, overrides the configuration"
+		registryUsage      = "docker registry used in the templates, overrides the configuration"
+		messageUsage       = "message of the commit of the generated files"
+		authorNameUsage    = "author of the commit of the generated files (default user.name from git config)"
+		authorEmailUsage   = "email of the author of the commit of the generated files (default user.email from git config)"
 	)
-	set := flag.NewFlagSet("service-setup", flag.ExitOnError)
-	set.Usage = func() {
-		_, _ = fmt.Fprint(out, tml.Sprintf("Usage: service-setup [options] <name>\n\nFor example <blue>`service-setup --stack go gosvc`</blue> would create a new repository and scaffold it as a Go-project\n\nOptions:\n"))
-		set.PrintDefaults()
+	set := newFlagSet("scaffold new", "[options] <name>", "For example <blue>`scaffold new --stack go gosvc`</blue> would create a new repository and scaffold it as a Go-project", out)
+	set.StringVar(&opts.stack, "stack", "none", stackUsage)
+	set.StringVar(&opts.stack, "s", "none", stackUsage+" (shorthand)")
+	set.BoolVar(&opts.dryRun, "dry-run", false, dryRunUsage)
+	set.BoolVar(&opts.keepOnFailure, "keep-on-failure", false, keepOnFailureUsage)
+	set.BoolVar(&opts.resume, "resume", false, resumeUsage)
+	set.StringVar(&opts.output, "output", "text", outputUsage)
+	set.StringVar(&opts.progress, "progress", report.Auto, report.Usage)
+	selectionFlags(set, &opts.selection)
+	set.BoolVar(&opts.local, "local", false, localUsage)
+	set.StringVar(&opts.module, "module", "", moduleUsage)
+	set.StringVar(&opts.organisation, "organisation", "", organisationUsage)
+	set.StringVar(&opts.registry, "registry", "", registryUsage)
+	set.StringVar(&opts.commit.Message, "commit-message", config.DefaultCommitMessage, messageUsage)
+	set.StringVar(&opts.commit.Name, "author-name", "", authorNameUsage)
+	set.StringVar(&opts.commit.Email, "author-email", "", authorEmailUsage)
+	set.DurationVar(&opts.timeout, "timeout", defaultTimeout, timeoutUsage)
+
+	if exitCode, ok := parseFlags(set, args); !ok {
+		return exitCode
 	}
-	set.StringVar(&selectedStack, "stack", "none", stackUsage)
-	set.StringVar(&selectedStack, "s", "none", stackUsage+" (shorthand)")
 
-	_ = set.Parse(args)
+	listener := events.Nop
+	switch opts.output {
+	case "text":
+	case "json":
+		listener = events.NewJSON(out)
+		out = ioutil.Discard
+		set.SetOutput(out)
+	default:
+		set.Usage()
+		return failure.Usage.ExitCode()
+	}
 
-	if set.NArg() < 1 {
+	r, err := report.New(out, opts.progress)
+	if err != nil {
 		set.Usage()
-		return -1
+		return failure.Usage.ExitCode()
 	}
+	followColors(set, out, r)
 
-	name := set.Args()[0]
-	currentStack, exists := stack.Stacks[selectedStack]
-	if !exists {
-		var stackNames []string
-		for k := range stack.Stacks {
-			stackNames = append(stackNames, k)
+	if set.NArg() > 0 {
+		opts.name = set.Args()[0]
+	} else if opts.output == "text" && isTerminal() {
+		opts.wizard = newWizard(stdin, out, r.Colored())
+	}
+	exitCode := create(dir, opts, r, listener)
+	r.Done()
+	if opts.name == "" && opts.wizard == nil {
+		set.Usage()
+	}
+	listener.Finish(opts.name, opts.stack, exitCode)
+	return exitCode
+}
+
+func create(dir string, opts *options, r report.Reporter, listener events.Listener) int {
+	if opts.wizard != nil {
+		if err := opts.wizard.project(opts); err != nil {
+			return fail(listener, r, "wizard", failure.Usage, err)
 		}
-		sort.Strings(stackNames)
-		_, _ = fmt.Fprint(out, tml.Sprintf("<red>Provided stack does not exist yet. Available stacks are: </red><white><bold>(%s)</bold></white>\n", strings.Join(stackNames, ", ")))
-		return -2
 	}
-	cfg, err := config.Load(dir, out)
+	if opts.name == "" {
+		return fail(listener, report.Nop, "arguments", failure.Usage, errors.New("missing name of the service to create"))
+	}
+	currentStack, exists := stack.Stacks[opts.stack]
+	if !exists {
+		stackNames := strings.Join(stackNames(), ", ")
+		r.Failed("Provided stack does not exist yet. Available stacks are: %s", "("+stackNames+")")
+		return fail(listener, report.Nop, "arguments", failure.Usage, fmt.Errorf("stack '%s' does not exist, available stacks are: %s", opts.stack, stackNames))
+	}
+	cfg, err := config.Load(dir, opts.selection, r)
 	if err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -3
+		return fail(listener, r, "load-config", failure.Config, err)
+	}
+	cfg.Listener = listener
+	if opts.local {
+		cfg.Local(opts.module)
+	}
+	if opts.organisation != "" {
+		cfg.Organisation = opts.organisation
+	}
+	if opts.registry != "" {
+		cfg.RegistryUrl = opts.registry
+	}
+	if opts.resume {
+		if err := cfg.Resume(dir, opts.name); err != nil {
+			return fail(listener, r, "load-config", failure.Config, err)
+		}
 	}
 
 	if err := cfg.ValidateConfig(); err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -4
+		return fail(listener, r, "validate-config", failure.Config, err)
+	}
+	cfg.KeepOnFailure = opts.keepOnFailure
+	cfg.Commit = opts.commit
+
+	if opts.wizard != nil {
+		if err := opts.wizard.configure(cfg, opts); err != nil {
+			return fail(listener, r, "wizard", failure.Usage, err)
+		}
+	}
+
+	// Created after the wizard, which can still be aborted with Ctrl-C
+	ctx, cancel := interruptible(opts.timeout)
+	defer cancel()
+	if opts.dryRun {
+		return plan(ctx, cfg, dir, opts.name, currentStack, r)
 	}
+	return scaffold(ctx, cfg, dir, opts.name, currentStack, r)
+}
 
-	return scaffold(cfg, dir, name, currentStack, out)
+func stackNames() []string {
+	var names []string
+	for k := range stack.Stacks {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
 }
 
-func scaffold(cfg *config.Config, dir, name string, stack stack.Stack, out io.Writer) int {
-	if err := cfg.Configure(); err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -5
+func scaffold(ctx context.Context, cfg *config.Config, dir, name string, stack stack.Stack, r report.Reporter) int {
+	if err := cfg.Configure(r); err != nil {
+		return fail(cfg.Events(), r, "configure", failure.Config, err)
+	}
+	if err := cfg.Validate(ctx, name); err != nil {
+		return fail(cfg.Events(), r, "validate", failure.Remote, err)
 	}
-	if err := cfg.Validate(name); err != nil {
-		_, _ = fmt.Fprintln(out, tml.Sprintf("<red>%s</red>", err.Error()))
-		return -6
+	return cfg.Scaffold(ctx, dir, name, stack, r)
+}
+
+func plan(ctx context.Context, cfg *config.Config, dir, name string, stack stack.Stack, r report.Reporter) int {
+	cfg.ConfigureDryRun(r)
+	if err := cfg.Validate(ctx, name); err != nil {
+		return fail(cfg.Events(), r, "validate", failure.Remote, err)
 	}
-	return cfg.Scaffold(dir, name, stack, out)
+	return cfg.DryRun(ctx, dir, name, stack, r)
+}
+
+func fail(listener events.Listener, r report.Reporter, step string, kind failure.Kind, err error) int {
+	err = failure.Wrap(kind, step, err)
+	r.Error(err)
+	failed := events.Step{Name: step}
+	failed.Fail(err)
+	listener.Step(failed)
+	return failure.ExitCode(err)
 }
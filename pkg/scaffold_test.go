@@ -2,18 +2,24 @@ package pkg
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/buildtool/scaffold/pkg/config"
 	"github.com/buildtool/scaffold/pkg/config/ci"
 	"github.com/buildtool/scaffold/pkg/config/vcs"
+	"github.com/buildtool/scaffold/pkg/events"
+	"github.com/buildtool/scaffold/pkg/report"
 	"github.com/buildtool/scaffold/pkg/stack"
 	"github.com/buildtool/scaffold/pkg/templating"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -29,6 +35,7 @@ func TestMain(m *testing.M) {
 func setup() string {
 	name, _ = ioutil.TempDir(os.TempDir(), "scaffold")
 	_ = os.Chdir(name)
+	isTerminal = func() bool { return false }
 
 	return name
 }
@@ -42,8 +49,8 @@ func TestSetup_NoArgs(t *testing.T) {
 
 	exitCode := Setup(name, &out)
 
-	assert.Equal(t, -1, exitCode)
-	assert.Equal(t, "\x1b[0mUsage: service-setup [options] <name>\n\nFor example \x1b[34m`service-setup --stack go gosvc`\x1b[39m would create a new repository and scaffold it as a Go-project\n\nOptions:\n\x1b[0m", out.String())
+	assert.Equal(t, 2, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "\x1b[0mUsage: scaffold new [options] <name>\n\nFor example \x1b[34m`scaffold new --stack go gosvc`\x1b[39m would create a new repository and scaffold it as a Go-project\n\x1b[0m\nOptions:\n  -author-email string\n"))
 }
 
 func TestSetup_NonExistingStack(t *testing.T) {
@@ -51,7 +58,7 @@ func TestSetup_NonExistingStack(t *testing.T) {
 
 	exitCode := Setup(name, &out, "-s", "missing", "project")
 
-	assert.Equal(t, -2, exitCode)
+	assert.Equal(t, 2, exitCode)
 	assert.Equal(t, "\x1b[0m\x1b[31mProvided stack does not exist yet. Available stacks are: \x1b[39m\x1b[97m\x1b[1m(go, none, scala)\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", out.String())
 }
 
@@ -66,8 +73,8 @@ func TestSetup_BrokenConfig(t *testing.T) {
 
 	exitCode := Setup(name, &out, "project")
 
-	assert.Equal(t, -3, exitCode)
-	assert.Equal(t, fmt.Sprintf("\x1b[0mParsing config from file: \x1b[32m'%s'\x1b[39m\x1b[0m\n\x1b[0m\x1b[31myaml: unmarshal errors:\n  line 1: cannot unmarshal !!seq into config.CIConfig\x1b[39m\x1b[0m\n", file), out.String())
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0mParsing config from file: \x1b[32m'%s'\x1b[39m\x1b[0m\n\x1b[0m\x1b[31m%s:1: 'ci' must be a mapping\x1b[39m\x1b[0m\n", file, file), out.String())
 }
 
 func TestSetup_NoVCS(t *testing.T) {
@@ -75,10 +82,30 @@ func TestSetup_NoVCS(t *testing.T) {
 
 	exitCode := Setup(name, &out, "project")
 
-	assert.Equal(t, -4, exitCode)
+	assert.Equal(t, 3, exitCode)
 	assert.Equal(t, "\x1b[0m\x1b[31mno VCS configured\x1b[39m\x1b[0m\n", out.String())
 }
 
+func TestSetup_Several_VCS(t *testing.T) {
+	yaml := `
+vcs:
+  github:
+    token: abc
+  gitlab:
+    group: group
+    token: abc
+`
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+	out := bytes.Buffer{}
+
+	exitCode := Setup(name, &out, "project")
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0mParsing config from file: \x1b[32m'%s'\x1b[39m\x1b[0m\n\x1b[0m\x1b[31mseveral VCS are configured (github, gitlab), choose one with --vcs or vcs.default\x1b[39m\x1b[0m\n", file), out.String())
+}
+
 func TestScaffold_Missing_Token(t *testing.T) {
 	yaml := `
 ci:
@@ -98,8 +125,184 @@ vcs:
 
 	exitCode := Setup(name, &out, "project")
 
-	assert.Equal(t, -6, exitCode)
-	assert.Equal(t, fmt.Sprintf("\x1b[0mParsing config from file: \x1b[32m'%s'\x1b[39m\x1b[0m\n\x1b[0m\x1b[31mGET https://api.buildkite.com/v2/user: 401 Authentication required. Please supply a valid API Access Token: https://buildkite.com/docs/apis/rest-api#authentication\x1b[39m\x1b[0m\n", file), out.String())
+	assert.Equal(t, 4, exitCode)
+	assert.Equal(t, fmt.Sprintf("\x1b[0mParsing config from file: \x1b[32m'%s'\x1b[39m\x1b[0m\n\x1b[0m\x1b[31mGET https://api.buildkite.com/v2/user: 401 Authentication required. Please supply a valid API Access Token: https://buildkite.com/docs/apis/rest-api#authentication\x1b[39m\x1b[0m\n\x1b[0m\x1b[33mHint: the token for Buildkite is invalid or has expired\x1b[39m\x1b[0m\n", file), out.String())
+}
+
+func TestSetup_DryRun(t *testing.T) {
+	yaml := `
+ci:
+  buildkite:
+    organisation: example
+    token: abc
+vcs:
+  github:
+    organisation: example
+    token: abc
+`
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+
+	out := bytes.Buffer{}
+
+	exitCode := Setup(name, &out, "--dry-run", "project")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "Would call \x1b[39m\x1b[97m\x1b[1mPOST https://api.github.com/orgs/example/repos")
+	assert.Contains(t, out.String(), "Would call \x1b[39m\x1b[97m\x1b[1mPOST https://api.buildkite.com/v2/organizations/example/pipelines")
+	assert.Contains(t, out.String(), "Would call \x1b[39m\x1b[97m\x1b[1mPOST https://api.github.com/repos/example/project/hooks")
+	assert.Contains(t, out.String(), fmt.Sprintf("Would write file \x1b[39m\x1b[97m\x1b[1m'%s/project/README.md'", name))
+	_, err := os.Stat(filepath.Join(name, "project"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSetup_Local(t *testing.T) {
+	defer func() { _ = os.RemoveAll(filepath.Join(name, "project")) }()
+	out := bytes.Buffer{}
+
+	exitCode := Setup(name, &out, "--local", "--stack", "go", "--module", "github.com/org/project", "--registry", "registry.example.com", "--author-name", "Test", "--author-email", "test@example.com", "project")
+
+	assert.Equal(t, 0, exitCode)
+	goMod, _ := ioutil.ReadFile(filepath.Join(name, "project", "go.mod"))
+	assert.Equal(t, "module github.com/org/project\n\ngo 1.12\n", string(goMod))
+	deployment, _ := ioutil.ReadFile(filepath.Join(name, "project", "k8s", "deploy.yaml"))
+	assert.Contains(t, string(deployment), "image: registry.example.com/project:${COMMIT}")
+	assert.DirExists(t, filepath.Join(name, "project", ".git"))
+	_, err := os.Stat(filepath.Join(name, "project", ".buildkite"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Contains(t, out.String(), "Committing generated files")
+	assert.NotContains(t, out.String(), "Creating build pipeline")
+	assert.NotContains(t, out.String(), "Pushing to")
+}
+
+func TestSetup_Progress_Plain(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Setup(name, &out, "--progress", "plain", "-s", "missing", "project")
+
+	assert.Equal(t, 2, exitCode)
+	assert.Equal(t, "Provided stack does not exist yet. Available stacks are: (go, none, scala)\n", out.String())
+}
+
+func TestSetup_Progress_Plain_Usage(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Setup(name, &out, "--progress", "plain")
+
+	assert.Equal(t, 2, exitCode)
+	assert.Contains(t, out.String(), "Usage: scaffold new [options] <name>\n")
+	assert.NotContains(t, out.String(), "\x1b[")
+}
+
+func TestSetup_Progress_Quiet(t *testing.T) {
+	defer func() { _ = os.RemoveAll(filepath.Join(name, "project")) }()
+	out := bytes.Buffer{}
+
+	exitCode := Setup(name, &out, "--progress", "quiet", "--local", "--author-name", "Test", "--author-email", "test@example.com", "project")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "", out.String())
+	assert.DirExists(t, filepath.Join(name, "project", ".git"))
+}
+
+func TestSetup_Invalid_Progress(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Setup(name, &out, "--progress", "fancy", "project")
+
+	assert.Equal(t, 2, exitCode)
+	assert.Contains(t, out.String(), "Usage: scaffold new [options] <name>")
+}
+
+func TestSetup_Resume_Without_Journal(t *testing.T) {
+	yaml := `
+ci:
+  buildkite:
+    organisation: example
+    token: abc
+vcs:
+  github:
+    organisation: example
+    token: abc
+`
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+
+	out := bytes.Buffer{}
+
+	exitCode := Setup(name, &out, "--resume", "project")
+
+	assert.Equal(t, 3, exitCode)
+	assert.Contains(t, out.String(), fmt.Sprintf("no previous run to resume, '%s/.project.scaffold-journal' does not exist", name))
+}
+
+func TestSetup_Json_Output(t *testing.T) {
+	yaml := `
+ci:
+  buildkite:
+    organisation: example
+    token: abc
+vcs:
+  github:
+    organisation: example
+    token: abc
+`
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+
+	out := bytes.Buffer{}
+
+	exitCode := Setup(name, &out, "--dry-run", "--output", "json", "project")
+
+	assert.Equal(t, 0, exitCode)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, 15, len(lines))
+	var step events.Step
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &step))
+	assert.Equal(t, "step", step.Type)
+	assert.Equal(t, "repository", step.Name)
+	assert.Equal(t, events.Succeeded, step.Status)
+	assert.Equal(t, []string{"git@github.com:example/project.git", "https://github.com/example/project.git"}, step.Resources)
+	var summary events.Summary
+	assert.NoError(t, json.Unmarshal([]byte(lines[14]), &summary))
+	assert.Equal(t, "summary", summary.Type)
+	assert.Equal(t, events.Succeeded, summary.Status)
+	assert.Equal(t, "project", summary.Name)
+	assert.Equal(t, "none", summary.Stack)
+	assert.Equal(t, []string{"git@github.com:example/project.git", "https://github.com/example/project.git", "https://webhook.buildkite.com/deliver/dry-run", "https://github.com/example/project/pull/1"}, summary.Resources)
+}
+
+func TestSetup_Json_Output_NoArgs(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Setup(name, &out, "--output", "json")
+
+	assert.Equal(t, 2, exitCode)
+	assert.Equal(t, `{"type":"step","name":"arguments","status":"failed","durationMs":0,"error":"missing name of the service to create","errorKind":"usage","exitCode":2}
+{"type":"summary","name":"","stack":"none","status":"failed","durationMs":0,"failedStep":"arguments","error":"missing name of the service to create","errorKind":"usage","exitCode":2}
+`, out.String())
+}
+
+func TestSetup_Invalid_Output(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Setup(name, &out, "--output", "xml", "project")
+
+	assert.Equal(t, 2, exitCode)
+	assert.Contains(t, out.String(), "Usage: scaffold new [options] <name>")
+}
+
+func TestPlan_Validate_Error(t *testing.T) {
+	cfg := config.InitEmptyConfig()
+	cfg.CurrentCI = &mockCi{validateErr: errors.New("validate error")}
+	cfg.CurrentVCS = &mockVcs{}
+	out := &bytes.Buffer{}
+	exitCode := plan(context.Background(), cfg, name, "project", &stack.None{}, report.NewText(out, true))
+	assert.Equal(t, 6, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[31mvalidate error\x1b[39m\x1b[0m\n", out.String())
 }
 
 func TestScaffold_Configure_Error(t *testing.T) {
@@ -107,8 +310,8 @@ func TestScaffold_Configure_Error(t *testing.T) {
 	cfg.CurrentCI = &mockCi{configErr: errors.New("config error")}
 	cfg.CurrentVCS = &mockVcs{}
 	out := &bytes.Buffer{}
-	exitCode := scaffold(cfg, name, "project", &stack.None{}, out)
-	assert.Equal(t, -5, exitCode)
+	exitCode := scaffold(context.Background(), cfg, name, "project", &stack.None{}, report.NewText(out, true))
+	assert.Equal(t, 3, exitCode)
 	assert.Equal(t, "\x1b[0m\x1b[31mconfig error\x1b[39m\x1b[0m\n", out.String())
 }
 
@@ -117,42 +320,61 @@ func TestScaffold_Ok(t *testing.T) {
 	cfg.CurrentCI = &mockCi{}
 	cfg.CurrentVCS = &mockVcs{}
 	out := &bytes.Buffer{}
-	exitCode := scaffold(cfg, name, "project", &stack.None{}, out)
+	exitCode := scaffold(context.Background(), cfg, name, "project", &stack.None{}, report.NewText(out, true))
 	assert.Equal(t, 0, exitCode)
-	assert.Equal(t, "\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mock'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'git@git'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", out.String())
+	assert.Equal(t, fmt.Sprintf("\x1b[0m\x1b[94mCreating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating repository at \x1b[39m\x1b[97m\x1b[1m'mock'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mCreated repository \x1b[39m\x1b[97m\x1b[1m'git@git'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCreating build pipeline for \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mCommitting generated files in \x1b[39m\x1b[97m\x1b[1m'%s/project'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mPushing to \x1b[39m\x1b[97m\x1b[1m'git@git'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[94mOpening pull request from \x1b[39m\x1b[97m\x1b[1m'scaffold/initial'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m\x1b[0m\x1b[32mOpened pull request \x1b[39m\x1b[97m\x1b[1m'https://example.org/pull/1'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", name), out.String())
 }
 
 type mockCi struct {
-	configErr error
+	configErr   error
+	validateErr error
+	checkErr    error
 }
 
 func (m mockCi) Name() string {
-	panic("implement me")
+	return "mockCi"
 }
 
 func (m mockCi) ValidateConfig() error {
 	panic("implement me")
 }
 
-func (m mockCi) Validate(name string) error {
-	return nil
+func (m mockCi) Adopt(ctx context.Context, path string) error {
+	return m.validateErr
+}
+
+func (m mockCi) Validate(ctx context.Context, name string) error {
+	return m.validateErr
 }
 
-func (m mockCi) Scaffold(dir string, data templating.TemplateData) (*string, error) {
+func (m mockCi) Scaffold(ctx context.Context, fs billy.Filesystem, data templating.TemplateData) (*string, error) {
 	return nil, nil
 }
 
-func (m mockCi) Badges(name string) ([]templating.Badge, error) {
+func (m mockCi) Badges(ctx context.Context, name string) ([]templating.Badge, error) {
 	return nil, nil
 }
 
-func (m mockCi) Configure() error {
+func (m mockCi) DryRun(r report.Reporter) {
+}
+
+func (m mockCi) DeletePipeline(ctx context.Context, name string) error {
+	return nil
+}
+
+func (m mockCi) Configure(r report.Reporter) error {
 	return m.configErr
 }
 
+func (m mockCi) Check(ctx context.Context, report func(check string, err error)) {
+	report("token", m.checkErr)
+}
+
 var _ ci.CI = &mockCi{}
 
 type mockVcs struct {
+	deleteErr error
+	checkErr  error
 }
 
 func (m mockVcs) Name() string {
@@ -163,26 +385,57 @@ func (m mockVcs) ValidateConfig() error {
 	panic("implement me")
 }
 
-func (m mockVcs) Configure() {
+func (m mockVcs) Configure(r report.Reporter) {
+}
+
+func (m mockVcs) Check(ctx context.Context, report func(check string, err error)) {
+	report("token", m.checkErr)
 }
 
-func (m mockVcs) Validate(name string) error {
+func (m mockVcs) DryRun(r report.Reporter) {
+}
+
+func (m mockVcs) Validate(ctx context.Context, name string) error {
 	return nil
 }
 
-func (m mockVcs) Scaffold(name string) (*vcs.RepositoryInfo, error) {
+func (m mockVcs) Scaffold(ctx context.Context, name string) (*vcs.RepositoryInfo, error) {
 	return &vcs.RepositoryInfo{
 		SSHURL:  "git@git",
 		HTTPURL: "https://git",
 	}, nil
 }
 
-func (m mockVcs) Webhook(name, url string) error {
+func (m mockVcs) Adopt(repository *vcs.RepositoryInfo) error {
+	return nil
+}
+
+func (m mockVcs) Webhook(ctx context.Context, name, url string) error {
 	panic("implement me")
 }
 
-func (m mockVcs) Clone(dir, name, url string, out io.Writer) error {
+func (m mockVcs) DeleteRepository(ctx context.Context, name string) error {
+	return m.deleteErr
+}
+
+func (m mockVcs) DeleteWebhook(ctx context.Context, name string) error {
+	return nil
+}
+
+func (m mockVcs) Clone(ctx context.Context, dir, name, url string, out io.Writer) error {
 	return nil
 }
 
+func (m mockVcs) Commit(dir, name string, files []string, commit vcs.Commit) error {
+	return nil
+}
+
+func (m mockVcs) Push(ctx context.Context, dir, name string, out io.Writer) error {
+	return nil
+}
+
+func (m mockVcs) PullRequest(ctx context.Context, name, branch, title, body string) (string, error) {
+	return "https://example.org/pull/1", nil
+}
+
 var _ vcs.VCS = &mockVcs{}
@@ -3,13 +3,13 @@ package stack
 import (
 	"github.com/buildtool/scaffold/pkg/file"
 	"github.com/buildtool/scaffold/pkg/templating"
-	"path/filepath"
+	"gopkg.in/src-d/go-billy.v4"
 )
 
 type Go struct{}
 
-func (g Go) Scaffold(dir string, data templating.TemplateData) error {
-	if err := file.WriteTemplated(dir, "go.mod", goMod, data); err != nil {
+func (g Go) Scaffold(fs billy.Filesystem, data templating.TemplateData) error {
+	if err := file.WriteTemplated(fs, "go.mod", goMod, data); err != nil {
 		return err
 	}
 
@@ -18,7 +18,7 @@ func (g Go) Scaffold(dir string, data templating.TemplateData) error {
 indent_style = tab
 indent_size = 4
 `
-	return file.Append(filepath.Join(dir, ".editorconfig"), editorconfig)
+	return file.Append(fs, ".editorconfig", editorconfig)
 }
 
 func (g Go) Name() string {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/buildtool/scaffold/pkg/templating"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/osfs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -31,7 +32,7 @@ func TestGo_Scaffold_Error(t *testing.T) {
 
 	stack := &Go{}
 
-	err := stack.Scaffold(filename, templating.TemplateData{
+	err := stack.Scaffold(osfs.New(filename), templating.TemplateData{
 		ProjectName:    "test",
 		Badges:         nil,
 		Organisation:   "org.example",
@@ -52,7 +53,7 @@ func TestGo_Scaffold(t *testing.T) {
 
 	stack := &Go{}
 
-	err := stack.Scaffold(name, templating.TemplateData{
+	err := stack.Scaffold(osfs.New(name), templating.TemplateData{
 		ProjectName:    "test",
 		Badges:         nil,
 		Organisation:   "org.example",
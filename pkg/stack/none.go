@@ -1,10 +1,13 @@
 package stack
 
-import "github.com/buildtool/scaffold/pkg/templating"
+import (
+	"github.com/buildtool/scaffold/pkg/templating"
+	"gopkg.in/src-d/go-billy.v4"
+)
 
 type None struct{}
 
-func (n *None) Scaffold(dir string, data templating.TemplateData) error {
+func (n *None) Scaffold(fs billy.Filesystem, data templating.TemplateData) error {
 	return nil
 }
 
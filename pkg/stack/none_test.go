@@ -3,13 +3,14 @@ package stack
 import (
 	"github.com/buildtool/scaffold/pkg/templating"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
 	"testing"
 )
 
 func TestNone_Scaffold(t *testing.T) {
 	stack := &None{}
 
-	err := stack.Scaffold("dir", templating.TemplateData{
+	err := stack.Scaffold(memfs.New(), templating.TemplateData{
 		ProjectName:    "test",
 		Badges:         nil,
 		Organisation:   "org.example",
@@ -3,23 +3,23 @@ package stack
 import (
 	"github.com/buildtool/scaffold/pkg/file"
 	"github.com/buildtool/scaffold/pkg/templating"
-	"os"
+	"gopkg.in/src-d/go-billy.v4"
 	"path/filepath"
 	"strings"
 )
 
 type Scala struct{}
 
-func (s Scala) Scaffold(dir string, data templating.TemplateData) error {
+func (s Scala) Scaffold(fs billy.Filesystem, data templating.TemplateData) error {
 	for _, s := range []string{"main", "test"} {
 		for _, t := range []string{"scala", "resources"} {
-			if err := os.MkdirAll(filepath.Join(dir, "src", s, t), 0777); err != nil {
+			if err := fs.MkdirAll(filepath.Join("src", s, t), 0777); err != nil {
 				return err
 			}
 		}
 	}
-	orgPath := append([]string{dir, "src", "main", "scala"}, strings.Split(data.Organisation, ".")...)
-	if err := os.MkdirAll(filepath.Join(orgPath...), 0777); err != nil {
+	orgPath := append([]string{"src", "main", "scala"}, strings.Split(data.Organisation, ".")...)
+	if err := fs.MkdirAll(filepath.Join(orgPath...), 0777); err != nil {
 		return err
 	}
 	files := []struct {
@@ -33,14 +33,14 @@ func (s Scala) Scaffold(dir string, data templating.TemplateData) error {
 		{"src/main/resources/logback.xml", logbackXml},
 	}
 	for _, x := range files {
-		if err := file.WriteTemplated(dir, x.name, x.content, data); err != nil {
+		if err := file.WriteTemplated(fs, x.name, x.content, data); err != nil {
 			return err
 		}
 	}
-	if err := file.Append(filepath.Join(dir, ".dockerignore"), dockerignore); err != nil {
+	if err := file.Append(fs, ".dockerignore", dockerignore); err != nil {
 		return err
 	}
-	return file.Append(filepath.Join(dir, ".gitignore"), "target")
+	return file.Append(fs, ".gitignore", "target")
 }
 
 func (s Scala) Name() string {
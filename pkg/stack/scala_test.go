@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/buildtool/scaffold/pkg/templating"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/osfs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -18,7 +19,7 @@ func TestScala_Scaffold_Error_Creating_Base_Directories(t *testing.T) {
 
 	stack := &Scala{}
 
-	err := stack.Scaffold(filename, templating.TemplateData{
+	err := stack.Scaffold(osfs.New(filename), templating.TemplateData{
 		ProjectName:    "test",
 		Badges:         nil,
 		Organisation:   "org.example",
@@ -39,7 +40,7 @@ func TestScala_Scaffold_Error_Creating_Organisation_Directories(t *testing.T) {
 
 	stack := &Scala{}
 
-	err := stack.Scaffold(name, templating.TemplateData{
+	err := stack.Scaffold(osfs.New(name), templating.TemplateData{
 		ProjectName:    "test",
 		Badges:         nil,
 		Organisation:   "org.example",
@@ -60,7 +61,7 @@ func TestScala_Scaffold_Error_Creating_Dockerfile(t *testing.T) {
 
 	stack := &Scala{}
 
-	err := stack.Scaffold(name, templating.TemplateData{
+	err := stack.Scaffold(osfs.New(name), templating.TemplateData{
 		ProjectName:    "test",
 		Badges:         nil,
 		Organisation:   "org.example",
@@ -81,7 +82,7 @@ func TestScala_Scaffold_Error_Appending_Dockerignore(t *testing.T) {
 
 	stack := &Scala{}
 
-	err := stack.Scaffold(name, templating.TemplateData{
+	err := stack.Scaffold(osfs.New(name), templating.TemplateData{
 		ProjectName:    "test",
 		Badges:         nil,
 		Organisation:   "org.example",
@@ -100,7 +101,7 @@ func TestScala_Scaffold(t *testing.T) {
 	_ = ioutil.WriteFile(filepath.Join(name, ".gitignore"), []byte(""), 0666)
 	stack := &Scala{}
 
-	err := stack.Scaffold(name, templating.TemplateData{
+	err := stack.Scaffold(osfs.New(name), templating.TemplateData{
 		ProjectName:    "test",
 		Badges:         nil,
 		Organisation:   "org.example",
@@ -1,9 +1,12 @@
 package stack
 
-import "github.com/buildtool/scaffold/pkg/templating"
+import (
+	"github.com/buildtool/scaffold/pkg/templating"
+	"gopkg.in/src-d/go-billy.v4"
+)
 
 type Stack interface {
-	Scaffold(dir string, data templating.TemplateData) error
+	Scaffold(fs billy.Filesystem, data templating.TemplateData) error
 	Name() string
 }
 
@@ -6,19 +6,19 @@ import (
 )
 
 type TemplateData struct {
-	ProjectName    string
-	Badges         []Badge
-	Organisation   string
-	RegistryUrl    string
-	RepositoryUrl  string
-	RepositoryHost string
-	RepositoryPath string
+	ProjectName    string  `yaml:"projectName"`
+	Badges         []Badge `yaml:"badges,omitempty"`
+	Organisation   string  `yaml:"organisation"`
+	RegistryUrl    string  `yaml:"registryUrl"`
+	RepositoryUrl  string  `yaml:"repositoryUrl"`
+	RepositoryHost string  `yaml:"repositoryHost"`
+	RepositoryPath string  `yaml:"repositoryPath"`
 }
 
 type Badge struct {
-	Title    string
-	ImageUrl string
-	LinkUrl  string
+	Title    string `yaml:"title" json:"title"`
+	ImageUrl string `yaml:"imageUrl" json:"imageUrl"`
+	LinkUrl  string `yaml:"linkUrl" json:"linkUrl"`
 }
 
 func Execute(content string, data TemplateData) (string, error) {
@@ -0,0 +1,43 @@
+package pkg
+
+import (
+	"github.com/buildtool/scaffold/pkg/config"
+	"github.com/buildtool/scaffold/pkg/events"
+	"github.com/buildtool/scaffold/pkg/failure"
+	"github.com/buildtool/scaffold/pkg/report"
+	"io"
+)
+
+func Update(dir string, out io.Writer, args ...string) int {
+	var diff, local bool
+	var progress string
+	var selection config.Selection
+	set := newFlagSet("scaffold update", "[options]", "Run in the root of a scaffolded project, re-renders the templates and merges the changes into the files of the project, marking conflicting changes with <blue>`<<<<<<< current`</blue> and <blue>`>>>>>>> template`</blue>", out)
+	set.BoolVar(&diff, "diff", false, "print the changes for review instead of writing them")
+	set.BoolVar(&local, "local", false, "update a project created with 'scaffold new --local', without any VCS or CI provider")
+	set.StringVar(&progress, "progress", report.Auto, report.Usage)
+	selectionFlags(set, &selection)
+
+	if exitCode, ok := parseFlags(set, args); !ok {
+		return exitCode
+	}
+	r, err := report.New(out, progress)
+	if err != nil {
+		set.Usage()
+		return failure.Usage.ExitCode()
+	}
+	followColors(set, out, r)
+	if set.NArg() > 0 {
+		set.Usage()
+		return failure.Usage.ExitCode()
+	}
+	defer r.Done()
+	cfg, err := config.Load(dir, selection, r)
+	if err != nil {
+		return fail(events.Nop, r, "load-config", failure.Config, err)
+	}
+	if local {
+		cfg.Local("")
+	}
+	return cfg.Update(dir, diff, r)
+}
@@ -0,0 +1,124 @@
+package pkg
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdate_Arguments(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Update(name, &out, "project")
+
+	assert.Equal(t, 2, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "\x1b[0mUsage: scaffold update [options]\n"))
+}
+
+func TestUpdate_Help(t *testing.T) {
+	out := bytes.Buffer{}
+
+	exitCode := Update(name, &out, "--help")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "  -local\n    \tupdate a project created with 'scaffold new --local', without any VCS or CI provider\n")
+}
+
+func TestUpdate_Without_Providers(t *testing.T) {
+	dir := filepath.Join(name, "project")
+	defer func() { _ = os.RemoveAll(dir) }()
+	_ = os.MkdirAll(dir, 0777)
+	state := `
+stack: none
+ci: buildkite
+data:
+  projectName: project
+`
+	_ = ioutil.WriteFile(filepath.Join(dir, ".scaffold-state.yaml"), []byte(state), 0666)
+	out := bytes.Buffer{}
+
+	exitCode := Update(dir, &out)
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "\x1b[32mAdding \x1b[39m\x1b[97m\x1b[1m'.buildkite/pipeline.yml'\x1b[0m\x1b[97m\x1b[39m\n")
+}
+
+func TestUpdate_Progress_Plain(t *testing.T) {
+	dir := filepath.Join(name, "project")
+	defer func() { _ = os.RemoveAll(dir) }()
+	_ = os.MkdirAll(dir, 0777)
+	_ = ioutil.WriteFile(filepath.Join(dir, ".scaffold-state.yaml"), []byte("stack: none\nci: buildkite\ndata:\n  projectName: project\n"), 0666)
+	out := bytes.Buffer{}
+
+	exitCode := Update(dir, &out, "--progress", "plain")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "Updating 'project' using stack 'none'\nAdding '.buildkite/pipeline.yml'\n")
+	assert.NotContains(t, out.String(), "\x1b[")
+}
+
+func TestUpdate_NoCI(t *testing.T) {
+	dir := filepath.Join(name, "project")
+	defer func() { _ = os.RemoveAll(dir) }()
+	_ = os.MkdirAll(dir, 0777)
+	_ = ioutil.WriteFile(filepath.Join(dir, ".scaffold-state.yaml"), []byte("stack: none\n"), 0666)
+	out := bytes.Buffer{}
+
+	exitCode := Update(dir, &out)
+
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[31mno CI configured\x1b[39m\x1b[0m\n", out.String())
+}
+
+func TestUpdate_Adds_Files(t *testing.T) {
+	yaml := `
+ci:
+  buildkite:
+    organisation: example
+    token: abc
+vcs:
+  github:
+    organisation: example
+    token: abc
+`
+	dir := filepath.Join(name, "project")
+	defer func() { _ = os.RemoveAll(dir) }()
+	_ = os.MkdirAll(dir, 0777)
+	_ = ioutil.WriteFile(filepath.Join(dir, ".scaffold.yaml"), []byte(yaml), 0666)
+	state := `
+stack: go
+data:
+  projectName: project
+  repositoryUrl: git@github.com:example/project.git
+  repositoryHost: github.com
+  repositoryPath: /example/project
+files:
+  .gitignore: "\n"
+`
+	_ = ioutil.WriteFile(filepath.Join(dir, ".scaffold-state.yaml"), []byte(state), 0666)
+	_ = ioutil.WriteFile(filepath.Join(dir, ".gitignore"), []byte("\n"), 0666)
+	out := bytes.Buffer{}
+
+	exitCode := Update(dir, &out)
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "\x1b[32mAdding \x1b[39m\x1b[97m\x1b[1m'.buildkite/pipeline.yml'\x1b[0m\x1b[97m\x1b[39m\n")
+	goMod, _ := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	assert.Equal(t, "module github.com/example/project\n\ngo 1.12\n", string(goMod))
+}
+
+func TestUpdate_Local(t *testing.T) {
+	dir := filepath.Join(name, "project")
+	defer func() { _ = os.RemoveAll(dir) }()
+	assert.Equal(t, 0, Setup(name, &bytes.Buffer{}, "--local", "--author-name", "Test", "--author-email", "test@example.com", "project"))
+	out := bytes.Buffer{}
+
+	exitCode := Update(dir, &out, "--local")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "\x1b[0m\x1b[94mUpdating \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'none'\x1b[0m\x1b[97m\x1b[39m\n\x1b[0m", out.String())
+}
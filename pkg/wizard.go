@@ -0,0 +1,161 @@
+package pkg
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/buildtool/scaffold/pkg/color"
+	"github.com/buildtool/scaffold/pkg/config"
+	"github.com/buildtool/scaffold/pkg/config/ci"
+	"github.com/buildtool/scaffold/pkg/config/vcs"
+	"github.com/buildtool/scaffold/pkg/stack"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	stdin      io.Reader = os.Stdin
+	isTerminal           = func() bool {
+		stat, err := os.Stdin.Stat()
+		return err == nil && stat.Mode()&os.ModeCharDevice != 0
+	}
+)
+
+type wizard struct {
+	in    *bufio.Reader
+	out   io.Writer
+	color bool
+}
+
+func newWizard(in io.Reader, out io.Writer, colored bool) *wizard {
+	return &wizard{in: bufio.NewReader(in), out: out, color: colored}
+}
+
+func (w *wizard) project(opts *options) error {
+	for opts.name == "" {
+		name, err := w.ask("Name of the service", "")
+		if err != nil {
+			return err
+		}
+		opts.name = name
+	}
+	names := stackNames()
+	_, _ = fmt.Fprintln(w.out, color.Format(w.color, "<lightblue>Available stacks:</lightblue>"))
+	for i, name := range names {
+		_, _ = fmt.Fprint(w.out, color.Format(w.color, "  %d) <white><bold>%s</bold></white>\n", i+1, name))
+	}
+	for {
+		answer, err := w.ask("Stack", opts.stack)
+		if err != nil {
+			return err
+		}
+		if i, err := strconv.Atoi(answer); err == nil && i > 0 && i <= len(names) {
+			answer = names[i-1]
+		}
+		if _, exists := stack.Stacks[answer]; exists {
+			opts.stack = answer
+			return nil
+		}
+		_, _ = fmt.Fprintln(w.out, color.Format(w.color, "<red>Unknown stack '%s'</red>", answer))
+	}
+}
+
+func (w *wizard) configure(cfg *config.Config, opts *options) error {
+	_, _ = fmt.Fprint(w.out, color.Format(w.color, "<lightblue>Using VCS </lightblue><white><bold>'%s'</bold></white> <lightblue>and CI </lightblue><white><bold>'%s'</bold></white>\n", cfg.CurrentVCS.Name(), cfg.CurrentCI.Name()))
+	var organisation, visibility string
+	var err error
+	switch v := cfg.CurrentVCS.(type) {
+	case *vcs.Github:
+		// An empty answer keeps the configured organisation, so the current user needs an answer of its own
+		if organisation, err = w.ask("Organisation ('-' for the current user)", v.Organisation); err != nil {
+			return err
+		}
+		if organisation == "-" {
+			organisation = ""
+		}
+		visibility = "private"
+		if v.Public {
+			visibility = "public"
+		}
+		if visibility, err = w.choose("Visibility", []string{"private", "public"}, visibility); err != nil {
+			return err
+		}
+		v.Organisation = organisation
+		v.Public = visibility == "public"
+	case *vcs.Gitlab:
+		if organisation, err = w.ask("Group", v.Group); err != nil {
+			return err
+		}
+		visibility = v.Visibility
+		if visibility == "" {
+			visibility = "private"
+		}
+		if visibility, err = w.choose("Visibility", []string{"private", "internal", "public"}, visibility); err != nil {
+			return err
+		}
+		v.Group = organisation
+		v.Visibility = visibility
+		if c, ok := cfg.CurrentCI.(*ci.Gitlab); ok {
+			c.Group = organisation
+		}
+	}
+
+	_, _ = fmt.Fprintln(w.out, color.Format(w.color, "<lightblue>Summary:</lightblue>"))
+	w.summary("Name", opts.name)
+	w.summary("Stack", opts.stack)
+	w.summary("VCS", cfg.CurrentVCS.Name())
+	if organisation != "" {
+		w.summary("Organisation", organisation)
+	}
+	if visibility != "" {
+		w.summary("Visibility", visibility)
+	}
+	w.summary("CI", cfg.CurrentCI.Name())
+	answer, err := w.choose("Create the service?", []string{"y", "n"}, "n")
+	if err != nil {
+		return err
+	}
+	if answer != "y" {
+		return errors.New("aborted")
+	}
+	return nil
+}
+
+func (w *wizard) summary(key, value string) {
+	_, _ = fmt.Fprint(w.out, color.Format(w.color, "  %-13s <white><bold>%s</bold></white>\n", key+":", value))
+}
+
+func (w *wizard) choose(question string, options []string, current string) (string, error) {
+	for {
+		answer, err := w.ask(fmt.Sprintf("%s (%s)", question, strings.Join(options, "/")), current)
+		if err != nil {
+			return "", err
+		}
+		for _, option := range options {
+			if strings.EqualFold(answer, option) {
+				return option, nil
+			}
+		}
+		_, _ = fmt.Fprintln(w.out, color.Format(w.color, "<red>Please answer one of %s</red>", strings.Join(options, ", ")))
+	}
+}
+
+func (w *wizard) ask(question, current string) (string, error) {
+	if current != "" {
+		_, _ = fmt.Fprint(w.out, color.Format(w.color, "<lightblue>%s</lightblue> [%s]: ", question, current))
+	} else {
+		_, _ = fmt.Fprint(w.out, color.Format(w.color, "<lightblue>%s</lightblue>: ", question))
+	}
+	line, err := w.in.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return "", errors.New("aborted")
+	} else if err != nil && err != io.EOF {
+		return "", err
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return current, nil
+}
@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"bytes"
+	"github.com/buildtool/scaffold/pkg/config"
+	"github.com/buildtool/scaffold/pkg/config/ci"
+	"github.com/buildtool/scaffold/pkg/config/vcs"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetup_Wizard(t *testing.T) {
+	yaml := `
+ci:
+  buildkite:
+    organisation: example
+    token: abc
+vcs:
+  github:
+    organisation: example
+    token: abc
+`
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+	isTerminal = func() bool { return true }
+	stdin = strings.NewReader("\nproject\n1\nother\npublic\ny\n")
+	defer func() {
+		isTerminal = func() bool { return false }
+		stdin = os.Stdin
+	}()
+
+	out := bytes.Buffer{}
+
+	exitCode := Setup(name, &out, "--dry-run")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, out.String(), "Available stacks:")
+	assert.Contains(t, out.String(), "Using VCS \x1b[39m\x1b[97m\x1b[1m'Github'\x1b[0m\x1b[97m\x1b[39m \x1b[94mand CI \x1b[39m\x1b[97m\x1b[1m'Buildkite'")
+	assert.Contains(t, out.String(), "Creating new service \x1b[39m\x1b[97m\x1b[1m'project'\x1b[0m\x1b[97m\x1b[39m \x1b[94musing stack \x1b[39m\x1b[97m\x1b[1m'go'")
+	assert.Contains(t, out.String(), "POST https://api.github.com/orgs/other/repos")
+	assert.Contains(t, out.String(), `"private": false`)
+}
+
+func TestSetup_Wizard_Aborted(t *testing.T) {
+	yaml := `
+ci:
+  buildkite:
+    organisation: example
+    token: abc
+vcs:
+  github:
+    organisation: example
+    token: abc
+`
+	file := filepath.Join(name, ".scaffold.yaml")
+	_ = ioutil.WriteFile(file, []byte(yaml), 0777)
+	defer func() { _ = os.Remove(file) }()
+	isTerminal = func() bool { return true }
+	stdin = strings.NewReader("project\n\n\n\n\n")
+	defer func() {
+		isTerminal = func() bool { return false }
+		stdin = os.Stdin
+	}()
+
+	out := bytes.Buffer{}
+
+	exitCode := Setup(name, &out)
+
+	assert.Equal(t, 2, exitCode)
+	assert.True(t, strings.HasSuffix(out.String(), "\x1b[0m\x1b[31maborted\x1b[39m\x1b[0m\n"))
+	assert.NotContains(t, out.String(), "Usage:")
+}
+
+func TestWizard_Project_Unknown_Stack(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := newWizard(strings.NewReader("project\nmissing\nscala\n"), out, true)
+	opts := &options{stack: "none"}
+
+	err := w.project(opts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "project", opts.name)
+	assert.Equal(t, "scala", opts.stack)
+	assert.Contains(t, out.String(), "\x1b[31mUnknown stack 'missing'\x1b[39m")
+}
+
+func TestWizard_Project_Plain(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := newWizard(strings.NewReader("project\nmissing\nscala\n"), out, false)
+
+	err := w.project(&options{stack: "none"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Unknown stack 'missing'\n")
+	assert.NotContains(t, out.String(), "\x1b[")
+}
+
+func TestWizard_Project_EOF(t *testing.T) {
+	w := newWizard(strings.NewReader(""), &bytes.Buffer{}, true)
+
+	err := w.project(&options{})
+
+	assert.EqualError(t, err, "aborted")
+}
+
+func TestWizard_Configure_Gitlab(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := newWizard(strings.NewReader("other\nplenty\ninternal\ny\n"), out, true)
+	gitlabVcs := &vcs.Gitlab{Group: "group"}
+	gitlabCi := &ci.Gitlab{Group: "group"}
+	cfg := config.InitEmptyConfig()
+	cfg.CurrentVCS = gitlabVcs
+	cfg.CurrentCI = gitlabCi
+
+	err := w.configure(cfg, &options{name: "project", stack: "go"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "other", gitlabVcs.Group)
+	assert.Equal(t, "other", gitlabCi.Group)
+	assert.Equal(t, "internal", gitlabVcs.Visibility)
+	assert.Contains(t, out.String(), "\x1b[31mPlease answer one of private, internal, public\x1b[39m")
+	assert.Contains(t, out.String(), "Visibility:   \x1b[97m\x1b[1minternal")
+}
+
+func TestWizard_Configure_Github_Current_User(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := newWizard(strings.NewReader("-\n\ny\n"), out, true)
+	githubVcs := &vcs.Github{Organisation: "example"}
+	cfg := config.InitEmptyConfig()
+	cfg.CurrentVCS = githubVcs
+	cfg.CurrentCI = &ci.Buildkite{}
+
+	err := w.configure(cfg, &options{name: "project", stack: "go"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", githubVcs.Organisation)
+	assert.False(t, githubVcs.Public)
+	assert.NotContains(t, out.String(), "Organisation:")
+}